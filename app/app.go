@@ -2,8 +2,14 @@ package app
 
 import (
 	"context"
+	"errors"
+	"expvar"
+	"fmt"
 	"github.com/ShelbyKS/Roamly-backend/internal/utils"
 	"log"
+	"net/http"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/ShelbyKS/Roamly-backend/internal/middleware"
@@ -20,21 +26,36 @@ import (
 
 	"github.com/ShelbyKS/Roamly-backend/app/config"
 	_ "github.com/ShelbyKS/Roamly-backend/docs"
-	"github.com/ShelbyKS/Roamly-backend/internal/database/orm"
+	"github.com/ShelbyKS/Roamly-backend/internal/database/migrator"
+	"github.com/ShelbyKS/Roamly-backend/internal/database/storage/blob"
 	"github.com/ShelbyKS/Roamly-backend/internal/database/storage/postgresql"
 	"github.com/ShelbyKS/Roamly-backend/internal/database/storage/redis"
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/clients"
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/storage"
 	"github.com/ShelbyKS/Roamly-backend/internal/handler"
+	"github.com/ShelbyKS/Roamly-backend/internal/outbox"
 	"github.com/ShelbyKS/Roamly-backend/internal/service"
-	"github.com/ShelbyKS/Roamly-backend/pkg/chatgpt"
+	"github.com/ShelbyKS/Roamly-backend/migrations"
+	"github.com/ShelbyKS/Roamly-backend/pkg/broker"
 	"github.com/ShelbyKS/Roamly-backend/pkg/googleapi"
-	"github.com/ShelbyKS/Roamly-backend/pkg/kafka"
+	"github.com/ShelbyKS/Roamly-backend/pkg/llm"
+	"github.com/ShelbyKS/Roamly-backend/pkg/osm"
+)
+
+const (
+	// geoProviderOSM selects nominatim and osrm instead of google by GEO_PROVIDER.
+	geoProviderOSM = "osm"
+	// photoStorageS3 selects an s3 compatible bucket instead of a local directory by PHOTO_STORAGE.
+	photoStorageS3 = "s3"
 )
 
 type Roamly struct {
-	config  *config.Config
-	logger  *logrus.Logger
-	pgDB    *gorm.DB
-	redisDB *goRedis.Client
+	config   *config.Config
+	logger   *logrus.Logger
+	pgDB     *gorm.DB
+	redisDB  *goRedis.Client
+	producer broker.Publisher
+	relay    *outbox.Relay
 }
 
 func New(cfg *config.Config, lg *logrus.Logger) *Roamly {
@@ -45,6 +66,9 @@ func New(cfg *config.Config, lg *logrus.Logger) *Roamly {
 }
 
 func (app *Roamly) Run() {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
 	app.initDBs()
 
 	r := app.newRouter()
@@ -52,9 +76,84 @@ func (app *Roamly) Run() {
 	app.initExternalClients()
 	app.initAPI(r)
 
-	if err := r.Run(":" + app.config.ServerPort); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	server := &http.Server{
+		Addr:    ":" + app.config.ServerPort,
+		Handler: r,
+	}
+
+	relayCtx, stopRelay := context.WithCancel(context.Background())
+	relayDone := make(chan struct{})
+	go func() {
+		defer close(relayDone)
+		app.relay.Run(relayCtx)
+	}()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.ListenAndServe()
+	}()
+
+	debugServer := app.newDebugServer()
+	if debugServer != nil {
+		go func() {
+			err := debugServer.ListenAndServe()
+			if !errors.Is(err, http.ErrServerClosed) {
+				app.logger.WithError(err).Error("Failed to start debug server")
+			}
+		}()
+	}
+
+	select {
+	case err := <-serverErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			app.logger.WithError(err).Error("Failed to start server")
+		}
+	case <-ctx.Done():
+		app.logger.Info("Shutting down server")
 	}
+
+	app.shutdown(server, debugServer, stopRelay, relayDone)
+}
+
+// shutdown drains in-flight requests, stops the outbox relay and then releases
+// connections in the reverse order of their creation, all within ShutdownTimeout.
+// Messages left in the outbox are sent after the restart.
+func (app *Roamly) shutdown(server, debugServer *http.Server, stopRelay context.CancelFunc, relayDone <-chan struct{}) {
+	ctx, cancel := context.WithTimeout(context.Background(), app.config.ShutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		app.logger.WithError(err).Error("Failed to drain http requests")
+	}
+	if debugServer != nil {
+		if err := debugServer.Shutdown(ctx); err != nil {
+			app.logger.WithError(err).Error("Failed to stop debug server")
+		}
+	}
+
+	stopRelay()
+	select {
+	case <-relayDone:
+	case <-ctx.Done():
+		app.logger.Error("Outbox relay did not stop in time")
+	}
+
+	deadline, _ := ctx.Deadline()
+	if err := app.producer.Close(time.Until(deadline)); err != nil {
+		app.logger.WithError(err).Error("Failed to flush message producer")
+	}
+
+	if err := app.redisDB.Close(); err != nil {
+		app.logger.WithError(err).Error("Failed to close redis client")
+	}
+
+	if sqlDB, err := app.pgDB.DB(); err == nil {
+		if err := sqlDB.Close(); err != nil {
+			app.logger.WithError(err).Error("Failed to close postgres pool")
+		}
+	}
+
+	app.logger.Info("Server stopped")
 }
 
 func (app *Roamly) newRouter() *gin.Engine {
@@ -90,43 +189,97 @@ func (app *Roamly) initDBs() {
 		log.Fatalf("Failed to connect to redis: %v", err)
 	}
 
-	err = pgDB.AutoMigrate(&orm.User{}, &orm.Trip{}, &orm.Place{}, &orm.Event{},
-		&orm.TripUsers{}, &orm.Invite{}, orm.AIChatMessage{})
-
+	err = checkSchemaVersion(pgDB)
 	if err != nil {
-		log.Fatalf("Failed to migrate db: %v", err)
+		log.Fatalf("Failed to check db schema: %v", err)
 	}
 
 	app.pgDB = pgDB
 	app.redisDB = redisClient
 }
 
+// checkSchemaVersion refuses to work with a database that misses migrations:
+// they are applied by cmd/migrate before the deploy.
+func checkSchemaVersion(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+
+	m, err := migrator.New(sqlDB, migrations.FS)
+	if err != nil {
+		return err
+	}
+
+	pending, err := m.Check(context.Background())
+	if errors.Is(err, migrator.ErrNotInitialized) {
+		return fmt.Errorf("%w; run `migrate up`", err)
+	}
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("schema is behind: %d migration(s) pending, latest is %d; run `migrate up`",
+			len(pending), m.Latest())
+	}
+
+	return nil
+}
+
 func (app *Roamly) initAPI(router *gin.Engine) {
 	userStorage := postgresql.NewUserStorage(app.pgDB)
 	sessionStorage := redis.NewSessionStorage(app.redisDB)
+	presenceStorage := redis.NewPresenceStorage(app.redisDB, app.config.PresenceTTL)
+	schedulePreviewStorage := redis.NewSchedulePreviewStorage(app.redisDB)
 	tripStorage := postgresql.NewTripStorage(app.pgDB)
 	placeStorage := postgresql.NewPlaceStorage(app.pgDB)
 	eventStorage := postgresql.NewEventStorage(app.pgDB)
 	inviteStorage := postgresql.NewInviteStorage(app.pgDB)
 	aiChatStorage := postgresql.NewAIChatStorage(app.pgDB)
+	outboxStorage := postgresql.NewOutboxStorage(app.pgDB)
+	revisionStorage := postgresql.NewRevisionStorage(app.pgDB)
+	travelLegStorage := postgresql.NewTravelLegStorage(app.pgDB)
+	transactor := postgresql.NewTransactor(app.pgDB)
 
-	openAIClient := chatgpt.NewChatGPTClient(app.config.OpenAiKey) //todo: move to external
-	// if err != nil {
-	// 	log.Fatalf("Failed to create chat-gpt-client %v", err)
-	// }
-	googleApi := googleapi.NewClient(app.config.GoogleApiKey) //todo: move to external
+	llmClient, err := app.newLLMClient()
+	if err != nil {
+		log.Fatalf("Failed to init llm client: %v", err)
+	}
+	googleApiClient := googleapi.NewClient(app.config.GoogleApiKey) //todo: move to external
+	googleApi := googleapi.NewCachedClient(app.newGeoClient(googleApiClient), app.redisDB, googleapi.CacheTTL{
+		Place:    app.config.GoogleCache.PlaceTTL,
+		Search:   app.config.GoogleCache.SearchTTL,
+		Matrix:   app.config.GoogleCache.MatrixTTL,
+		TimeZone: app.config.GoogleCache.TimeZoneTTL,
+	})
 
-	producer := kafka.NewMessageBrokerProducer(app.config.Kafka.Host, app.config.Kafka.Port, app.config.Kafka.Topic)
-	notifyUrils := utils.NewNotifyUtils(tripStorage, sessionStorage, producer)
+	producer, err := app.newPublisher()
+	if err != nil {
+		log.Fatalf("Failed to init message broker: %v", err)
+	}
+	app.producer = producer
+	notifyUrils := utils.NewNotifyUtils(tripStorage, outboxStorage)
+	revisionUtils := utils.NewRevisionUtils(tripStorage, revisionStorage)
+	travelUtils := utils.NewTravelUtils(tripStorage, travelLegStorage, googleApi, transactor, notifyUrils)
+	app.relay = outbox.NewRelay(transactor, outboxStorage, broker.NewMessageProducer(producer), app.logger, outbox.Options{
+		PollInterval: app.config.Outbox.PollInterval,
+		BatchSize:    app.config.Outbox.BatchSize,
+		Retention:    app.config.Outbox.Retention,
+		SendTimeout:  app.config.Outbox.SendTimeout,
+	})
 
-	schedulerService := service.NewShedulerService(openAIClient, googleApi, tripStorage, eventStorage, placeStorage, sessionStorage, producer)
+	schedulerService := service.NewShedulerService(llmClient, googleApi, tripStorage, eventStorage, placeStorage, transactor, notifyUrils, revisionUtils, travelUtils,
+		revisionStorage, schedulePreviewStorage, app.config.SchedulePreviewTTL)
 	userService := service.NewUserService(userStorage, sessionStorage)
 	authService := service.NewAuthService(userStorage, sessionStorage)
-	tripService := service.NewTripService(tripStorage, placeStorage, googleApi, openAIClient, sessionStorage, producer, aiChatStorage)
-	placeService := service.NewPlaceService(placeStorage, tripStorage, googleApi, eventStorage, openAIClient, sessionStorage, producer)
-	eventService := service.NewEventService(eventStorage, tripStorage, placeStorage, sessionStorage, producer)
+	tripService := service.NewTripService(tripStorage, placeStorage, googleApi, llmClient, aiChatStorage, transactor, notifyUrils, travelUtils)
+	placeService := service.NewPlaceService(placeStorage, tripStorage, googleApi, googleApi, eventStorage, llmClient, transactor, notifyUrils, revisionUtils, travelUtils)
+	eventService := service.NewEventService(eventStorage, tripStorage, placeStorage, transactor, notifyUrils, revisionUtils, travelUtils)
+	revisionService := service.NewRevisionService(revisionStorage, tripStorage, placeStorage, eventStorage, transactor, notifyUrils, revisionUtils, travelUtils)
 	inviteService := service.NewInviteService(inviteStorage, tripStorage, app.config.JWTSecret)
-	aiChatService := service.NewAIChatService(aiChatStorage, tripStorage, sessionStorage, notifyUrils, openAIClient, googleApi)
+	presenceService := service.NewPresenceService(presenceStorage)
+	photoService := service.NewPhotoService(googleApiClient, app.newPhotoStorage(), app.config.Photo.FetchTimeout)
+	aiChatService := service.NewAIChatService(aiChatStorage, tripStorage, sessionStorage, notifyUrils, llmClient, googleApi)
 
 	middleware.Mw = middleware.InitMiddleware(sessionStorage)
 	router.Use(middleware.Mw.CORSMiddleware())
@@ -134,14 +287,87 @@ func (app *Roamly) initAPI(router *gin.Engine) {
 	handler.NewAuthHandler(router, app.logger, authService)
 	handler.NewUserHandler(router, app.logger, userService)
 	handler.NewTripHandler(router, app.logger, tripService, placeService, schedulerService)
-	handler.NewPlaceHandler(router, app.logger, placeService, *googleApi)
+	handler.NewPlaceHandler(router, app.logger, placeService, photoService, *googleApiClient, app.config.Photo.MaxAge)
 	handler.NewEventHandler(router, app.logger, eventService, tripService)
 	handler.NewInviteHandler(router, app.logger, inviteService, tripService)
 	handler.NewAIChatHandler(router, app.logger, aiChatService, tripService)
+	handler.NewPresenceHandler(router, app.logger, presenceService, tripService)
+	handler.NewRevisionHandler(router, app.logger, revisionService, tripService)
 
 	router.GET("/api/v1/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 }
 
+// newDebugServer serves expvar counters on DebugAddr, apart from the public
+// router as they have no auth. It is nil if DebugAddr is empty.
+func (app *Roamly) newDebugServer() *http.Server {
+	if app.config.DebugAddr == "" {
+		return nil
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/debug/vars", expvar.Handler())
+
+	return &http.Server{
+		Addr:    app.config.DebugAddr,
+		Handler: mux,
+	}
+}
+
+// newGeoClient picks the provider of place search and routing. Photos are
+// served by google regardless of it.
+func (app *Roamly) newGeoClient(googleApiClient *googleapi.GoogleApiClient) clients.IGoogleApiClient {
+	if app.config.Geo.Provider == geoProviderOSM {
+		return osm.NewClient(app.config.Geo.NominatimURL, app.config.Geo.OSRMURL, app.config.Geo.UserAgent)
+	}
+	return googleApiClient
+}
+
+func (app *Roamly) newPhotoStorage() storage.IBlobStorage {
+	if app.config.Photo.Storage == photoStorageS3 {
+		return blob.NewS3Storage(blob.S3Config{
+			Endpoint:  app.config.Photo.S3Endpoint,
+			Bucket:    app.config.Photo.S3Bucket,
+			Region:    app.config.Photo.S3Region,
+			AccessKey: app.config.Photo.S3AccessKey,
+			SecretKey: app.config.Photo.S3SecretKey,
+		})
+	}
+	return blob.NewFSStorage(app.config.Photo.Dir)
+}
+
+func (app *Roamly) newLLMClient() (clients.IChatClient, error) {
+	return llm.NewClient(llm.Config{
+		Type:      app.config.LLM.Provider,
+		OpenAIURL: app.config.LLM.OpenAIURL,
+		OpenAIKey: app.config.LLM.OpenAIKey,
+		OllamaURL: app.config.LLM.OllamaURL,
+		Models: map[string]string{
+			clients.LLMUseSchedule: app.config.LLM.ScheduleModel,
+			clients.LLMUseChat:     app.config.LLM.ChatModel,
+			clients.LLMUseDuration: app.config.LLM.DurationModel,
+			clients.LLMUsePlaces:   app.config.LLM.PlacesModel,
+		},
+	})
+}
+
+func (app *Roamly) newPublisher() (broker.Publisher, error) {
+	cfg := broker.Config{
+		Type:        app.config.Broker.Type,
+		Topic:       app.config.Broker.Stream,
+		Redis:       app.redisDB,
+		RedisMaxLen: app.config.Broker.StreamMaxLen,
+
+		SingleProcess: app.config.Broker.SingleProcess,
+	}
+	if cfg.Type == broker.TypeKafka {
+		cfg.Topic = app.config.Kafka.Topic
+		cfg.KafkaHost = app.config.Kafka.Host
+		cfg.KafkaPort = app.config.Kafka.Port
+	}
+
+	return broker.NewPublisher(cfg)
+}
+
 func (app *Roamly) initExternalClients() {
 	googleapi.Init(app.config.GoogleApiKey)
 }
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/joho/godotenv"
 
@@ -13,12 +14,29 @@ type Config struct {
 	ServerPort   string `envconfig:"SERVER_PORT"`
 	LogLevel     string `envconfig:"LOG_LEVEL"`
 	GoogleApiKey string `envconfig:"GOOGLE_API_KEY"`
-	OpenAiKey    string `envconfig:"OPEN_AI_KEY"`
 	JWTSecret    string `envconfig:"JWT_SECRET"`
 
-	Postgres PostgresConfig
-	Redis    RedisConfig
-	Kafka    KafkaConfig
+	// DebugAddr is an internal address /debug/vars is served on, apart from
+	// the public api. Empty disables it.
+	DebugAddr string `envconfig:"DEBUG_ADDR" default:"127.0.0.1:6060"`
+
+	// ShutdownTimeout limits draining of in-flight requests and queued messages on SIGTERM.
+	ShutdownTimeout time.Duration `envconfig:"SHUTDOWN_TIMEOUT" default:"15s"`
+	// PresenceTTL is how long a trip member stays present after the last
+	// heartbeat of the notifier, must match the notifier setting.
+	PresenceTTL time.Duration `envconfig:"PRESENCE_TTL" default:"2m"`
+	// SchedulePreviewTTL is how long a schedule preview can be applied.
+	SchedulePreviewTTL time.Duration `envconfig:"SCHEDULE_PREVIEW_TTL" default:"30m"`
+
+	Postgres    PostgresConfig
+	Redis       RedisConfig
+	Kafka       KafkaConfig
+	Broker      BrokerConfig
+	Outbox      OutboxConfig
+	GoogleCache GoogleCacheConfig
+	Geo         GeoConfig
+	Photo       PhotoConfig
+	LLM         LLMConfig
 }
 
 type PostgresConfig struct {
@@ -43,6 +61,76 @@ type KafkaConfig struct {
 	Group string `envconfig:"KAFKA_GROUP"`
 }
 
+type BrokerConfig struct {
+	// Type is one of kafka, redis or memory. The memory broker works only
+	// when the notifier runs in the same process, e.g. in tests.
+	Type string `envconfig:"BROKER" default:"kafka"`
+	// Stream is the redis stream and the memory queue name, kafka uses KAFKA_TOPIC.
+	Stream       string `envconfig:"BROKER_STREAM" default:"notifications"`
+	StreamMaxLen int64  `envconfig:"BROKER_STREAM_MAXLEN" default:"10000"`
+	// SingleProcess is set by cmd/standalone, which runs the notifier too.
+	SingleProcess bool `ignored:"true"`
+}
+
+type OutboxConfig struct {
+	PollInterval time.Duration `envconfig:"OUTBOX_POLL_INTERVAL" default:"1s"`
+	BatchSize    int           `envconfig:"OUTBOX_BATCH_SIZE" default:"100"`
+	// Retention is how long sent messages are kept before they are deleted.
+	Retention time.Duration `envconfig:"OUTBOX_RETENTION" default:"24h"`
+	// SendTimeout limits publishing of a single message to the broker.
+	SendTimeout time.Duration `envconfig:"OUTBOX_SEND_TIMEOUT" default:"10s"`
+}
+
+// GoogleCacheConfig are lifetimes of cached google api responses, 0 disables caching.
+type GoogleCacheConfig struct {
+	PlaceTTL    time.Duration `envconfig:"GOOGLE_CACHE_PLACE_TTL" default:"168h"`
+	SearchTTL   time.Duration `envconfig:"GOOGLE_CACHE_SEARCH_TTL" default:"24h"`
+	MatrixTTL   time.Duration `envconfig:"GOOGLE_CACHE_MATRIX_TTL" default:"168h"`
+	TimeZoneTTL time.Duration `envconfig:"GOOGLE_CACHE_TIME_ZONE_TTL" default:"720h"`
+}
+
+type GeoConfig struct {
+	// Provider of place search and routing, one of google or osm. Osm has
+	// no time zone lookup, trips are planned in UTC unless created with a zone.
+	Provider string `envconfig:"GEO_PROVIDER" default:"google"`
+	// NominatimURL and OSRMURL are the osm services, self-hosted or stand-ins.
+	NominatimURL string `envconfig:"NOMINATIM_URL" default:"https://nominatim.openstreetmap.org"`
+	OSRMURL      string `envconfig:"OSRM_URL" default:"https://router.project-osrm.org"`
+	// UserAgent identifies the application to the nominatim.
+	UserAgent string `envconfig:"OSM_USER_AGENT" default:"Roamly-backend"`
+}
+
+type PhotoConfig struct {
+	// Storage of fetched place photos, one of local or s3.
+	Storage string `envconfig:"PHOTO_STORAGE" default:"local"`
+	// Dir is the directory of the local storage.
+	Dir string `envconfig:"PHOTO_DIR" default:"photos"`
+	// MaxAge is how long clients may cache photos.
+	MaxAge time.Duration `envconfig:"PHOTO_MAX_AGE" default:"720h"`
+	// FetchTimeout limits fetching and storing of a photo missing in the storage.
+	FetchTimeout time.Duration `envconfig:"PHOTO_FETCH_TIMEOUT" default:"30s"`
+
+	S3Endpoint  string `envconfig:"PHOTO_S3_ENDPOINT"`
+	S3Bucket    string `envconfig:"PHOTO_S3_BUCKET"`
+	S3Region    string `envconfig:"PHOTO_S3_REGION" default:"us-east-1"`
+	S3AccessKey string `envconfig:"PHOTO_S3_ACCESS_KEY"`
+	S3SecretKey string `envconfig:"PHOTO_S3_SECRET_KEY"`
+}
+
+type LLMConfig struct {
+	// Provider of the models, one of openai, ollama or fake.
+	Provider  string `envconfig:"LLM_PROVIDER" default:"openai"`
+	OpenAIURL string `envconfig:"OPENAI_URL" default:"https://api.openai.com/v1"`
+	OpenAIKey string `envconfig:"OPEN_AI_KEY"`
+	OllamaURL string `envconfig:"OLLAMA_URL" default:"http://localhost:11434"`
+
+	// Models of the use cases at the provider, empty ones fall back to the chat model.
+	ScheduleModel string `envconfig:"LLM_MODEL_SCHEDULE" default:"gpt-4o"`
+	ChatModel     string `envconfig:"LLM_MODEL_CHAT" default:"gpt-4o"`
+	DurationModel string `envconfig:"LLM_MODEL_DURATION" default:"gpt-4o-mini"`
+	PlacesModel   string `envconfig:"LLM_MODEL_PLACES" default:"gpt-4o-mini"`
+}
+
 func LoadConfig() *Config {
 	err := godotenv.Load()
 	if err != nil {
@@ -16,7 +16,7 @@ func InitLogger(config *config.Config) *logrus.Logger {
 
 	logLevel, err := logrus.ParseLevel(strings.ToLower(config.LogLevel))
 	if err != nil {
-		logger.Warn("Invalid log level '%s', defaulting to 'info'", config.LogLevel)
+		logger.Warnf("Invalid log level '%s', defaulting to 'info'", config.LogLevel)
 		logLevel = logrus.InfoLevel
 	}
 
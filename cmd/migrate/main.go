@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/ShelbyKS/Roamly-backend/app/config"
+	"github.com/ShelbyKS/Roamly-backend/internal/database/migrator"
+	"github.com/ShelbyKS/Roamly-backend/migrations"
+)
+
+const usage = `usage: migrate <command>
+
+commands:
+  up       apply all pending migrations
+  down     roll back the latest applied migration
+  status   list migrations and whether they are applied
+  version  print the current schema version`
+
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+
+	appCfg := config.LoadConfig()
+
+	pgDB, err := gorm.Open(postgres.Open(appCfg.GetPostgresCfg()), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		log.Fatalf("Failed to connect to postgres: %v", err)
+	}
+
+	sqlDB, err := pgDB.DB()
+	if err != nil {
+		log.Fatalf("Failed to get db connection: %v", err)
+	}
+	defer sqlDB.Close()
+
+	m, err := migrator.New(sqlDB, migrations.FS)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	switch os.Args[1] {
+	case "up":
+		applied, err := m.Up(ctx)
+		for _, migration := range applied {
+			fmt.Printf("applied %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("no pending migrations")
+		}
+	case "down":
+		migration, err := m.Down(ctx)
+		if errors.Is(err, migrator.ErrNoMigrations) {
+			fmt.Println(err)
+			return
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("rolled back %04d_%s\n", migration.Version, migration.Name)
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied {
+				state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%-40s %s\n", status.Migration.Version, status.Migration.Name, state)
+		}
+	case "version":
+		version, err := m.Version(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("current: %d, latest: %d\n", version, m.Latest())
+	default:
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+}
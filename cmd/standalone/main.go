@@ -0,0 +1,36 @@
+// Command standalone runs the API and the notifier in one process, so the
+// memory broker (BROKER=memory) can be used for local development.
+package main
+
+import (
+	"sync"
+
+	"github.com/ShelbyKS/Roamly-backend/app"
+	"github.com/ShelbyKS/Roamly-backend/app/config"
+	"github.com/ShelbyKS/Roamly-backend/app/logger"
+	"github.com/ShelbyKS/Roamly-backend/notifier"
+	notifierConfig "github.com/ShelbyKS/Roamly-backend/notifier/config"
+)
+
+func main() {
+	appCfg := config.LoadConfig()
+	appCfg.Broker.SingleProcess = true
+
+	notifierCfg := notifierConfig.LoadConfig()
+	notifierCfg.BrokerConfig.SingleProcess = true
+
+	lg := logger.InitLogger(appCfg)
+
+	// both stop on SIGINT or SIGTERM
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		notifier.New(notifierCfg).Run()
+	}()
+	go func() {
+		defer wg.Done()
+		app.New(appCfg, lg).Run()
+	}()
+	wg.Wait()
+}
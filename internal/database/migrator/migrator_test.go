@@ -0,0 +1,412 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// fakeState is the database of fakeDB: schema_migrations and the statements
+// of migrations executed so far.
+type fakeState struct {
+	versionTable bool
+	applied      map[int64]time.Time
+	statements   []string
+}
+
+func (s fakeState) clone() fakeState {
+	s.applied = maps.Clone(s.applied)
+	s.statements = slices.Clone(s.statements)
+	return s
+}
+
+// fakeDB understands the queries of the migrator. Migration bodies are
+// statements separated by ";", the "FAIL" statement fails.
+type fakeDB struct {
+	mu    sync.Mutex
+	state fakeState
+}
+
+func newFakeDB() *fakeDB {
+	return &fakeDB{state: fakeState{applied: make(map[int64]time.Time)}}
+}
+
+func (db *fakeDB) Connect(ctx context.Context) (driver.Conn, error) {
+	return &fakeConn{db: db}, nil
+}
+
+func (db *fakeDB) Driver() driver.Driver {
+	return nil
+}
+
+func (db *fakeDB) snapshot() fakeState {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.state.clone()
+}
+
+type fakeConn struct {
+	db *fakeDB
+	// tx is the state changed by the transaction, nil outside of it
+	tx *fakeState
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("prepared statements are not supported")
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	state := c.db.snapshot()
+	c.tx = &state
+	return c, nil
+}
+
+func (c *fakeConn) Commit() error {
+	c.db.mu.Lock()
+	c.db.state = *c.tx
+	c.db.mu.Unlock()
+	c.tx = nil
+	return nil
+}
+
+func (c *fakeConn) Rollback() error {
+	c.tx = nil
+	return nil
+}
+
+// do runs the query on the transaction state or, outside of it, right on the database.
+func (c *fakeConn) do(query string, args []driver.NamedValue) ([]driver.Value, error) {
+	if c.tx != nil {
+		return c.run(c.tx, query, args)
+	}
+
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	return c.run(&c.db.state, query, args)
+}
+
+func (c *fakeConn) run(state *fakeState, query string, args []driver.NamedValue) ([]driver.Value, error) {
+	query = strings.TrimSpace(query)
+	errNoTable := errors.New(`relation "schema_migrations" does not exist`)
+
+	switch {
+	case strings.HasPrefix(query, "CREATE TABLE IF NOT EXISTS schema_migrations"):
+		state.versionTable = true
+		return nil, nil
+	case strings.HasPrefix(query, "SELECT to_regclass('schema_migrations')"):
+		return []driver.Value{state.versionTable}, nil
+	case strings.HasPrefix(query, "SELECT pg_advisory_xact_lock"):
+		if c.tx == nil {
+			return nil, errors.New("advisory lock outside of a transaction")
+		}
+		return []driver.Value{nil}, nil
+	}
+
+	if !state.versionTable {
+		return nil, errNoTable
+	}
+
+	switch {
+	case strings.HasPrefix(query, "SELECT max(version)"):
+		var latest driver.Value
+		for version := range state.applied {
+			if latest == nil || version > latest.(int64) {
+				latest = version
+			}
+		}
+		return []driver.Value{latest}, nil
+	case strings.HasPrefix(query, "SELECT version, applied_at"):
+		var values []driver.Value
+		for version, appliedAt := range state.applied {
+			values = append(values, version, appliedAt)
+		}
+		return values, nil
+	case strings.HasPrefix(query, "SELECT EXISTS"):
+		_, ok := state.applied[args[0].Value.(int64)]
+		return []driver.Value{ok}, nil
+	case strings.HasPrefix(query, "INSERT INTO schema_migrations"):
+		state.applied[args[0].Value.(int64)] = time.Now()
+		return nil, nil
+	case strings.HasPrefix(query, "DELETE FROM schema_migrations"):
+		delete(state.applied, args[0].Value.(int64))
+		return nil, nil
+	}
+
+	for _, statement := range strings.Split(query, ";") {
+		statement = strings.TrimSpace(statement)
+		if statement == "FAIL" {
+			return nil, errors.New("syntax error")
+		}
+		if statement != "" {
+			state.statements = append(state.statements, statement)
+		}
+	}
+	return nil, nil
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	_, err := c.do(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	values, err := c.do(query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := []string{"value"}
+	if strings.HasPrefix(strings.TrimSpace(query), "SELECT version, applied_at") {
+		columns = []string{"version", "applied_at"}
+	}
+	return &fakeRows{columns: columns, values: values}, nil
+}
+
+type fakeRows struct {
+	columns []string
+	values  []driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.columns
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	n := copy(dest, r.values)
+	r.values = r.values[n:]
+	return nil
+}
+
+var testMigrations = fstest.MapFS{
+	"10_events.up.sql":    {Data: []byte("CREATE TABLE events")},
+	"10_events.down.sql":  {Data: []byte("DROP TABLE events")},
+	"1_users.up.sql":      {Data: []byte("CREATE TABLE users")},
+	"1_users.down.sql":    {Data: []byte("DROP TABLE users")},
+	"2_trips.up.sql":      {Data: []byte("CREATE TABLE trips; CREATE INDEX trips_name")},
+	"2_trips.down.sql":    {Data: []byte("DROP TABLE trips")},
+	"README.md":           {Data: []byte("not a migration")},
+	"3_notes.up.sql.orig": {Data: []byte("FAIL")},
+}
+
+func newTestMigrator(t *testing.T, fsys fstest.MapFS) (*Migrator, *fakeDB) {
+	fake := newFakeDB()
+	db := sql.OpenDB(fake)
+	t.Cleanup(func() { db.Close() })
+
+	m, err := New(db, fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m, fake
+}
+
+func versions(migrations []Migration) []int64 {
+	result := make([]int64, len(migrations))
+	for i, migration := range migrations {
+		result[i] = migration.Version
+	}
+	return result
+}
+
+func appliedVersions(state fakeState) []int64 {
+	return slices.Sorted(maps.Keys(state.applied))
+}
+
+func TestLoad(t *testing.T) {
+	migrations, err := load(testMigrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := versions(migrations); !slices.Equal(got, []int64{1, 2, 10}) {
+		t.Errorf("versions %v, want [1 2 10]", got)
+	}
+	if migrations[0].Name != "users" || migrations[0].Up != "CREATE TABLE users" || migrations[0].Down != "DROP TABLE users" {
+		t.Errorf("first migration %+v", migrations[0])
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+	}{
+		{
+			name: "no up file",
+			fsys: fstest.MapFS{"1_users.down.sql": {Data: []byte("DROP TABLE users")}},
+		},
+		{
+			name: "different names of a version",
+			fsys: fstest.MapFS{
+				"1_users.up.sql":     {Data: []byte("CREATE TABLE users")},
+				"1_members.down.sql": {Data: []byte("DROP TABLE members")},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := load(tt.fsys); err == nil {
+				t.Error("no error")
+			}
+		})
+	}
+}
+
+func TestUp(t *testing.T) {
+	m, db := newTestMigrator(t, testMigrations)
+
+	done, err := m.Up(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := versions(done); !slices.Equal(got, []int64{1, 2, 10}) {
+		t.Errorf("applied %v, want [1 2 10]", got)
+	}
+	state := db.snapshot()
+	want := []string{"CREATE TABLE users", "CREATE TABLE trips", "CREATE INDEX trips_name", "CREATE TABLE events"}
+	if !slices.Equal(state.statements, want) {
+		t.Errorf("executed %q, want %q", state.statements, want)
+	}
+	if got := appliedVersions(state); !slices.Equal(got, []int64{1, 2, 10}) {
+		t.Errorf("recorded %v, want [1 2 10]", got)
+	}
+
+	version, err := m.Version(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != m.Latest() || version != 10 {
+		t.Errorf("version %d, want %d", version, m.Latest())
+	}
+}
+
+func TestUpSkipsApplied(t *testing.T) {
+	m, db := newTestMigrator(t, testMigrations)
+	db.state.versionTable = true
+	db.state.applied[1] = time.Now()
+	db.state.applied[2] = time.Now()
+
+	done, err := m.Up(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := versions(done); !slices.Equal(got, []int64{10}) {
+		t.Errorf("applied %v, want [10]", got)
+	}
+	if state := db.snapshot(); !slices.Equal(state.statements, []string{"CREATE TABLE events"}) {
+		t.Errorf("executed %q, want only the pending migration", state.statements)
+	}
+
+	done, err = m.Up(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(done) != 0 {
+		t.Errorf("applied %v again", versions(done))
+	}
+}
+
+func TestUpRollsBackFailedMigration(t *testing.T) {
+	fsys := maps.Clone(testMigrations)
+	fsys["2_trips.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE trips; FAIL")}
+	m, db := newTestMigrator(t, fsys)
+
+	done, err := m.Up(context.Background())
+	if err == nil {
+		t.Fatal("no error for a failed migration")
+	}
+
+	if got := versions(done); !slices.Equal(got, []int64{1}) {
+		t.Errorf("applied %v, want [1]", got)
+	}
+	state := db.snapshot()
+	// the statement before the failure is rolled back with its migration
+	if !slices.Equal(state.statements, []string{"CREATE TABLE users"}) {
+		t.Errorf("executed %q, want only the first migration", state.statements)
+	}
+	if got := appliedVersions(state); !slices.Equal(got, []int64{1}) {
+		t.Errorf("recorded %v, want [1]", got)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	m, db := newTestMigrator(t, testMigrations)
+
+	_, err := m.Check(context.Background())
+	if !errors.Is(err, ErrNotInitialized) {
+		t.Errorf("error %v, want %v", err, ErrNotInitialized)
+	}
+	if db.snapshot().versionTable {
+		t.Error("check created schema_migrations")
+	}
+
+	db.state.versionTable = true
+	db.state.applied[1] = time.Now()
+
+	pending, err := m.Check(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := versions(pending); !slices.Equal(got, []int64{2, 10}) {
+		t.Errorf("pending %v, want [2 10]", got)
+	}
+	state := db.snapshot()
+	if len(state.statements) != 0 || !slices.Equal(appliedVersions(state), []int64{1}) {
+		t.Errorf("check changed the database: %+v", state)
+	}
+}
+
+func TestDown(t *testing.T) {
+	m, db := newTestMigrator(t, testMigrations)
+
+	_, err := m.Down(context.Background())
+	if !errors.Is(err, ErrNoMigrations) {
+		t.Errorf("error %v, want %v", err, ErrNoMigrations)
+	}
+
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	migration, err := m.Down(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if migration.Version != 10 {
+		t.Errorf("rolled back %d, want 10", migration.Version)
+	}
+	state := db.snapshot()
+	if got := appliedVersions(state); !slices.Equal(got, []int64{1, 2}) {
+		t.Errorf("recorded %v, want [1 2]", got)
+	}
+	if last := state.statements[len(state.statements)-1]; last != "DROP TABLE events" {
+		t.Errorf("last statement %q, want the down migration", last)
+	}
+}
@@ -1,6 +1,8 @@
 package orm
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 )
 
@@ -12,6 +14,8 @@ type Event struct {
 	Place   Place
 	Trip    Trip `gorm:"constraint:OnDelete:CASCADE;"`
 
-	StartTime string `gorm:"type:TIME"`
-	EndTime   string `gorm:"type:TIME"`
+	StartTime time.Time `gorm:"type:timestamptz"`
+	EndTime   time.Time `gorm:"type:timestamptz"`
+	Version   int64     `gorm:"not null;default:1"`
+	Pinned    bool      `gorm:"not null;default:false"`
 }
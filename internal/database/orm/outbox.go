@@ -0,0 +1,17 @@
+package orm
+
+import "time"
+
+type OutboxMessage struct {
+	ID            int64      `gorm:"primaryKey"`
+	Payload       []byte     `gorm:"type:jsonb;not null"`
+	Attempts      int        `gorm:"not null;default:0"`
+	LastError     string     `gorm:"not null;default:''"`
+	NextAttemptAt time.Time  `gorm:"type:timestamptz;not null"`
+	SentAt        *time.Time `gorm:"type:timestamptz"`
+	CreatedAt     time.Time  `gorm:"type:timestamptz;not null"`
+}
+
+func (OutboxMessage) TableName() string {
+	return "outbox_messages"
+}
@@ -1,6 +1,10 @@
 package orm
 
-import "time"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 type Location struct {
 	Lat float64 `json:"lat"`
@@ -20,19 +24,32 @@ type GooglePlace struct {
 	Geometry         Geometry `gorm:"embedded"`
 	Name             string   `json:"name"`
 	Photos           []Photo  `gorm:"json"`
-	PlaceID          string   `json:"place_id"`
 	Rating           float64  `json:"rating"`
 	Types            []string `gorm:"json"`
 }
 
+type OpeningHours struct {
+	Weekday int    `json:"weekday"`
+	Opening string `json:"opening"`
+	Closing string `json:"closing"`
+}
+
 type Place struct {
-	ID    string  `gorm:"primary_key"`
-	Trips []*Trip `gorm:"many2many:trip_place;constraint:OnDelete:CASCADE;"`
-	// Closing time.Time
-	// Opening time.Time
+	ID           string             `gorm:"primary_key"`
+	ExternalRefs []PlaceExternalRef `gorm:"foreignKey:PlaceID;constraint:OnDelete:CASCADE,OnUpdate:CASCADE;"`
+	Trips        []*Trip            `gorm:"many2many:trip_place;constraint:OnDelete:CASCADE;"`
+	OpeningHours []OpeningHours     `gorm:"serializer:json"`
 	// Name        string
 	// Photo string
 	// Rating      float32
 	GooglePlace                 GooglePlace `gorm:"embedded"`
 	RecommendedVisitingDuration time.Duration
+	OwnerTripID                 *uuid.UUID
+	Notes                       string
+}
+
+type PlaceExternalRef struct {
+	PlaceID    string `gorm:"index"`
+	Provider   string `gorm:"primaryKey"`
+	ExternalID string `gorm:"primaryKey"`
 }
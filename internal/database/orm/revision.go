@@ -0,0 +1,17 @@
+package orm
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type TripRevision struct {
+	ID          int64     `gorm:"primaryKey"`
+	TripID      uuid.UUID `gorm:"type:uuid;not null;index"`
+	AuthorID    int       `gorm:"not null;default:0"`
+	Action      string    `gorm:"not null"`
+	BeforeState []byte    `gorm:"type:jsonb;not null"`
+	AfterState  []byte    `gorm:"type:jsonb;not null"`
+	CreatedAt   time.Time `gorm:"type:timestamptz;not null"`
+}
@@ -0,0 +1,14 @@
+package orm
+
+import "github.com/google/uuid"
+
+type TravelLeg struct {
+	ID              uuid.UUID `gorm:"primaryKey"`
+	TripID          uuid.UUID `gorm:"index"`
+	FromEventID     uuid.UUID
+	ToEventID       uuid.UUID
+	Mode            string
+	CustomMode      bool
+	DurationSeconds int64
+	DistanceMeters  int64
+}
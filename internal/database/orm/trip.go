@@ -1,21 +1,28 @@
 package orm
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 )
 
 type Trip struct {
 	ID                uuid.UUID `gorm:"primaryKey"`
 	Name              string
-	StartTime         string `gorm:"type:TIME"`
-	EndTime           string `gorm:"type:TIME"`
+	StartTime         time.Time `gorm:"type:timestamptz"`
+	EndTime           time.Time `gorm:"type:timestamptz"`
+	TimeZone          string
+	DatesUnknown      bool   `gorm:"not null;default:false"`
+	TravelMode        string `gorm:"not null;default:driving"`
 	AreaID            string
+	Version           int64 `gorm:"not null;default:1"`
 	Area              Place
 	Users             []*User         `gorm:"many2many:trip_users;constraint:OnDelete:CASCADE;"`
 	TripUsers         []TripUsers     `gorm:"foreignKey:TripID"`
 	Places            []*Place        `gorm:"many2many:trip_place;constraint:OnDelete:CASCADE;"`
 	RecommendedPlaces []*Place        `gorm:"many2many:trip_recommended_place;constraint:OnDelete:CASCADE;"`
 	Events            []Event         `gorm:"constraint:OnDelete:CASCADE;"`
+	Legs              []TravelLeg     `gorm:"constraint:OnDelete:CASCADE;"`
 	Invites           []Invite        `gorm:"foreignKey:TripID;constraint:OnDelete:CASCADE;"`
 	Messages          []AIChatMessage `gorm:"constraint:OnDelete:CASCADE;"`
 }
@@ -0,0 +1,67 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ShelbyKS/Roamly-backend/internal/domain"
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/storage"
+)
+
+// FSStorage keeps blobs as files under a local directory.
+type FSStorage struct {
+	dir string
+}
+
+func NewFSStorage(dir string) storage.IBlobStorage {
+	return &FSStorage{
+		dir: dir,
+	}
+}
+
+func (s *FSStorage) path(key string) string {
+	return filepath.Join(s.dir, filepath.FromSlash(filepath.Clean("/"+key)))
+}
+
+func (s *FSStorage) Get(ctx context.Context, key string) ([]byte, error) {
+	data, err := os.ReadFile(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, domain.ErrBlobNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blob %s: %w", key, err)
+	}
+
+	return data, nil
+}
+
+// Put writes to a temporary file first, so readers never see a partial blob.
+func (s *FSStorage) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	path := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create blob dir: %w", err)
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), ".blob-*")
+	if err != nil {
+		return fmt.Errorf("failed to create blob %s: %w", key, err)
+	}
+	defer os.Remove(file.Name())
+
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write blob %s: %w", key, err)
+	}
+
+	if err := os.Rename(file.Name(), path); err != nil {
+		return fmt.Errorf("failed to save blob %s: %w", key, err)
+	}
+
+	return nil
+}
@@ -31,7 +31,7 @@ func (storage *AIChatStorage) SaveAIChatMessage(ctx context.Context, message mod
 		CreatedAt: time.Now(),
 	}
 
-	err := storage.db.WithContext(ctx).Create(&messageDB).Error
+	err := conn(ctx, storage.db).Create(&messageDB).Error
 	if err != nil {
 		log.Fatalf("failed to save ai chat message to trip: %v", err)
 	}
@@ -42,7 +42,7 @@ func (storage *AIChatStorage) SaveAIChatMessage(ctx context.Context, message mod
 func (storage *AIChatStorage) GetMessagesByTripID(ctx context.Context, tripID uuid.UUID) ([]model.ChatMessage, error) {
 	var messagesDB []orm.AIChatMessage
 
-	err := storage.db.WithContext(ctx).
+	err := conn(ctx, storage.db).
 		Where("trip_id = ?", tripID).
 		Order("created_at ASC").
 		Find(&messagesDB).Error
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"github.com/ShelbyKS/Roamly-backend/internal/database/orm"
 	"github.com/ShelbyKS/Roamly-backend/internal/domain/model"
+	"github.com/google/uuid"
 	"time"
 )
 
@@ -68,7 +69,11 @@ func (TripConverter) ToDb(trip model.Trip) orm.Trip {
 		Users:             users,
 		StartTime:         trip.StartTime,
 		EndTime:           trip.EndTime,
+		TimeZone:          trip.TimeZone,
+		DatesUnknown:      trip.DatesUnknown,
+		TravelMode:        trip.TravelMode,
 		AreaID:            trip.AreaID,
+		Version:           trip.Version,
 		Places:            tripPlaces,
 		RecommendedPlaces: tripRecommendedPlaces,
 		Events:            tripEvents,
@@ -110,6 +115,11 @@ func (TripConverter) ToDomain(trip orm.Trip) model.Trip {
 		events[i] = EventConverter{}.ToDomain(event)
 	}
 
+	legs := make([]model.TravelLeg, len(trip.Legs))
+	for i, leg := range trip.Legs {
+		legs[i] = TravelLegConverter{}.ToDomain(leg)
+	}
+
 	area := PlaceConverter{}.ToDomain(trip.Area)
 
 	return model.Trip{
@@ -118,11 +128,16 @@ func (TripConverter) ToDomain(trip orm.Trip) model.Trip {
 		Users:             users,
 		StartTime:         trip.StartTime,
 		EndTime:           trip.EndTime,
+		TimeZone:          trip.TimeZone,
+		DatesUnknown:      trip.DatesUnknown,
+		TravelMode:        trip.TravelMode,
 		AreaID:            trip.AreaID,
+		Version:           trip.Version,
 		Area:              &area,
 		Places:            tripPlaces,
 		RecommendedPlaces: tripRecommendedPlaces,
 		Events:            events,
+		Legs:              legs,
 	}
 }
 
@@ -135,16 +150,40 @@ func (PlaceConverter) ToDb(place model.Place) orm.Place {
 		trips = append(trips, &trip)
 	}
 
+	var openingHours []orm.OpeningHours
+	if place.OpeningHours != nil {
+		openingHours = make([]orm.OpeningHours, len(place.OpeningHours))
+		for i, hours := range place.OpeningHours {
+			openingHours[i] = orm.OpeningHours{
+				Weekday: int(hours.Weekday),
+				Opening: hours.Opening,
+				Closing: hours.Closing,
+			}
+		}
+	}
+
+	var ownerTripID *uuid.UUID
+	if place.IsCustom() {
+		ownerTripID = &place.OwnerTripID
+	}
+
+	externalRefs := make([]orm.PlaceExternalRef, len(place.ExternalRefs))
+	for i, ref := range place.ExternalRefs {
+		externalRefs[i] = ExternalRefConverter{}.ToDb(place.ID, ref)
+	}
+
 	return orm.Place{
-		ID: place.ID,
+		ID:           place.ID,
+		ExternalRefs: externalRefs,
 		// Photo:       place.Photo,
 		// Name:        place.Name,
-		// Closing:     place.Closing,
-		// Opening:     place.Opening,
 		// Rating:      place.Rating,
 		Trips:                       trips,
+		OpeningHours:                openingHours,
 		GooglePlace:                 GooglePlaceConverter{}.ToDb(place.GooglePlace),
 		RecommendedVisitingDuration: time.Duration(place.RecommendedVisitingDuration) * time.Minute,
+		OwnerTripID:                 ownerTripID,
+		Notes:                       place.Notes,
 	}
 }
 
@@ -155,16 +194,57 @@ func (PlaceConverter) ToDomain(place orm.Place) model.Place {
 		trips = append(trips, &trip)
 	}
 
+	var openingHours []model.OpeningHours
+	if place.OpeningHours != nil {
+		openingHours = make([]model.OpeningHours, len(place.OpeningHours))
+		for i, hours := range place.OpeningHours {
+			openingHours[i] = model.OpeningHours{
+				Weekday: time.Weekday(hours.Weekday),
+				Opening: hours.Opening,
+				Closing: hours.Closing,
+			}
+		}
+	}
+
+	var ownerTripID uuid.UUID
+	if place.OwnerTripID != nil {
+		ownerTripID = *place.OwnerTripID
+	}
+
+	externalRefs := make([]model.ExternalRef, len(place.ExternalRefs))
+	for i, ref := range place.ExternalRefs {
+		externalRefs[i] = ExternalRefConverter{}.ToDomain(ref)
+	}
+
 	return model.Place{
-		ID: place.ID,
+		ID:           place.ID,
+		ExternalRefs: externalRefs,
 		// Photo:       place.Photo,
 		// Name:        place.Name,
-		// Closing:     place.Closing,
-		// Opening:     place.Opening,
 		// Rating:      place.Rating,
 		Trips:                       trips,
+		OpeningHours:                openingHours,
 		GooglePlace:                 GooglePlaceConverter{}.ToDomain(place.GooglePlace),
 		RecommendedVisitingDuration: int(place.RecommendedVisitingDuration.Minutes()),
+		OwnerTripID:                 ownerTripID,
+		Notes:                       place.Notes,
+	}
+}
+
+type ExternalRefConverter struct{}
+
+func (ExternalRefConverter) ToDb(placeID string, ref model.ExternalRef) orm.PlaceExternalRef {
+	return orm.PlaceExternalRef{
+		PlaceID:    placeID,
+		Provider:   ref.Provider,
+		ExternalID: ref.ExternalID,
+	}
+}
+
+func (ExternalRefConverter) ToDomain(ref orm.PlaceExternalRef) model.ExternalRef {
+	return model.ExternalRef{
+		Provider:   ref.Provider,
+		ExternalID: ref.ExternalID,
 	}
 }
 
@@ -195,7 +275,6 @@ func (GooglePlaceConverter) ToDb(gp model.GooglePlace) orm.GooglePlace {
 		Geometry:         GeometryConverter{}.ToDb(gp.Geometry),
 		Photos:           photos,
 		Name:             gp.Name,
-		PlaceID:          gp.PlaceID,
 		Rating:           gp.Rating,
 		Types:            gp.Types,
 	}
@@ -212,7 +291,6 @@ func (GooglePlaceConverter) ToDomain(gp orm.GooglePlace) model.GooglePlace {
 		Geometry:         GeometryConverter{}.ToDomain(gp.Geometry),
 		Photos:           photos,
 		Name:             gp.Name,
-		PlaceID:          gp.PlaceID,
 		Rating:           gp.Rating,
 		Types:            gp.Types,
 	}
@@ -294,6 +372,8 @@ func (EventConverter) ToDb(event model.Event) orm.Event {
 		TripID:    event.TripID,
 		StartTime: event.StartTime,
 		EndTime:   event.EndTime,
+		Version:   event.Version,
+		Pinned:    event.Pinned,
 	}
 }
 
@@ -305,6 +385,8 @@ func (EventConverter) ToDomain(event orm.Event) model.Event {
 		TripID:    event.TripID,
 		StartTime: event.StartTime,
 		EndTime:   event.EndTime,
+		Version:   event.Version,
+		Pinned:    event.Pinned,
 	}
 }
 
@@ -28,7 +28,7 @@ func (storage *EventStorage) GetEventByID(ctx context.Context, eventID uuid.UUID
 		ID: eventID,
 	}
 
-	tx := storage.db.WithContext(ctx).
+	tx := conn(ctx, storage.db).
 		//Preload("Place").
 		//Preload("Trip").
 		First(&event)
@@ -49,7 +49,7 @@ func (storage *EventStorage) DeleteEvent(ctx context.Context, eventID uuid.UUID)
 		ID: eventID,
 	}
 
-	tx := storage.db.WithContext(ctx).Delete(&event)
+	tx := conn(ctx, storage.db).Delete(&event)
 
 	if tx.Error != nil {
 		return tx.Error
@@ -64,22 +64,25 @@ func (storage *EventStorage) DeleteEvent(ctx context.Context, eventID uuid.UUID)
 func (storage *EventStorage) CreateEvent(ctx context.Context, event model.Event) error {
 	eventDb := EventConverter{}.ToDb(event)
 
-	tx := storage.db.WithContext(ctx).Create(&eventDb)
+	tx := conn(ctx, storage.db).Create(&eventDb)
 
 	return tx.Error
 }
 
 func (storage *EventStorage) UpdateEvent(ctx context.Context, event model.Event) (model.Event, error) {
 	eventDb := EventConverter{}.ToDb(event)
-	tx := storage.db.WithContext(ctx).
-		Model(&orm.Event{ID: eventDb.ID}).
-		Updates(eventDb)
 
-	if tx.Error != nil {
-		return model.Event{}, tx.Error
-	}
-	if tx.RowsAffected == 0 {
-		return model.Event{}, domain.ErrEventNotFound
+	err := conn(ctx, storage.db).Transaction(func(tx *gorm.DB) error {
+		if err := bumpVersion(tx, &orm.Event{}, eventDb.ID, event.Version, domain.ErrEventNotFound); err != nil {
+			return err
+		}
+
+		return tx.Model(&orm.Event{ID: eventDb.ID}).
+			Omit("version").
+			Updates(eventDb).Error
+	})
+	if err != nil {
+		return model.Event{}, err
 	}
 
 	return EventConverter{}.ToDomain(eventDb), nil
@@ -94,21 +97,41 @@ func (storage *EventStorage) CreateBatchEvents(ctx context.Context, events *[]mo
 		eventsDb = append(eventsDb, EventConverter{}.ToDb(event))
 	}
 
-	tx := storage.db.WithContext(ctx).Create(&eventsDb)
+	tx := conn(ctx, storage.db).Create(&eventsDb)
 
 	return tx.Error
 }
 
-func (storage *EventStorage) DeleteEventsByTrip(ctx context.Context, tripID uuid.UUID) error {
-	tx := storage.db.WithContext(ctx).
-		Where("trip_id = ?", tripID).
-		Delete(&orm.Event{})
+func (storage *EventStorage) SetEventPinned(ctx context.Context, eventID uuid.UUID, pinned bool, version int64) (model.Event, error) {
+	err := conn(ctx, storage.db).Transaction(func(tx *gorm.DB) error {
+		if err := bumpVersion(tx, &orm.Event{}, eventID, version, domain.ErrEventNotFound); err != nil {
+			return err
+		}
+
+		return tx.Model(&orm.Event{ID: eventID}).
+			Update("pinned", pinned).Error
+	})
+	if err != nil {
+		return model.Event{}, err
+	}
+
+	return storage.GetEventByID(ctx, eventID)
+}
+
+func (storage *EventStorage) DeleteEventsByTrip(ctx context.Context, tripID uuid.UUID, keepPinned bool) error {
+	query := conn(ctx, storage.db).
+		Where("trip_id = ?", tripID)
+	if keepPinned {
+		query = query.Where("pinned = ?", false)
+	}
+
+	tx := query.Delete(&orm.Event{})
 
 	return tx.Error
 }
 
 func (storage *EventStorage) DeleteEventsByPlace(ctx context.Context, tripID uuid.UUID, placeID string) error {
-	tx := storage.db.WithContext(ctx).
+	tx := conn(ctx, storage.db).
 		Where("trip_id = ?", tripID).
 		Where("place_id = ?", placeID).
 		Delete(&orm.Event{})
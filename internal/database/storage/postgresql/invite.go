@@ -27,7 +27,7 @@ func NewInviteStorage(db *gorm.DB) storage.IInviteStorage {
 func (storage *InviteStorage) GetInviteByTripAccess(ctx context.Context, invite model.Invite) (model.Invite, error) {
 	inviteDB := &orm.Invite{}
 
-	tx := storage.db.WithContext(ctx).
+	tx := conn(ctx, storage.db).
 		Model(&orm.Invite{}).
 		Where("trip_id = ? AND access = ?", invite.TripID, invite.Access).
 		First(inviteDB)
@@ -46,7 +46,7 @@ func (storage *InviteStorage) GetInviteByTripAccess(ctx context.Context, invite
 func (storage *InviteStorage) CreateInvite(ctx context.Context, invite model.Invite) error {
 	inviteDb := InviteConverter{}.ToDb(invite)
 
-	tx := storage.db.WithContext(ctx).Create(&inviteDb)
+	tx := conn(ctx, storage.db).Create(&inviteDb)
 
 	return tx.Error
 }
@@ -54,7 +54,7 @@ func (storage *InviteStorage) CreateInvite(ctx context.Context, invite model.Inv
 func (storage *InviteStorage) UpdateInviteByTripAccess(ctx context.Context, invite model.Invite) error {
 	inviteDb := InviteConverter{}.ToDb(invite)
 
-	tx := storage.db.WithContext(ctx).
+	tx := conn(ctx, storage.db).
 		Model(&orm.Invite{}).
 		Where("trip_id = ? AND access = ?", invite.TripID, invite.Access).
 		Updates(&inviteDb)
@@ -72,7 +72,7 @@ func (storage *InviteStorage) UpdateInviteByTripAccess(ctx context.Context, invi
 func (storage *InviteStorage) GetInvitesByTripID(ctx context.Context, tripID uuid.UUID) ([]model.Invite, error) {
 	var invitesDB []orm.Invite
 
-	tx := storage.db.WithContext(ctx).
+	tx := conn(ctx, storage.db).
 		Where("trip_id = ? AND enable = true", tripID).
 		Find(&invitesDB)
 
@@ -93,7 +93,7 @@ func (storage *InviteStorage) GetInviteByToken(ctx context.Context, token string
 
 	fmt.Println("TOKEN:", token)
 
-	tx := storage.db.WithContext(ctx).
+	tx := conn(ctx, storage.db).
 		Model(&orm.Invite{}).
 		Where("token = ?", token).
 		Preload("Trip").
@@ -123,13 +123,13 @@ func (storage *InviteStorage) JoinTripByInvite(ctx context.Context, invite model
 		UserRole: int(userRole),
 	}
 
-	tx := storage.db.WithContext(ctx).Create(&tripUser)
+	tx := conn(ctx, storage.db).Create(&tripUser)
 
 	return tx.Error
 }
 
 func (storage *InviteStorage) UpdateMember(ctx context.Context, tripID uuid.UUID, userID int, role model.UserTripRole) error {
-	tx := storage.db.WithContext(ctx).
+	tx := conn(ctx, storage.db).
 		Model(&orm.TripUsers{}).
 		Where("trip_id = ? AND user_id = ?", tripID, userID).
 		Updates(&orm.TripUsers{UserRole: int(role)})
@@ -138,7 +138,7 @@ func (storage *InviteStorage) UpdateMember(ctx context.Context, tripID uuid.UUID
 }
 
 func (storage *InviteStorage) DeleteMember(ctx context.Context, tripID uuid.UUID, userID int) error {
-	tx := storage.db.WithContext(ctx).
+	tx := conn(ctx, storage.db).
 		Delete(&orm.TripUsers{
 			UserID: userID,
 			TripID: tripID,
@@ -0,0 +1,116 @@
+package postgresql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/ShelbyKS/Roamly-backend/internal/database/orm"
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/model"
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/storage"
+)
+
+// maxErrorLength limits the broker error saved with a failed message.
+const maxErrorLength = 1024
+
+type OutboxStorage struct {
+	db *gorm.DB
+}
+
+func NewOutboxStorage(db *gorm.DB) storage.IOutboxStorage {
+	return &OutboxStorage{
+		db: db,
+	}
+}
+
+func (storage *OutboxStorage) Add(ctx context.Context, message model.NotifyMessage) error {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to serialize message: %w", err)
+	}
+
+	now := time.Now()
+	messageDB := orm.OutboxMessage{
+		Payload:       payload,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}
+
+	return conn(ctx, storage.db).Create(&messageDB).Error
+}
+
+func (storage *OutboxStorage) LockPending(ctx context.Context, limit int) ([]model.OutboxMessage, error) {
+	var messagesDB []orm.OutboxMessage
+
+	err := conn(ctx, storage.db).
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("sent_at IS NULL AND next_attempt_at <= ?", time.Now()).
+		// a message waits for the earlier ones of its trip, even while they
+		// back off, so the trip seq order holds for the notifier
+		Where(`NOT EXISTS (
+			SELECT 1 FROM outbox_messages earlier
+			WHERE earlier.sent_at IS NULL
+			  AND earlier.id < outbox_messages.id
+			  AND earlier.payload -> 'payload' ->> 'trip_id' = outbox_messages.payload -> 'payload' ->> 'trip_id')`).
+		Order("id").
+		Limit(limit).
+		Find(&messagesDB).Error
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]model.OutboxMessage, 0, len(messagesDB))
+	for _, messageDB := range messagesDB {
+		message := model.OutboxMessage{
+			ID:        messageDB.ID,
+			Attempts:  messageDB.Attempts,
+			CreatedAt: messageDB.CreatedAt,
+		}
+
+		err = json.Unmarshal(messageDB.Payload, &message.Message)
+		if err != nil {
+			return nil, fmt.Errorf("failed to deserialize outbox message %d: %w", messageDB.ID, err)
+		}
+
+		messages = append(messages, message)
+	}
+
+	return messages, nil
+}
+
+func (storage *OutboxStorage) MarkSent(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	return conn(ctx, storage.db).
+		Model(&orm.OutboxMessage{}).
+		Where("id IN ?", ids).
+		Update("sent_at", time.Now()).Error
+}
+
+func (storage *OutboxStorage) MarkFailed(ctx context.Context, id int64, nextAttemptAt time.Time, reason string) error {
+	if len(reason) > maxErrorLength {
+		reason = reason[:maxErrorLength]
+	}
+
+	return conn(ctx, storage.db).
+		Model(&orm.OutboxMessage{ID: id}).
+		Updates(map[string]interface{}{
+			"attempts":        gorm.Expr("attempts + 1"),
+			"next_attempt_at": nextAttemptAt,
+			"last_error":      reason,
+		}).Error
+}
+
+func (storage *OutboxStorage) DeleteSent(ctx context.Context, before time.Time) (int64, error) {
+	tx := conn(ctx, storage.db).
+		Where("sent_at IS NOT NULL AND sent_at < ?", before).
+		Delete(&orm.OutboxMessage{})
+
+	return tx.RowsAffected, tx.Error
+}
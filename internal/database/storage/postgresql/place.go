@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgconn"
@@ -29,7 +30,8 @@ func (storage *PlaceStorage) GetPlaceByID(ctx context.Context, placeID string) (
 	placeModel := &orm.Place{
 		ID: placeID,
 	}
-	res := storage.db.WithContext(ctx).
+	res := conn(ctx, storage.db).
+		Preload("ExternalRefs").
 		First(placeModel)
 
 	if errors.Is(res.Error, gorm.ErrRecordNotFound) {
@@ -45,24 +47,50 @@ func (storage *PlaceStorage) GetPlaceByID(ctx context.Context, placeID string) (
 	return place, nil
 }
 
+func (storage *PlaceStorage) GetPlaceByExternalID(ctx context.Context, provider string, externalID string) (model.Place, error) {
+	var ref orm.PlaceExternalRef
+	res := conn(ctx, storage.db).
+		Where("provider = ? AND external_id = ?", provider, externalID).
+		First(&ref)
+
+	if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+		return model.Place{}, domain.ErrPlaceNotFound
+	}
+
+	if res.Error != nil {
+		return model.Place{}, res.Error
+	}
+
+	return storage.GetPlaceByID(ctx, ref.PlaceID)
+}
+
 func (storage *PlaceStorage) CreatePlace(ctx context.Context, place *model.Place) (model.Place, error) {
 	placeModel := PlaceConverter{}.ToDb(*place)
 	// log.Println("in storage", placeModel)
 
-	res := storage.db.WithContext(ctx).Create(&placeModel)
-	if pgErr, ok := res.Error.(*pgconn.PgError); ok && pgErr.Code == "23505" {
+	// refs are created explicitly: gorm skips conflicting associations,
+	// and a place known by its external id must not be created twice
+	err := conn(ctx, storage.db).Transaction(func(tx *gorm.DB) error {
+		err := tx.Omit("ExternalRefs").Create(&placeModel).Error
+		if err != nil || len(placeModel.ExternalRefs) == 0 {
+			return err
+		}
+
+		return tx.Create(&placeModel.ExternalRefs).Error
+	})
+	if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == "23505" {
 		return model.Place{}, domain.ErrPlaceAlreadyExists
 	}
 
-	if res.Error != nil {
-		return model.Place{}, fmt.Errorf("create place in db: %w", res.Error)
+	if err != nil {
+		return model.Place{}, fmt.Errorf("create place in db: %w", err)
 	}
 
 	return *place, nil
 }
 
 func (storage *PlaceStorage) AppendPlaceToTrip(ctx context.Context, placeID string, tripID uuid.UUID) error {
-	err := storage.db.WithContext(ctx).
+	err := conn(ctx, storage.db).
 		Model(&orm.Trip{
 			ID: tripID,
 		}).
@@ -86,7 +114,7 @@ func (storage *PlaceStorage) DeletePlace(ctx context.Context, tripID uuid.UUID,
 		ID: placeID,
 	}
 
-	if err := storage.db.WithContext(ctx).Model(&trip).Association("Places").Delete(place); err != nil {
+	if err := conn(ctx, storage.db).Model(&trip).Association("Places").Delete(place); err != nil {
 		return err
 	}
 
@@ -102,7 +130,7 @@ func (storage *PlaceStorage) AppendPlace(ctx context.Context, tripID uuid.UUID,
 		ID: placeID,
 	}
 
-	if err := storage.db.WithContext(ctx).Model(&trip).Association("Places").Delete(place); err != nil {
+	if err := conn(ctx, storage.db).Model(&trip).Association("Places").Delete(place); err != nil {
 		return err
 	}
 
@@ -112,9 +140,34 @@ func (storage *PlaceStorage) AppendPlace(ctx context.Context, tripID uuid.UUID,
 func (storage *PlaceStorage) UpdatePlace(ctx context.Context, place model.Place) error {
 	placeDB := PlaceConverter{}.ToDb(place)
 
-	tx := storage.db.WithContext(ctx).
+	tx := conn(ctx, storage.db).
 		Model(&orm.Place{ID: place.ID}).
+		Omit("ExternalRefs").
 		Updates(&placeDB)
 
 	return tx.Error
 }
+
+func (storage *PlaceStorage) UpdateCustomPlace(ctx context.Context, place model.Place) error {
+	res := conn(ctx, storage.db).
+		Model(&orm.Place{}).
+		Where("id = ? AND owner_trip_id = ?", place.ID, place.OwnerTripID).
+		Updates(map[string]interface{}{
+			"name":                          place.GooglePlace.Name,
+			"formatted_address":             place.GooglePlace.FormattedAddress,
+			"lat":                           place.GooglePlace.Geometry.Location.Lat,
+			"lng":                           place.GooglePlace.Geometry.Location.Lng,
+			"notes":                         place.Notes,
+			"recommended_visiting_duration": time.Duration(place.RecommendedVisitingDuration) * time.Minute,
+		})
+
+	if res.Error != nil {
+		return fmt.Errorf("update custom place in db: %w", res.Error)
+	}
+
+	if res.RowsAffected == 0 {
+		return domain.ErrPlaceNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,247 @@
+package postgresql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/ShelbyKS/Roamly-backend/internal/database/orm"
+	"github.com/ShelbyKS/Roamly-backend/internal/domain"
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/model"
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/storage"
+)
+
+type RevisionStorage struct {
+	db *gorm.DB
+}
+
+func NewRevisionStorage(db *gorm.DB) storage.IRevisionStorage {
+	return &RevisionStorage{
+		db: db,
+	}
+}
+
+func (storage *RevisionStorage) Add(ctx context.Context, revision model.TripRevision) (model.TripRevision, error) {
+	revisionDB, err := RevisionConverter{}.ToDb(revision)
+	if err != nil {
+		return model.TripRevision{}, err
+	}
+	if revisionDB.CreatedAt.IsZero() {
+		revisionDB.CreatedAt = time.Now()
+	}
+
+	err = conn(ctx, storage.db).Create(&revisionDB).Error
+	if err != nil {
+		return model.TripRevision{}, err
+	}
+
+	revision.ID = revisionDB.ID
+	revision.CreatedAt = revisionDB.CreatedAt
+	return revision, nil
+}
+
+func (storage *RevisionStorage) GetByTrip(ctx context.Context, tripID uuid.UUID, limit, offset int) ([]model.TripRevision, error) {
+	var revisionsDB []orm.TripRevision
+
+	err := conn(ctx, storage.db).
+		Where("trip_id = ?", tripID).
+		Order("id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&revisionsDB).Error
+	if err != nil {
+		return nil, err
+	}
+
+	revisions := make([]model.TripRevision, len(revisionsDB))
+	for i, revisionDB := range revisionsDB {
+		revisions[i], err = RevisionConverter{}.ToDomain(revisionDB)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return revisions, nil
+}
+
+func (storage *RevisionStorage) GetByID(ctx context.Context, tripID uuid.UUID, revisionID int64) (model.TripRevision, error) {
+	var revisionDB orm.TripRevision
+
+	err := conn(ctx, storage.db).
+		Where("trip_id = ?", tripID).
+		First(&revisionDB, revisionID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.TripRevision{}, domain.ErrRevisionNotFound
+	}
+	if err != nil {
+		return model.TripRevision{}, err
+	}
+
+	return RevisionConverter{}.ToDomain(revisionDB)
+}
+
+func (storage *RevisionStorage) LatestID(ctx context.Context, tripID uuid.UUID) (int64, error) {
+	var id int64
+
+	err := conn(ctx, storage.db).
+		Model(&orm.TripRevision{}).
+		Where("trip_id = ?", tripID).
+		Select("COALESCE(MAX(id), 0)").
+		Scan(&id).Error
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// snapshotDB is the json of a trip snapshot kept in a revision.
+type snapshotDB struct {
+	PlaceIDs     []string          `json:"place_ids"`
+	Events       []snapshotEventDB `json:"events"`
+	CustomPlaces []snapshotPlaceDB `json:"custom_places,omitempty"`
+}
+
+type snapshotPlaceDB struct {
+	ID                          string  `json:"id"`
+	Name                        string  `json:"name"`
+	FormattedAddress            string  `json:"formatted_address"`
+	Lat                         float64 `json:"lat"`
+	Lng                         float64 `json:"lng"`
+	Notes                       string  `json:"notes,omitempty"`
+	RecommendedVisitingDuration int     `json:"recommended_visiting_duration"`
+}
+
+type snapshotEventDB struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	PlaceID   string    `json:"place_id"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	Version   int64     `json:"version"`
+	Pinned    bool      `json:"pinned,omitempty"`
+}
+
+type RevisionConverter struct{}
+
+func (RevisionConverter) ToDb(revision model.TripRevision) (orm.TripRevision, error) {
+	before, err := encodeSnapshot(revision.Before)
+	if err != nil {
+		return orm.TripRevision{}, err
+	}
+	after, err := encodeSnapshot(revision.After)
+	if err != nil {
+		return orm.TripRevision{}, err
+	}
+
+	return orm.TripRevision{
+		ID:          revision.ID,
+		TripID:      revision.TripID,
+		AuthorID:    revision.AuthorID,
+		Action:      revision.Action,
+		BeforeState: before,
+		AfterState:  after,
+		CreatedAt:   revision.CreatedAt,
+	}, nil
+}
+
+func (RevisionConverter) ToDomain(revision orm.TripRevision) (model.TripRevision, error) {
+	before, err := decodeSnapshot(revision.TripID, revision.BeforeState)
+	if err != nil {
+		return model.TripRevision{}, err
+	}
+	after, err := decodeSnapshot(revision.TripID, revision.AfterState)
+	if err != nil {
+		return model.TripRevision{}, err
+	}
+
+	return model.TripRevision{
+		ID:        revision.ID,
+		TripID:    revision.TripID,
+		AuthorID:  revision.AuthorID,
+		Action:    revision.Action,
+		Before:    before,
+		After:     after,
+		CreatedAt: revision.CreatedAt,
+	}, nil
+}
+
+func encodeSnapshot(snapshot model.TripSnapshot) ([]byte, error) {
+	snapshotJSON := snapshotDB{
+		PlaceIDs: snapshot.PlaceIDs,
+		Events:   make([]snapshotEventDB, len(snapshot.Events)),
+	}
+	for i, event := range snapshot.Events {
+		snapshotJSON.Events[i] = snapshotEventDB{
+			ID:        event.ID,
+			Name:      event.Name,
+			PlaceID:   event.PlaceID,
+			StartTime: event.StartTime,
+			EndTime:   event.EndTime,
+			Version:   event.Version,
+			Pinned:    event.Pinned,
+		}
+	}
+	for _, place := range snapshot.CustomPlaces {
+		snapshotJSON.CustomPlaces = append(snapshotJSON.CustomPlaces, snapshotPlaceDB{
+			ID:                          place.ID,
+			Name:                        place.GooglePlace.Name,
+			FormattedAddress:            place.GooglePlace.FormattedAddress,
+			Lat:                         place.GooglePlace.Geometry.Location.Lat,
+			Lng:                         place.GooglePlace.Geometry.Location.Lng,
+			Notes:                       place.Notes,
+			RecommendedVisitingDuration: place.RecommendedVisitingDuration,
+		})
+	}
+
+	data, err := json.Marshal(snapshotJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode trip snapshot: %w", err)
+	}
+
+	return data, nil
+}
+
+func decodeSnapshot(tripID uuid.UUID, data []byte) (model.TripSnapshot, error) {
+	var snapshotJSON snapshotDB
+	if err := json.Unmarshal(data, &snapshotJSON); err != nil {
+		return model.TripSnapshot{}, fmt.Errorf("failed to decode trip snapshot: %w", err)
+	}
+
+	snapshot := model.TripSnapshot{
+		PlaceIDs: snapshotJSON.PlaceIDs,
+		Events:   make([]model.Event, len(snapshotJSON.Events)),
+	}
+	for i, event := range snapshotJSON.Events {
+		snapshot.Events[i] = model.Event{
+			ID:        event.ID,
+			Name:      event.Name,
+			PlaceID:   event.PlaceID,
+			TripID:    tripID,
+			StartTime: event.StartTime,
+			EndTime:   event.EndTime,
+			Version:   event.Version,
+			Pinned:    event.Pinned,
+		}
+	}
+	for _, place := range snapshotJSON.CustomPlaces {
+		custom := model.Place{
+			ID:                          place.ID,
+			OwnerTripID:                 tripID,
+			RecommendedVisitingDuration: place.RecommendedVisitingDuration,
+			Notes:                       place.Notes,
+		}
+		custom.GooglePlace.Name = place.Name
+		custom.GooglePlace.FormattedAddress = place.FormattedAddress
+		custom.GooglePlace.Geometry.Location.Lat = place.Lat
+		custom.GooglePlace.Geometry.Location.Lng = place.Lng
+		snapshot.CustomPlaces = append(snapshot.CustomPlaces, custom)
+	}
+
+	return snapshot, nil
+}
@@ -0,0 +1,63 @@
+package postgresql
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/model"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	tripID := uuid.New()
+	custom := model.Place{
+		ID:                          "custom",
+		OwnerTripID:                 tripID,
+		RecommendedVisitingDuration: 90,
+		Notes:                       "ring twice",
+	}
+	custom.GooglePlace.Name = "Дача"
+	custom.GooglePlace.FormattedAddress = "Лесная, 1"
+	custom.GooglePlace.Geometry.Location.Lat = 55.5
+	custom.GooglePlace.Geometry.Location.Lng = 37.5
+
+	snapshot := model.TripSnapshot{
+		PlaceIDs: []string{"shared", "custom"},
+		Events: []model.Event{{
+			ID:        uuid.New(),
+			Name:      "dinner",
+			PlaceID:   "custom",
+			TripID:    tripID,
+			StartTime: time.Date(2024, time.June, 4, 18, 0, 0, 0, time.UTC),
+			EndTime:   time.Date(2024, time.June, 4, 20, 0, 0, 0, time.UTC),
+			Version:   3,
+		}},
+		CustomPlaces: []model.Place{custom},
+	}
+
+	data, err := encodeSnapshot(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := decodeSnapshot(tripID, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(decoded, snapshot) {
+		t.Errorf("decoded %+v, want %+v", decoded, snapshot)
+	}
+}
+
+func TestDecodeSnapshotWithoutCustomPlaces(t *testing.T) {
+	decoded, err := decodeSnapshot(uuid.New(), []byte(`{"place_ids":["a"],"events":[]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(decoded.CustomPlaces) != 0 {
+		t.Errorf("custom places %v in a snapshot without them", decoded.CustomPlaces)
+	}
+}
@@ -0,0 +1,36 @@
+package postgresql
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/storage"
+)
+
+type txKey struct{}
+
+type Transactor struct {
+	db *gorm.DB
+}
+
+func NewTransactor(db *gorm.DB) storage.ITransactor {
+	return &Transactor{
+		db: db,
+	}
+}
+
+func (t *Transactor) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return conn(ctx, t.db).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
+
+// conn returns the transaction started by Transactor.InTx for ctx,
+// or db itself outside of a transaction.
+func conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
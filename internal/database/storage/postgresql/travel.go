@@ -0,0 +1,88 @@
+package postgresql
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/ShelbyKS/Roamly-backend/internal/database/orm"
+	"github.com/ShelbyKS/Roamly-backend/internal/domain"
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/model"
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/storage"
+)
+
+type TravelLegStorage struct {
+	db *gorm.DB
+}
+
+func NewTravelLegStorage(db *gorm.DB) storage.ITravelLegStorage {
+	return &TravelLegStorage{
+		db: db,
+	}
+}
+
+func (storage *TravelLegStorage) ReplaceLegs(ctx context.Context, tripID uuid.UUID, legs []model.TravelLeg) error {
+	return conn(ctx, storage.db).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("trip_id = ?", tripID).Delete(&orm.TravelLeg{}).Error
+		if err != nil {
+			return err
+		}
+		if len(legs) == 0 {
+			return nil
+		}
+
+		legsDb := make([]orm.TravelLeg, len(legs))
+		for i, leg := range legs {
+			legsDb[i] = TravelLegConverter{}.ToDb(leg)
+		}
+
+		return tx.Create(&legsDb).Error
+	})
+}
+
+func (storage *TravelLegStorage) UpdateLeg(ctx context.Context, leg model.TravelLeg) error {
+	legDb := TravelLegConverter{}.ToDb(leg)
+
+	tx := conn(ctx, storage.db).
+		Model(&orm.TravelLeg{ID: leg.ID}).
+		Select("mode", "custom_mode", "duration_seconds", "distance_meters").
+		Updates(&legDb)
+	if tx.Error != nil {
+		return tx.Error
+	}
+	if tx.RowsAffected == 0 {
+		return domain.ErrTravelLegNotFound
+	}
+
+	return nil
+}
+
+type TravelLegConverter struct{}
+
+func (TravelLegConverter) ToDb(leg model.TravelLeg) orm.TravelLeg {
+	return orm.TravelLeg{
+		ID:              leg.ID,
+		TripID:          leg.TripID,
+		FromEventID:     leg.FromEventID,
+		ToEventID:       leg.ToEventID,
+		Mode:            leg.Mode,
+		CustomMode:      leg.CustomMode,
+		DurationSeconds: int64(leg.Duration / time.Second),
+		DistanceMeters:  leg.Distance,
+	}
+}
+
+func (TravelLegConverter) ToDomain(leg orm.TravelLeg) model.TravelLeg {
+	return model.TravelLeg{
+		ID:          leg.ID,
+		TripID:      leg.TripID,
+		FromEventID: leg.FromEventID,
+		ToEventID:   leg.ToEventID,
+		Mode:        leg.Mode,
+		CustomMode:  leg.CustomMode,
+		Duration:    time.Duration(leg.DurationSeconds) * time.Second,
+		Distance:    leg.DistanceMeters,
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"log"
 
 	"github.com/ShelbyKS/Roamly-backend/internal/domain"
@@ -29,14 +30,15 @@ func (storage *TripStorage) GetTripByID(ctx context.Context, id uuid.UUID) (mode
 		ID: id,
 	}
 
-	tx := storage.db.WithContext(ctx).
+	tx := conn(ctx, storage.db).
 		Model(&orm.Trip{}).
-		Preload("Area").
+		Preload("Area.ExternalRefs").
 		Preload("Users").
 		Preload("TripUsers").
-		Preload("Places").
-		Preload("RecommendedPlaces").
+		Preload("Places.ExternalRefs").
+		Preload("RecommendedPlaces.ExternalRefs").
 		Preload("Events").
+		Preload("Legs").
 		First(&trip)
 
 	if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
@@ -53,7 +55,7 @@ func (storage *TripStorage) GetTripByID(ctx context.Context, id uuid.UUID) (mode
 func (storage *TripStorage) GetTrips(ctx context.Context, userId int) ([]model.Trip, error) {
 	var user orm.User
 
-	err := storage.db.WithContext(ctx).
+	err := conn(ctx, storage.db).
 		Preload("Trips").
 		Preload("Trips.Area").
 		Preload("Trips.Users").
@@ -76,7 +78,7 @@ func (storage *TripStorage) DeleteTrip(ctx context.Context, id uuid.UUID) error
 		ID: id,
 	}
 
-	tx := storage.db.WithContext(ctx).Delete(&trip)
+	tx := conn(ctx, storage.db).Delete(&trip)
 
 	if tx.Error != nil {
 		return tx.Error
@@ -100,49 +102,60 @@ func (storage *TripStorage) CreateTrip(ctx context.Context, trip model.Trip, use
 
 	tripDb.Users = []*orm.User{}
 
-	tx := storage.db.WithContext(ctx).Begin()
-	if err := tx.Create(&tripDb).Error; err != nil {
-		tx.Rollback()
-		log.Println("create trip err:", err)
-		return err
-	}
+	return conn(ctx, storage.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&tripDb).Error; err != nil {
+			log.Println("create trip err:", err)
+			return err
+		}
 
-	if err := tx.Create(&tripUser).Error; err != nil {
-		tx.Rollback()
-		log.Println("create tripUser err:", err)
-		return err
-	}
+		if err := tx.Create(&tripUser).Error; err != nil {
+			log.Println("create tripUser err:", err)
+			return err
+		}
 
-	return tx.Commit().Error
+		return nil
+	})
 }
 
 func (storage *TripStorage) UpdateTrip(ctx context.Context, trip model.Trip) error {
 	tripDb := TripConverter{}.ToDb(trip)
 
-	tx := storage.db.WithContext(ctx).Begin()
-	defer tx.Rollback()
-
-	if err := tx.Model(&orm.Trip{ID: trip.ID}).Updates(&tripDb).Error; err != nil {
-		return err
-	}
-
-	if trip.RecommendedPlaces != nil {
-		err := tx.Model(&orm.Trip{ID: trip.ID}).
-			Association("RecommendedPlaces").
-			Append(tripDb.RecommendedPlaces)
+	return conn(ctx, storage.db).Transaction(func(tx *gorm.DB) error {
+		if err := bumpVersion(tx, &orm.Trip{}, trip.ID, trip.Version, domain.ErrTripNotFound); err != nil {
+			return err
+		}
 
-		if err != nil {
-			tx.Rollback()
+		if err := tx.Model(&orm.Trip{ID: trip.ID}).Omit("version").Updates(&tripDb).Error; err != nil {
 			return err
 		}
+
+		// dates of migrated trips are known once the user sets them
+		if !trip.StartTime.IsZero() && !trip.EndTime.IsZero() {
+			err := tx.Model(&orm.Trip{ID: trip.ID}).Update("dates_unknown", false).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (storage *TripStorage) AddRecommendedPlaces(ctx context.Context, tripID uuid.UUID, places []*model.Place) error {
+	if len(places) == 0 {
+		return nil
 	}
 
-	//if tx.RowsAffected == 0 {
-	//	return domain.ErrTripNotFound
-	//}
+	placesDb := make([]*orm.Place, 0, len(places))
+	for _, place := range places {
+		placeDb := PlaceConverter{}.ToDb(*place)
+		placesDb = append(placesDb, &placeDb)
+	}
 
-	tx.Commit()
-	return tx.Error
+	return conn(ctx, storage.db).
+		Model(&orm.Trip{ID: tripID}).
+		Association("RecommendedPlaces").
+		Append(placesDb)
 }
 
 func (storage *TripStorage) GetUserRole(ctx context.Context, userID int, tripID uuid.UUID) (model.UserTripRole, error) {
@@ -151,7 +164,7 @@ func (storage *TripStorage) GetUserRole(ctx context.Context, userID int, tripID
 		TripID: tripID,
 	}
 
-	err := storage.db.WithContext(ctx).
+	err := conn(ctx, storage.db).
 		First(&tripUser).Error
 
 	if err != nil {
@@ -166,8 +179,7 @@ func (storage *TripStorage) GetTripByEventID(ctx context.Context, eventID uuid.U
 		ID: eventID,
 	}
 
-	err := storage.db.
-		WithContext(ctx).
+	err := conn(ctx, storage.db).
 		Preload("Trip").
 		Preload("Trip.Users").
 		First(&event).Error
@@ -185,8 +197,7 @@ func (storage *TripStorage) RemoveUserFromTrip(ctx context.Context, userID int,
 		TripID: tripID,
 	}
 
-	err := storage.db.
-		WithContext(ctx).
+	err := conn(ctx, storage.db).
 		Delete(&tripUser).Error
 
 	if err != nil {
@@ -195,3 +206,34 @@ func (storage *TripStorage) RemoveUserFromTrip(ctx context.Context, userID int,
 
 	return nil
 }
+
+func (storage *TripStorage) LockTrip(ctx context.Context, tripID uuid.UUID) error {
+	var trip orm.Trip
+
+	err := conn(ctx, storage.db).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id").
+		First(&trip, "id = ?", tripID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return domain.ErrTripNotFound
+	}
+
+	return err
+}
+
+func (storage *TripStorage) NextNotifySeq(ctx context.Context, tripID uuid.UUID) (int64, error) {
+	var seq int64
+
+	tx := conn(ctx, storage.db).
+		Raw("UPDATE trips SET notify_seq = notify_seq + 1 WHERE id = ? RETURNING notify_seq", tripID).
+		Scan(&seq)
+	if tx.Error != nil {
+		return 0, tx.Error
+	}
+
+	if tx.RowsAffected == 0 {
+		return 0, domain.ErrTripNotFound
+	}
+
+	return seq, nil
+}
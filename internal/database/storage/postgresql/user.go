@@ -29,7 +29,7 @@ func (storage *UserStorage) GetUserByID(ctx context.Context, id int) (model.User
 		ID: id,
 	}
 
-	tx := storage.db.WithContext(ctx).First(&user)
+	tx := conn(ctx, storage.db).First(&user)
 	if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
 		tx.Error = errors.Join(domain.ErrUserNotFound, tx.Error)
 	}
@@ -48,7 +48,7 @@ func (storage *UserStorage) GetUserByID(ctx context.Context, id int) (model.User
 func (storage *UserStorage) GetUserByEmail(ctx context.Context, email string) (model.User, error) {
 	user := orm.User{}
 
-	res := storage.db.WithContext(ctx).
+	res := conn(ctx, storage.db).
 		Where(&orm.User{Email: email}).
 		First(&user)
 	if errors.Is(res.Error, gorm.ErrRecordNotFound) {
@@ -73,7 +73,7 @@ func (storage *UserStorage) CreateUser(ctx context.Context, user *model.User) er
 		Password: user.Password,
 	}
 
-	res := storage.db.WithContext(ctx).Create(&usrModel)
+	res := conn(ctx, storage.db).Create(&usrModel)
 	if res.Error != nil {
 		return fmt.Errorf("failed to create user: %s", res.Error)
 	}
@@ -85,7 +85,7 @@ func (storage *UserStorage) CreateUser(ctx context.Context, user *model.User) er
 }
 
 func (storage *UserStorage) UpdateUser(ctx context.Context, user model.User) error {
-	tx := storage.db.WithContext(ctx).
+	tx := conn(ctx, storage.db).
 		Model(&orm.User{ID: user.ID}).
 		Updates(&orm.User{
 			Login:    user.Login,
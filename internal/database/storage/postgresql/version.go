@@ -0,0 +1,37 @@
+package postgresql
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/ShelbyKS/Roamly-backend/internal/domain"
+)
+
+// bumpVersion increments the version of the row with the id if it still has
+// the expected one, expected 0 increments any version. It returns errNotFound
+// if there is no such row and domain.ErrVersionConflict if it was changed.
+func bumpVersion(tx *gorm.DB, model any, id uuid.UUID, expected int64, errNotFound error) error {
+	query := tx.Model(model).Where("id = ?", id)
+	if expected > 0 {
+		query = query.Where("version = ?", expected)
+	}
+
+	res := query.UpdateColumn("version", gorm.Expr("version + 1"))
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected > 0 {
+		return nil
+	}
+
+	var count int64
+	err := tx.Model(model).Where("id = ?", id).Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return errNotFound
+	}
+
+	return domain.ErrVersionConflict
+}
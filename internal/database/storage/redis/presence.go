@@ -0,0 +1,91 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/model"
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/storage"
+)
+
+// PresenceStorage keeps presences of a trip in a hash by connection id.
+// Entries not refreshed within ttl belong to crashed connections and are skipped.
+type PresenceStorage struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+func NewPresenceStorage(client *redis.Client, ttl time.Duration) storage.IPresenceStorage {
+	return &PresenceStorage{
+		client: client,
+		ttl:    ttl,
+	}
+}
+
+func presenceKey(tripID uuid.UUID) string {
+	return fmt.Sprintf("presence:trip:%s", tripID)
+}
+
+func (s *PresenceStorage) Set(ctx context.Context, presence model.Presence) error {
+	value, err := json.Marshal(presence)
+	if err != nil {
+		return fmt.Errorf("failed to encode presence: %w", err)
+	}
+
+	key := presenceKey(presence.TripID)
+
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, presence.ConnectionID, value)
+		pipe.Expire(ctx, key, s.ttl)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set presence: %w", err)
+	}
+
+	return nil
+}
+
+func (s *PresenceStorage) Remove(ctx context.Context, tripID uuid.UUID, connectionID string) error {
+	err := s.client.HDel(ctx, presenceKey(tripID), connectionID).Err()
+	if err != nil {
+		return fmt.Errorf("failed to remove presence: %w", err)
+	}
+
+	return nil
+}
+
+func (s *PresenceStorage) GetByTrip(ctx context.Context, tripID uuid.UUID) ([]model.Presence, error) {
+	key := presenceKey(tripID)
+
+	values, err := s.client.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get presences: %w", err)
+	}
+
+	presences := make([]model.Presence, 0, len(values))
+	var stale []string
+	for connectionID, value := range values {
+		var presence model.Presence
+		if err := json.Unmarshal([]byte(value), &presence); err != nil {
+			return nil, fmt.Errorf("failed to decode presence: %w", err)
+		}
+
+		if time.Since(presence.SeenAt) > s.ttl {
+			stale = append(stale, connectionID)
+			continue
+		}
+		presences = append(presences, presence)
+	}
+
+	if len(stale) > 0 {
+		s.client.HDel(ctx, key, stale...)
+	}
+
+	return presences, nil
+}
@@ -0,0 +1,70 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/ShelbyKS/Roamly-backend/internal/domain"
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/model"
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/storage"
+)
+
+type SchedulePreviewStorage struct {
+	client *redis.Client
+}
+
+func NewSchedulePreviewStorage(client *redis.Client) storage.ISchedulePreviewStorage {
+	return &SchedulePreviewStorage{
+		client: client,
+	}
+}
+
+func schedulePreviewKey(previewID uuid.UUID) string {
+	return fmt.Sprintf("schedule:preview:%s", previewID)
+}
+
+func (s *SchedulePreviewStorage) Add(ctx context.Context, preview model.SchedulePreview) error {
+	value, err := json.Marshal(preview)
+	if err != nil {
+		return fmt.Errorf("failed to encode schedule preview: %w", err)
+	}
+
+	err = s.client.Set(ctx, schedulePreviewKey(preview.ID), value, time.Until(preview.ExpiresAt)).Err()
+	if err != nil {
+		return fmt.Errorf("failed to add schedule preview: %w", err)
+	}
+
+	return nil
+}
+
+func (s *SchedulePreviewStorage) Get(ctx context.Context, previewID uuid.UUID) (model.SchedulePreview, error) {
+	value, err := s.client.Get(ctx, schedulePreviewKey(previewID)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return model.SchedulePreview{}, domain.ErrSchedulePreviewNotFound
+	}
+	if err != nil {
+		return model.SchedulePreview{}, fmt.Errorf("failed to get schedule preview: %w", err)
+	}
+
+	var preview model.SchedulePreview
+	if err := json.Unmarshal(value, &preview); err != nil {
+		return model.SchedulePreview{}, fmt.Errorf("failed to decode schedule preview: %w", err)
+	}
+
+	return preview, nil
+}
+
+func (s *SchedulePreviewStorage) Delete(ctx context.Context, previewID uuid.UUID) error {
+	err := s.client.Del(ctx, schedulePreviewKey(previewID)).Err()
+	if err != nil {
+		return fmt.Errorf("failed to delete schedule preview: %w", err)
+	}
+
+	return nil
+}
@@ -2,14 +2,33 @@ package clients
 
 import (
 	"context"
+	"errors"
 
 	"github.com/ShelbyKS/Roamly-backend/internal/domain/model"
 )
 
+// ErrNoTimeZone is returned by GetTimeZone of providers without a time zone lookup.
+var ErrNoTimeZone = errors.New("the geo provider has no time zone lookup")
+
+// IGoogleApiCache drops cached responses about a place, so they are fetched again.
+type IGoogleApiCache interface {
+	InvalidatePlace(ctx context.Context, placeID string) error
+}
+
 type IGoogleApiClient interface {
+	// Provider is one of the model.PlaceProvider* values, ids of places
+	// passed to and returned by the client are ids at this provider.
+	Provider() string
 	FindPlace(ctx context.Context, input string, fields []string) ([]model.GooglePlace, error)
 	GetPlaceByID(ctx context.Context, id string, fields []string) (model.GooglePlace, error)
-	GetTimeDistanceMatrix(ctx context.Context, placeIDs []string) (model.DistanceMatrix, error)
+	// GetTimeDistanceMatrix returns travel between the places by mode,
+	// one of the model.TravelMode* values. Unreachable pairs are omitted.
+	GetTimeDistanceMatrix(ctx context.Context, placeIDs []string, mode string) (model.DistanceMatrix, error)
+	// GetTimeDistanceMatrixBetween is GetTimeDistanceMatrix from every origin
+	// to every destination only.
+	GetTimeDistanceMatrixBetween(ctx context.Context, origins []string, destinations []string, mode string) (model.DistanceMatrix, error)
+	// GetTimeZone returns the IANA zone at the point or ErrNoTimeZone.
+	GetTimeZone(ctx context.Context, lat float64, lng float64) (string, error)
 	GetPlacesNearby(ctx context.Context,
 		includedTypes []string,
 		maxPlaces int,
@@ -0,0 +1,21 @@
+package clients
+
+import (
+	"context"
+
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/model"
+)
+
+// Use cases of the llm, each of them is routed to its own model by config.
+const (
+	LLMUseSchedule = "schedule"
+	LLMUseChat     = "chat"
+	LLMUseDuration = "duration"
+	LLMUsePlaces   = "places"
+)
+
+type IChatClient interface {
+	// PostPrompt returns the reply of the model configured for useCase,
+	// one of the LLMUse* values.
+	PostPrompt(ctx context.Context, messages []model.ChatMessage, useCase string) (string, error)
+}
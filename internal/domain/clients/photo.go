@@ -0,0 +1,10 @@
+package clients
+
+import "context"
+
+// IPlacePhotoClient fetches photos of places by references from place photos.
+type IPlacePhotoClient interface {
+	// GetPlacePhoto returns the photo scaled down to maxWidth pixels,
+	// smaller photos are returned as is.
+	GetPlacePhoto(ctx context.Context, reference string, maxWidth int) ([]byte, error)
+}
@@ -1,9 +1,12 @@
 package clients
 
 import (
+	"context"
+
 	"github.com/ShelbyKS/Roamly-backend/internal/domain/model"
 )
 
 type IMessageProdcuer interface {
-	SendMessage(msg model.NotifyMessage) error
+	// SendMessage returns after the broker has accepted msg or ctx is done.
+	SendMessage(ctx context.Context, msg model.NotifyMessage) error
 }
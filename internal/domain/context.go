@@ -0,0 +1,17 @@
+package domain
+
+import "context"
+
+type userIDKey struct{}
+
+// ContextWithUserID stores the id of the user the request is made by.
+func ContextWithUserID(ctx context.Context, userID int) context.Context {
+	return context.WithValue(ctx, userIDKey{}, userID)
+}
+
+// UserIDFromContext returns the id of the user the request is made by,
+// 0 for requests made by the system itself.
+func UserIDFromContext(ctx context.Context) int {
+	userID, _ := ctx.Value(userIDKey{}).(int)
+	return userID
+}
@@ -11,22 +11,45 @@ var (
 	ErrPlaceNotFound      = errors.New("place not found")
 	ErrEventNotFound      = errors.New("event not found")
 	ErrInviteNotFound     = errors.New("invite not found")
+	ErrRevisionNotFound   = errors.New("revision not found")
+	ErrTravelLegNotFound  = errors.New("travel leg not found")
 	ErrInviteForbidden    = errors.New("invite forbidden")
 	ErrSessionNotFound    = errors.New("session not found")
 	ErrWrongCredentials   = errors.New("wrong credentials")
 	ErrUserAlreadyExists  = errors.New("user already exists")
 	ErrPlaceAlreadyExists = errors.New("place already exists")
+	ErrVersionConflict    = errors.New("version conflict: the resource was changed by someone else")
+
+	ErrInvalidScheduleOptions  = errors.New("invalid schedule options")
+	ErrSchedulePreviewNotFound = errors.New("schedule preview not found or expired")
+	ErrInvalidTripDates        = errors.New("trip end time must not be before start time")
+	ErrInvalidEventTime        = errors.New("event end time must be after start time")
+	ErrEventOutsideTrip        = errors.New("event must be within trip dates")
+	ErrInvalidTravelMode       = errors.New("travel mode must be one of driving, walking, bicycling, transit")
+	ErrInvalidTimeZone         = errors.New("time zone must be an IANA zone name, e.g. Europe/Moscow")
+	ErrNoTravelRoute           = errors.New("no route between the events by this travel mode")
+	ErrInvalidCustomPlace      = errors.New("custom place must have a name, valid coordinates and non-negative duration")
+	ErrInvalidPhotoRequest     = errors.New("photo reference is required and width must not be negative")
+	ErrBlobNotFound            = errors.New("blob not found")
 )
 
 func GetStatusCodeByError(err error) int {
+	if errors.Is(err, ErrInvalidScheduleOptions) {
+		return http.StatusBadRequest
+	}
+
 	switch err {
-	case ErrUserNotFound, ErrTripNotFound, ErrPlaceNotFound, ErrEventNotFound, ErrInviteNotFound:
+	case ErrUserNotFound, ErrTripNotFound, ErrPlaceNotFound, ErrEventNotFound, ErrInviteNotFound, ErrRevisionNotFound,
+		ErrSchedulePreviewNotFound, ErrTravelLegNotFound:
 		return http.StatusNotFound
+	case ErrInvalidTripDates, ErrInvalidEventTime, ErrEventOutsideTrip, ErrInvalidTravelMode, ErrInvalidTimeZone,
+		ErrNoTravelRoute, ErrInvalidCustomPlace, ErrInvalidPhotoRequest:
+		return http.StatusBadRequest
 	case ErrInviteForbidden:
 		return http.StatusForbidden
 	case ErrSessionNotFound, ErrWrongCredentials:
 		return http.StatusUnauthorized
-	case ErrUserAlreadyExists:
+	case ErrUserAlreadyExists, ErrVersionConflict:
 		return http.StatusConflict
 	default:
 		return http.StatusInternalServerError
@@ -1,6 +1,8 @@
 package model
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 )
 
@@ -10,8 +12,30 @@ type Event struct {
 	PlaceID string
 	TripID  uuid.UUID
 
-	StartTime string
-	EndTime   string
+	StartTime time.Time
+	EndTime   time.Time
+	// Version is incremented on every update of the event.
+	Version int64
+	// Pinned events are kept by the scheduler as they are,
+	// other places are scheduled around them.
+	Pinned bool
+}
+
+// PinnedEvents returns the events the scheduler must keep.
+func (trip Trip) PinnedEvents() []Event {
+	var pinned []Event
+	for _, event := range trip.Events {
+		if event.Pinned {
+			pinned = append(pinned, event)
+		}
+	}
+	return pinned
+}
+
+func (event Event) In(loc *time.Location) Event {
+	event.StartTime = event.StartTime.In(loc)
+	event.EndTime = event.EndTime.In(loc)
+	return event
 }
 
 type DistanceMatrix map[string]map[string]map[string]float64
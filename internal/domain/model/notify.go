@@ -8,6 +8,15 @@ type NotifyMessage struct {
 		Author  string    `json:"author"`
 		TripID  uuid.UUID `json:"trip_id"`
 		Message string    `json:"message"`
+		// Seq numbers the notifications of the trip starting from 1.
+		Seq int64 `json:"seq"`
+		// EventID and Version are set when a trip or an event is changed:
+		// Version is the new version of the event if EventID is set,
+		// of the trip otherwise.
+		EventID *uuid.UUID `json:"event_id,omitempty"`
+		Version int64      `json:"version,omitempty"`
 	} `json:"payload"`
-	Clients []string `json:"clients"`
+	// Members are the ids of the trip users, the notifier delivers
+	// the message only to their connections.
+	Members []int `json:"members"`
 }
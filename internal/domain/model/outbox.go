@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+// OutboxMessage is a notification saved together with the change it
+// describes and waiting to be published to the message broker.
+type OutboxMessage struct {
+	ID        int64
+	Message   NotifyMessage
+	Attempts  int
+	CreatedAt time.Time
+}
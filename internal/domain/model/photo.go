@@ -0,0 +1,9 @@
+package model
+
+// PhotoFile is an image ready to be served.
+type PhotoFile struct {
+	Data        []byte
+	ContentType string
+	// ETag identifies the content, the same data always has the same tag.
+	ETag string
+}
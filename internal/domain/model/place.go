@@ -1,6 +1,16 @@
 package model
 
-import "time"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Geo providers of places.
+const (
+	PlaceProviderGoogle = "google"
+	PlaceProviderOSM    = "osm"
+)
 
 type Location struct {
 	Lat float64 `json:"lat"`
@@ -15,23 +25,203 @@ type Photo struct {
 	PhotoReference string `json:"photo_reference"`
 }
 
+type OpeningPoint struct {
+	Day  int    `json:"day"`  // 0 - sunday
+	Time string `json:"time"` // "hhmm"
+}
+
+type OpeningPeriod struct {
+	Open  OpeningPoint  `json:"open"`
+	Close *OpeningPoint `json:"close"`
+}
+
+type GoogleOpeningHours struct {
+	Periods []OpeningPeriod `json:"periods"`
+}
+
 type GooglePlace struct {
-	FormattedAddress string   `json:"formatted_address"`
-	Geometry         Geometry `json:"geometry"`
-	Name             string   `json:"name"`
-	Photos           []Photo  `json:"photos"`
-	PlaceID          string   `json:"place_id"`
-	Rating           float64  `json:"rating"`
-	Types            []string `json:"types"`
-	EditorialSummary string   `json:"editorial_summary"`
-	Vicinity         string   `json:"vicinity"`
+	FormattedAddress string              `json:"formatted_address"`
+	Geometry         Geometry            `json:"geometry"`
+	Name             string              `json:"name"`
+	Photos           []Photo             `json:"photos"`
+	PlaceID          string              `json:"place_id"`
+	Rating           float64             `json:"rating"`
+	Types            []string            `json:"types"`
+	EditorialSummary string              `json:"editorial_summary"`
+	Vicinity         string              `json:"vicinity"`
+	OpeningHours     *GoogleOpeningHours `json:"opening_hours"`
+}
+
+// OpeningHours is a single open window of a place on a weekday.
+// A place can have several windows on the same day (e.g. lunch break).
+type OpeningHours struct {
+	Weekday time.Weekday `json:"weekday"`
+	Opening string       `json:"opening"` // "15:04"
+	Closing string       `json:"closing"` // "15:04", "24:00" for midnight
+}
+
+// ExternalRef is the id of a place at a geo provider.
+type ExternalRef struct {
+	Provider   string
+	ExternalID string
 }
 
 type Place struct {
-	ID                          string `json:"id"`
-	Trips                       []*Trip
-	Closing                     time.Time
-	Opening                     time.Time
+	ID string `json:"id"`
+	// ExternalRefs are ids of the place at geo providers, a place may be
+	// known to several providers or to none.
+	ExternalRefs []ExternalRef
+	Trips        []*Trip
+	// nil - opening hours were never fetched, empty - provider has no data
+	OpeningHours                []OpeningHours
 	GooglePlace                 GooglePlace
 	RecommendedVisitingDuration int
+	// OwnerTripID is set for custom places created by users of the trip,
+	// such places are unknown to geo providers and not shared with other trips.
+	OwnerTripID uuid.UUID
+	Notes       string
+}
+
+// IsCustom reports whether the place was created by users of a trip.
+func (p *Place) IsCustom() bool {
+	return p.OwnerTripID != uuid.Nil
+}
+
+// CustomFields returns the fields of a custom place users can change.
+func (p *Place) CustomFields() Place {
+	return Place{
+		ID:          p.ID,
+		OwnerTripID: p.OwnerTripID,
+		GooglePlace: GooglePlace{
+			Name:             p.GooglePlace.Name,
+			FormattedAddress: p.GooglePlace.FormattedAddress,
+			Geometry:         p.GooglePlace.Geometry,
+		},
+		RecommendedVisitingDuration: p.RecommendedVisitingDuration,
+		Notes:                       p.Notes,
+	}
+}
+
+// VisibleIn reports whether the place can be used in the trip.
+func (p *Place) VisibleIn(tripID uuid.UUID) bool {
+	return !p.IsCustom() || p.OwnerTripID == tripID
+}
+
+// NewPlace makes a place with a new id for the place found at the provider.
+func NewPlace(provider string, googlePlace GooglePlace) Place {
+	return Place{
+		ID:           uuid.NewString(),
+		ExternalRefs: []ExternalRef{{Provider: provider, ExternalID: googlePlace.PlaceID}},
+		GooglePlace:  googlePlace,
+	}
+}
+
+// ExternalID returns the id of the place at the provider, empty if the provider doesn't know it.
+func (p *Place) ExternalID(provider string) string {
+	for _, ref := range p.ExternalRefs {
+		if ref.Provider == provider {
+			return ref.ExternalID
+		}
+	}
+	return ""
+}
+
+const (
+	clockLayout = "15:04"
+	midnight    = "24:00"
+)
+
+// WeeklyOpeningHours converts google periods to windows per weekday.
+// Periods crossing midnight are cut at the end of the opening day.
+func (gp GooglePlace) WeeklyOpeningHours() []OpeningHours {
+	hours := []OpeningHours{}
+	if gp.OpeningHours == nil {
+		return hours
+	}
+
+	for _, period := range gp.OpeningHours.Periods {
+		// a single period without close means the place is always open
+		if period.Close == nil {
+			for day := time.Sunday; day <= time.Saturday; day++ {
+				hours = append(hours, OpeningHours{Weekday: day, Opening: "00:00", Closing: midnight})
+			}
+			return hours
+		}
+
+		closing := formatGoogleTime(period.Close.Time)
+		if period.Close.Day != period.Open.Day {
+			closing = midnight
+		}
+
+		hours = append(hours, OpeningHours{
+			Weekday: time.Weekday(period.Open.Day),
+			Opening: formatGoogleTime(period.Open.Time),
+			Closing: closing,
+		})
+	}
+
+	return hours
+}
+
+func formatGoogleTime(hhmm string) string {
+	if len(hhmm) != 4 {
+		return hhmm
+	}
+	return hhmm[:2] + ":" + hhmm[2:]
+}
+
+// OpenWindows returns open intervals of the place on the given day.
+// Opening hours are local to the place, so the day must be in its zone.
+// A place without known opening hours is considered always open.
+func (p *Place) OpenWindows(day time.Time) [][2]time.Time {
+	dayStart := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+
+	if len(p.OpeningHours) == 0 {
+		return [][2]time.Time{{dayStart, dayStart.AddDate(0, 0, 1)}}
+	}
+
+	var windows [][2]time.Time
+	for _, hours := range p.OpeningHours {
+		if hours.Weekday != day.Weekday() {
+			continue
+		}
+
+		opening, err := clockOffset(hours.Opening)
+		if err != nil {
+			continue
+		}
+		closing, err := clockOffset(hours.Closing)
+		if err != nil {
+			continue
+		}
+
+		windows = append(windows, [2]time.Time{dayStart.Add(opening), dayStart.Add(closing)})
+	}
+
+	return windows
+}
+
+// IsOpenDuring reports whether the whole [start, end] interval fits into one
+// open window, loc is the zone of the opening hours.
+func (p *Place) IsOpenDuring(start, end time.Time, loc *time.Location) bool {
+	start, end = start.In(loc), end.In(loc)
+	for _, window := range p.OpenWindows(start) {
+		if !start.Before(window[0]) && !end.After(window[1]) {
+			return true
+		}
+	}
+	return false
+}
+
+func clockOffset(clock string) (time.Duration, error) {
+	if clock == midnight {
+		return 24 * time.Hour, nil
+	}
+
+	t, err := time.Parse(clockLayout, clock)
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
 }
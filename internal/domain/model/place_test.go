@@ -0,0 +1,61 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIsOpenDuring(t *testing.T) {
+	moscow := time.FixedZone("MSK", 3*60*60)
+	place := Place{OpeningHours: []OpeningHours{
+		{Weekday: time.Monday, Opening: "10:00", Closing: "18:00"},
+	}}
+
+	tests := []struct {
+		name  string
+		start time.Time
+		loc   *time.Location
+		open  bool
+	}{
+		{
+			name:  "local hours",
+			start: time.Date(2024, time.June, 3, 10, 0, 0, 0, moscow),
+			loc:   moscow,
+			open:  true,
+		},
+		{
+			name:  "utc times are checked in the local zone",
+			start: time.Date(2024, time.June, 3, 7, 0, 0, 0, time.UTC),
+			loc:   moscow,
+			open:  true,
+		},
+		{
+			name:  "before opening in the local zone",
+			start: time.Date(2024, time.June, 3, 6, 30, 0, 0, time.UTC),
+			loc:   moscow,
+			open:  false,
+		},
+		{
+			name:  "closed in the local zone",
+			start: time.Date(2024, time.June, 3, 16, 0, 0, 0, time.UTC),
+			loc:   moscow,
+			open:  false,
+		},
+		{
+			name:  "hours of a trip without a zone are utc",
+			start: time.Date(2024, time.June, 3, 16, 0, 0, 0, time.UTC),
+			loc:   time.UTC,
+			open:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			end := tt.start.Add(time.Hour)
+
+			if got := place.IsOpenDuring(tt.start, end, tt.loc); got != tt.open {
+				t.Errorf("open %v, want %v", got, tt.open)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	FocusEvent = "event"
+	FocusPlace = "place"
+)
+
+// PresenceFocus is the event or place a trip member is looking at or editing.
+type PresenceFocus struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// Presence is one connection of a member viewing the trip,
+// a member with several tabs open has several of them.
+type Presence struct {
+	ConnectionID string         `json:"connection_id"`
+	TripID       uuid.UUID      `json:"trip_id"`
+	UserID       int            `json:"user_id"`
+	Focus        *PresenceFocus `json:"focus,omitempty"`
+	SeenAt       time.Time      `json:"seen_at"`
+}
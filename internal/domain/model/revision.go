@@ -0,0 +1,58 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	RevisionPlaceAdd     = "place_add"
+	RevisionPlaceDelete  = "place_delete"
+	RevisionPlaceUpdate  = "place_update"
+	RevisionEventCreate  = "event_create"
+	RevisionEventUpdate  = "event_update"
+	RevisionEventDelete  = "event_delete"
+	RevisionEventsDelete = "events_delete"
+	RevisionSchedule     = "schedule"
+	RevisionAutoSchedule = "schedule_auto"
+	RevisionRestore      = "restore"
+)
+
+// TripSnapshot is the state of the trip places and events kept in a revision.
+type TripSnapshot struct {
+	PlaceIDs []string
+	Events   []Event
+	// CustomPlaces are the places created by users of the trip as they were,
+	// only the fields users can change are kept.
+	CustomPlaces []Place
+}
+
+// TripRevision is a change of the trip places or events.
+type TripRevision struct {
+	ID     int64
+	TripID uuid.UUID
+	// AuthorID is 0 for changes made by the system.
+	AuthorID  int
+	Action    string
+	Before    TripSnapshot
+	After     TripSnapshot
+	CreatedAt time.Time
+}
+
+// Snapshot returns the places and events of the trip.
+func (trip Trip) Snapshot() TripSnapshot {
+	snapshot := TripSnapshot{
+		PlaceIDs: make([]string, 0, len(trip.Places)),
+		Events:   make([]Event, len(trip.Events)),
+	}
+	for _, place := range trip.Places {
+		snapshot.PlaceIDs = append(snapshot.PlaceIDs, place.ID)
+		if place.OwnerTripID == trip.ID {
+			snapshot.CustomPlaces = append(snapshot.CustomPlaces, place.CustomFields())
+		}
+	}
+	copy(snapshot.Events, trip.Events)
+
+	return snapshot
+}
@@ -0,0 +1,88 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ScheduleEngineSolver = "solver"
+	ScheduleEngineLLM    = "llm"
+)
+
+type ScheduleOptions struct {
+	Engine          string
+	DayStart        string // "15:04"
+	MaxPlacesPerDay int
+}
+
+const (
+	DefaultScheduleDayStart        = "10:00"
+	DefaultScheduleDayEnd          = "21:00"
+	DefaultScheduleMaxPlacesPerDay = 3
+	DefaultVisitingDuration        = 60
+)
+
+func (opts ScheduleOptions) WithDefaults() ScheduleOptions {
+	if opts.Engine == "" {
+		opts.Engine = ScheduleEngineSolver
+	}
+	if opts.DayStart == "" {
+		opts.DayStart = DefaultScheduleDayStart
+	}
+	if opts.MaxPlacesPerDay <= 0 {
+		opts.MaxPlacesPerDay = DefaultScheduleMaxPlacesPerDay
+	}
+	return opts
+}
+
+const (
+	WarningOutsideOpeningHours = "outside_opening_hours"
+	WarningNoFreeTime          = "no_free_time"
+)
+
+// ScheduleWarning describes a place that could not be put into the schedule.
+type ScheduleWarning struct {
+	PlaceID   string
+	PlaceName string
+	Reason    string
+}
+
+// SchedulePreview is a proposed schedule of the trip that is not saved yet.
+type SchedulePreview struct {
+	ID     uuid.UUID
+	TripID uuid.UUID
+	// PlaceIDs are added to the trip places on apply, set by auto schedule.
+	PlaceIDs []string
+	// TimeZone is saved as the trip zone on apply, set if it was looked up for the preview.
+	TimeZone string
+	Auto     bool
+	Events   []Event
+	Warnings []ScheduleWarning
+	Diff     ScheduleDiff
+	// BaseRevisionID and TripVersion are the trip state the preview was made
+	// for, it is applied only while the trip is unchanged.
+	BaseRevisionID int64
+	TripVersion    int64
+	ExpiresAt      time.Time
+}
+
+// EventMove is an event of the place scheduled at another time.
+type EventMove struct {
+	Before Event
+	After  Event
+}
+
+// ScheduleDiff compares a proposed schedule with the current events,
+// events are matched by their place.
+type ScheduleDiff struct {
+	Added     []Event
+	Removed   []Event
+	Moved     []EventMove
+	Unchanged int
+	// TravelBefore and TravelAfter are the total travel time between
+	// consecutive events of the same day.
+	TravelBefore time.Duration
+	TravelAfter  time.Duration
+}
@@ -0,0 +1,147 @@
+package model
+
+import (
+	"math"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	TravelModeDriving   = "driving"
+	TravelModeWalking   = "walking"
+	TravelModeBicycling = "bicycling"
+	TravelModeTransit   = "transit"
+
+	DefaultTravelMode = TravelModeDriving
+)
+
+var travelModes = []string{TravelModeDriving, TravelModeWalking, TravelModeBicycling, TravelModeTransit}
+
+func IsTravelMode(mode string) bool {
+	return slices.Contains(travelModes, mode)
+}
+
+// Travel between places the maps api has no route for is estimated by the
+// straight line distance made longer by estimateDetour and the mode speed.
+const (
+	// MetricEstimated is set to 1 in the matrix metrics of estimated pairs.
+	MetricEstimated = "estimated"
+
+	estimateDetour = 1.3
+	earthRadiusKm  = 6371.0
+)
+
+// estimateSpeeds are average speeds by travel mode in km/h.
+var estimateSpeeds = map[string]float64{
+	TravelModeDriving:   30,
+	TravelModeWalking:   4.5,
+	TravelModeBicycling: 14,
+	TravelModeTransit:   20,
+}
+
+// TravelLeg is the way between consecutive events of a trip day.
+type TravelLeg struct {
+	ID          uuid.UUID
+	TripID      uuid.UUID
+	FromEventID uuid.UUID
+	ToEventID   uuid.UUID
+	Mode        string
+	// CustomMode is set when the mode was chosen for the leg,
+	// otherwise the leg follows the trip travel mode.
+	CustomMode bool
+	Duration   time.Duration
+	// Distance is in meters.
+	Distance int64
+}
+
+// GetTravelMode returns the trip travel mode, the default one if it is not set.
+func (trip Trip) GetTravelMode() string {
+	if trip.TravelMode == "" {
+		return DefaultTravelMode
+	}
+	return trip.TravelMode
+}
+
+// LegPairs returns consecutive events of the same trip day that need a travel
+// leg between them: both have places and the places differ.
+func (trip Trip) LegPairs() [][2]Event {
+	loc := trip.Location()
+
+	events := slices.Clone(trip.Events)
+	slices.SortStableFunc(events, func(a, b Event) int {
+		return a.StartTime.Compare(b.StartTime)
+	})
+
+	var pairs [][2]Event
+	for i := 1; i < len(events); i++ {
+		from, to := events[i-1], events[i]
+		if from.PlaceID == "" || to.PlaceID == "" || from.PlaceID == to.PlaceID {
+			continue
+		}
+
+		fromDay, toDay := from.StartTime.In(loc), to.StartTime.In(loc)
+		if fromDay.Year() != toDay.Year() || fromDay.YearDay() != toDay.YearDay() {
+			continue
+		}
+
+		pairs = append(pairs, [2]Event{from, to})
+	}
+
+	return pairs
+}
+
+// FillMissing estimates the pairs missing from the matrix by the locations of
+// the places, pairs of places without a location stay missing.
+// It returns the number of estimated pairs.
+func (matrix DistanceMatrix) FillMissing(locations map[string]Location, mode string) int {
+	speed, ok := estimateSpeeds[mode]
+	if !ok {
+		speed = estimateSpeeds[DefaultTravelMode]
+	}
+
+	estimated := 0
+	for origin, from := range locations {
+		for destination, to := range locations {
+			if _, ok := matrix[origin][destination]; ok {
+				continue
+			}
+			if matrix[origin] == nil {
+				matrix[origin] = make(map[string]map[string]float64)
+			}
+
+			distance := haversineKm(from, to) * estimateDetour
+			matrix[origin][destination] = map[string]float64{
+				"distance":      distance,
+				"duration":      distance / speed * 60,
+				MetricEstimated: 1,
+			}
+			estimated++
+		}
+	}
+
+	return estimated
+}
+
+// PlaceLocations returns the locations of places that have one.
+func PlaceLocations(places []*Place) map[string]Location {
+	locations := make(map[string]Location, len(places))
+	for _, place := range places {
+		location := place.GooglePlace.Geometry.Location
+		if location.Lat == 0 && location.Lng == 0 {
+			continue
+		}
+		locations[place.ID] = location
+	}
+	return locations
+}
+
+func haversineKm(from, to Location) float64 {
+	lat1, lat2 := from.Lat*math.Pi/180, to.Lat*math.Pi/180
+	dLat := lat2 - lat1
+	dLng := (to.Lng - from.Lng) * math.Pi / 180
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
@@ -0,0 +1,62 @@
+package model
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFillMissing(t *testing.T) {
+	// a degree of latitude is about 111.19 km
+	locations := map[string]Location{
+		"a": {Lat: 55, Lng: 37},
+		"b": {Lat: 56, Lng: 37},
+	}
+	matrix := DistanceMatrix{
+		"a": {"b": {"distance": 150, "duration": 120}},
+		"c": {"a": {"distance": 10, "duration": 10}},
+	}
+
+	estimated := matrix.FillMissing(locations, TravelModeWalking)
+
+	// b -> a and the zero length a -> a, b -> b
+	if estimated != 3 {
+		t.Errorf("estimated %d pairs, want 3", estimated)
+	}
+
+	if known := matrix["a"]["b"]; known["distance"] != 150 || known["duration"] != 120 || known[MetricEstimated] != 0 {
+		t.Errorf("known pair changed to %v", known)
+	}
+
+	back := matrix["b"]["a"]
+	distance := 111.19 * estimateDetour
+	if math.Abs(back["distance"]-distance) > 0.1 {
+		t.Errorf("estimated distance %.2f, want %.2f", back["distance"], distance)
+	}
+	if duration := distance / estimateSpeeds[TravelModeWalking] * 60; math.Abs(back["duration"]-duration) > 1 {
+		t.Errorf("estimated duration %.2f, want %.2f", back["duration"], duration)
+	}
+	if back[MetricEstimated] != 1 {
+		t.Errorf("estimated pair is not marked")
+	}
+
+	// c has no location
+	if _, ok := matrix["a"]["c"]; ok {
+		t.Errorf("pair to a place without a location is estimated")
+	}
+}
+
+func TestFillMissingUnknownMode(t *testing.T) {
+	locations := map[string]Location{
+		"a": {Lat: 55, Lng: 37},
+		"b": {Lat: 56, Lng: 37},
+	}
+	matrix := DistanceMatrix{}
+	defaults := DistanceMatrix{}
+
+	matrix.FillMissing(locations, "teleport")
+	defaults.FillMissing(locations, DefaultTravelMode)
+
+	if matrix["a"]["b"]["duration"] != defaults["a"]["b"]["duration"] {
+		t.Errorf("unknown mode takes %.2f minutes, default mode %.2f", matrix["a"]["b"]["duration"], defaults["a"]["b"]["duration"])
+	}
+}
@@ -1,21 +1,65 @@
 package model
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 type Trip struct {
-	ID                uuid.UUID     `json:"id"`
-	Name              string        `json:"name"`
-	StartTime         string        `json:"start_time"`
-	EndTime           string        `json:"end_time"`
-	AreaID            string        `json:"area_id"`
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	TimeZone  string    `json:"time_zone"`
+	// DatesUnknown is set for trips migrated from times without dates: they
+	// are placed on 1970-01-01 until the user sets the dates again.
+	DatesUnknown bool `json:"dates_unknown"`
+	// TravelMode is used between the trip events unless a leg has its own.
+	TravelMode string `json:"travel_mode"`
+	AreaID     string `json:"area_id"`
+	// Version is incremented on every update of the trip.
+	Version           int64         `json:"version"`
 	Area              *Place        `json:"area"`
 	Users             []*User       `json:"users"`
 	Places            []*Place      `json:"places"`
 	RecommendedPlaces []*Place      `json:"recommended_places"`
 	Events            []Event       `json:"events"`
+	Legs              []TravelLeg   `json:"legs"`
 	AIChat            []ChatMessage `json:"ai_chat"`
 }
 
+// Location returns the IANA zone of the trip area, UTC when it is unknown.
+func (trip *Trip) Location() *time.Location {
+	if trip.TimeZone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(trip.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// In returns a copy of the trip with trip and event times in loc.
+func (trip Trip) In(loc *time.Location) Trip {
+	trip.StartTime = trip.StartTime.In(loc)
+	trip.EndTime = trip.EndTime.In(loc)
+
+	events := make([]Event, len(trip.Events))
+	for i, event := range trip.Events {
+		events[i] = event.In(loc)
+	}
+	trip.Events = events
+
+	return trip
+}
+
+// Contains reports whether the [start, end] interval lies inside the trip dates.
+func (trip *Trip) Contains(start, end time.Time) bool {
+	return !start.Before(trip.StartTime) && !end.After(trip.EndTime)
+}
+
 func (trip *Trip) GetTripPlaceIDs() []string {
 	var placeIDs []string
 	for _, place := range trip.Places {
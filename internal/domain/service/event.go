@@ -11,6 +11,9 @@ type IEventService interface {
 	GetEventByID(ctx context.Context, eventID uuid.UUID) (model.Event, error)
 	CreateEvent(ctx context.Context, event model.Event) (model.Event, error)
 	UpdateEvent(ctx context.Context, event model.Event) (model.Event, error)
+	// PinEvent pins or unpins the event, so the scheduler keeps or may replace it.
+	// A non-zero version must be the current one as in UpdateEvent.
+	PinEvent(ctx context.Context, eventID uuid.UUID, pinned bool, version int64) (model.Event, error)
 	DeleteEvent(ctx context.Context, eventID uuid.UUID) error
 	DeleteEventsByTrip(ctx context.Context, tripID uuid.UUID) error
 }
@@ -0,0 +1,13 @@
+package service
+
+import (
+	"context"
+
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/model"
+)
+
+type IPhotoService interface {
+	// GetPlacePhoto returns the photo by reference at least width pixels
+	// wide if the original allows, 0 means the default width.
+	GetPlacePhoto(ctx context.Context, reference string, width int) (model.PhotoFile, error)
+}
@@ -9,6 +9,10 @@ import (
 type IPlaceService interface {
 	AddPlaceToTrip(ctx context.Context, tripID uuid.UUID, placeID string) (model.Trip, error)
 	DeletePlace(ctx context.Context, tripID uuid.UUID, placeID string) (model.Trip, error)
+	// CreateCustomPlace adds a place created by users to the trip, it is seen only in this trip.
+	CreateCustomPlace(ctx context.Context, tripID uuid.UUID, place model.Place) (model.Trip, error)
+	// UpdateCustomPlace changes a custom place of the trip.
+	UpdateCustomPlace(ctx context.Context, tripID uuid.UUID, place model.Place) (model.Trip, error)
 	GetTimeMatrix(ctx context.Context, places []*model.Place) [][]int
 	FindPlace(ctx context.Context, searchString string) ([]model.Place, error)
 	GetPlacesNearby(ctx context.Context,
@@ -18,4 +22,6 @@ type IPlaceService interface {
 		placesTypes []string,
 		maxPlaces int) ([]model.GooglePlace, error)
 	DetermineRecommendedDuration(ctx context.Context, placeID string) error
+	// InvalidatePlace drops the cached google api responses about the place of the trip.
+	InvalidatePlace(ctx context.Context, tripID uuid.UUID, placeID string) error
 }
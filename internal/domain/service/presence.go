@@ -0,0 +1,12 @@
+package service
+
+import (
+	"context"
+
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/model"
+	"github.com/google/uuid"
+)
+
+type IPresenceService interface {
+	GetTripPresence(ctx context.Context, tripID uuid.UUID) ([]model.Presence, error)
+}
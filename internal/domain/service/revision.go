@@ -0,0 +1,16 @@
+package service
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/model"
+)
+
+type IRevisionService interface {
+	GetRevisions(ctx context.Context, tripID uuid.UUID, limit, offset int) ([]model.TripRevision, error)
+	// RestoreRevision returns the trip places and events to their state
+	// after the revision, the restore is recorded as a new revision.
+	RestoreRevision(ctx context.Context, tripID uuid.UUID, revisionID int64) (model.Trip, error)
+}
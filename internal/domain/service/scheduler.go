@@ -9,6 +9,12 @@ import (
 )
 
 type ISchedulerService interface {
-	ScheduleTrip(ctx context.Context, tripID uuid.UUID) (model.Trip, error)
-	AutoScheduleTrip(ctx context.Context, tripID uuid.UUID) (model.Trip, error)
+	ScheduleTrip(ctx context.Context, tripID uuid.UUID, opts model.ScheduleOptions) (model.Trip, []model.ScheduleWarning, error)
+	AutoScheduleTrip(ctx context.Context, tripID uuid.UUID, opts model.ScheduleOptions) (model.Trip, []model.ScheduleWarning, error)
+	// PreviewSchedule proposes a schedule without saving it, auto also adds
+	// the recommended places as AutoScheduleTrip does.
+	PreviewSchedule(ctx context.Context, tripID uuid.UUID, opts model.ScheduleOptions, auto bool) (model.SchedulePreview, error)
+	// ApplySchedulePreview saves the preview if the trip has not changed since
+	// it was made, otherwise domain.ErrVersionConflict is returned.
+	ApplySchedulePreview(ctx context.Context, tripID uuid.UUID, previewID uuid.UUID) (model.Trip, []model.ScheduleWarning, error)
 }
@@ -10,11 +10,14 @@ import (
 type ITripService interface {
 	GetTripByID(ctx context.Context, id uuid.UUID) (model.Trip, error)
 	CreateTrip(ctx context.Context, trip model.Trip) (uuid.UUID, error)
-	UpdateTrip(ctx context.Context, trip model.Trip) error
+	UpdateTrip(ctx context.Context, trip model.Trip) (model.Trip, error)
 	GetTrips(ctx context.Context, userId int) ([]model.Trip, error)
 	DeleteTrip(ctx context.Context, id uuid.UUID) error
 	GetUserRole(ctx context.Context, userID int, tripID uuid.UUID) (model.UserTripRole, error)
 	GetTripByEventID(ctx context.Context, eventID uuid.UUID) (model.Trip, error)
 	DetermineRecommendedPlaces(ctx context.Context, tripID uuid.UUID) error
 	RemoveUserFromTrip(ctx context.Context, userID int, tripID uuid.UUID) error
+	// SetLegMode chooses the travel mode of a single leg, an empty mode
+	// returns the leg to the trip travel mode.
+	SetLegMode(ctx context.Context, tripID uuid.UUID, legID uuid.UUID, mode string) (model.TravelLeg, error)
 }
@@ -0,0 +1,11 @@
+package storage
+
+import "context"
+
+// IBlobStorage keeps binary objects by slash separated keys, e.g. fetched
+// place photos.
+type IBlobStorage interface {
+	// Get returns domain.ErrBlobNotFound if there is no object by the key.
+	Get(ctx context.Context, key string) ([]byte, error)
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+}
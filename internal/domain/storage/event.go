@@ -10,9 +10,15 @@ import (
 type IEventStorage interface {
 	GetEventByID(ctx context.Context, eventID uuid.UUID) (model.Event, error)
 	CreateEvent(ctx context.Context, event model.Event) error
+	// UpdateEvent increments the event version. A non-zero event.Version must be
+	// the stored one, otherwise domain.ErrVersionConflict is returned.
 	UpdateEvent(ctx context.Context, event model.Event) (model.Event, error)
 	DeleteEvent(ctx context.Context, eventID uuid.UUID) error
+	// SetEventPinned pins or unpins the event and increments its version,
+	// a non-zero version is checked as in UpdateEvent.
+	SetEventPinned(ctx context.Context, eventID uuid.UUID, pinned bool, version int64) (model.Event, error)
 	CreateBatchEvents(ctx context.Context, events *[]model.Event) error
-	DeleteEventsByTrip(ctx context.Context, tripID uuid.UUID) error
+	// DeleteEventsByTrip deletes the trip events, pinned ones are kept if keepPinned is set.
+	DeleteEventsByTrip(ctx context.Context, tripID uuid.UUID, keepPinned bool) error
 	DeleteEventsByPlace(ctx context.Context, tripID uuid.UUID, placeID string) error
 }
@@ -0,0 +1,18 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/model"
+)
+
+type IOutboxStorage interface {
+	Add(ctx context.Context, message model.NotifyMessage) error
+	// LockPending locks up to limit messages due for sending, the lock is held
+	// until the transaction of ctx ends.
+	LockPending(ctx context.Context, limit int) ([]model.OutboxMessage, error)
+	MarkSent(ctx context.Context, ids []int64) error
+	MarkFailed(ctx context.Context, id int64, nextAttemptAt time.Time, reason string) error
+	DeleteSent(ctx context.Context, before time.Time) (int64, error)
+}
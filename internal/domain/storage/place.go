@@ -9,8 +9,12 @@ import (
 
 type IPlaceStorage interface {
 	GetPlaceByID(ctx context.Context, placeID string) (model.Place, error)
+	// GetPlaceByExternalID returns the place by its id at the geo provider.
+	GetPlaceByExternalID(ctx context.Context, provider string, externalID string) (model.Place, error)
 	DeletePlace(ctx context.Context, tripID uuid.UUID, placeID string) error
 	CreatePlace(ctx context.Context, place *model.Place) (model.Place, error)
 	AppendPlaceToTrip(ctx context.Context, placeID string, tripID uuid.UUID) error
 	UpdatePlace(ctx context.Context, place model.Place) error
+	// UpdateCustomPlace saves the fields users set in a custom place, zero values included.
+	UpdateCustomPlace(ctx context.Context, place model.Place) error
 }
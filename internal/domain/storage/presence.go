@@ -0,0 +1,15 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/model"
+	"github.com/google/uuid"
+)
+
+type IPresenceStorage interface {
+	// Set adds or refreshes the presence, it expires unless refreshed in time.
+	Set(ctx context.Context, presence model.Presence) error
+	Remove(ctx context.Context, tripID uuid.UUID, connectionID string) error
+	GetByTrip(ctx context.Context, tripID uuid.UUID) ([]model.Presence, error)
+}
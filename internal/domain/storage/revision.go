@@ -0,0 +1,18 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/model"
+)
+
+type IRevisionStorage interface {
+	Add(ctx context.Context, revision model.TripRevision) (model.TripRevision, error)
+	// GetByTrip returns revisions of the trip, the latest first.
+	GetByTrip(ctx context.Context, tripID uuid.UUID, limit, offset int) ([]model.TripRevision, error)
+	GetByID(ctx context.Context, tripID uuid.UUID, revisionID int64) (model.TripRevision, error)
+	// LatestID returns the id of the latest revision of the trip, 0 if there is none.
+	LatestID(ctx context.Context, tripID uuid.UUID) (int64, error)
+}
@@ -0,0 +1,16 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/model"
+)
+
+// ISchedulePreviewStorage keeps schedule previews until they are applied or expire.
+type ISchedulePreviewStorage interface {
+	Add(ctx context.Context, preview model.SchedulePreview) error
+	Get(ctx context.Context, previewID uuid.UUID) (model.SchedulePreview, error)
+	Delete(ctx context.Context, previewID uuid.UUID) error
+}
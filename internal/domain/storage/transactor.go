@@ -0,0 +1,9 @@
+package storage
+
+import "context"
+
+// ITransactor runs fn in a database transaction. Storages called with the
+// context passed to fn take part in it, nested calls reuse the outer one.
+type ITransactor interface {
+	InTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
@@ -0,0 +1,15 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/model"
+)
+
+type ITravelLegStorage interface {
+	// ReplaceLegs swaps all travel legs of the trip for legs.
+	ReplaceLegs(ctx context.Context, tripID uuid.UUID, legs []model.TravelLeg) error
+	UpdateLeg(ctx context.Context, leg model.TravelLeg) error
+}
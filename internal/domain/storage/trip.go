@@ -9,10 +9,21 @@ import (
 type ITripStorage interface {
 	GetTripByID(ctx context.Context, id uuid.UUID) (model.Trip, error)
 	CreateTrip(ctx context.Context, trip model.Trip, userRole model.UserTripRole) error
+	// UpdateTrip increments the trip version. A non-zero trip.Version must be
+	// the stored one, otherwise domain.ErrVersionConflict is returned.
 	UpdateTrip(ctx context.Context, trip model.Trip) error
+	// AddRecommendedPlaces appends places to the trip recommendations. The trip
+	// version is kept: recommendations are not edited by users.
+	AddRecommendedPlaces(ctx context.Context, tripID uuid.UUID, places []*model.Place) error
 	GetTrips(ctx context.Context, userId int) ([]model.Trip, error)
 	DeleteTrip(ctx context.Context, id uuid.UUID) error
 	GetUserRole(ctx context.Context, userID int, tripID uuid.UUID) (model.UserTripRole, error)
 	GetTripByEventID(ctx context.Context, eventID uuid.UUID) (model.Trip, error)
 	RemoveUserFromTrip(ctx context.Context, userID int, tripID uuid.UUID) error
+	// LockTrip locks the trip row until the transaction of ctx ends.
+	LockTrip(ctx context.Context, tripID uuid.UUID) error
+	// NextNotifySeq increments the notification sequence of the trip. The trip
+	// row stays locked until the transaction of ctx ends, so sequence numbers
+	// are committed in order.
+	NextNotifySeq(ctx context.Context, tripID uuid.UUID) (int64, error)
 }
@@ -1,6 +1,8 @@
 package dto
 
 import (
+	"time"
+
 	"github.com/ShelbyKS/Roamly-backend/internal/domain/model"
 )
 
@@ -20,13 +22,13 @@ type TripConverter struct{}
 func (TripConverter) ToDto(trip model.Trip) TripResponse {
 	places := make([]PlaceGoogle, len(trip.Places))
 	for i, place := range trip.Places {
-		placeDto := GooglePlaceConverter{}.ToDto(place.GooglePlace)
+		placeDto := PlaceConverter{}.ToDto(*place)
 		places[i] = placeDto
 	}
 
 	recommendedPlaces := make([]PlaceGoogle, len(trip.RecommendedPlaces))
 	for i, recommendedPlace := range trip.RecommendedPlaces {
-		placeDto := GooglePlaceConverter{}.ToDto(recommendedPlace.GooglePlace)
+		placeDto := PlaceConverter{}.ToDto(*recommendedPlace)
 		recommendedPlaces[i] = placeDto
 	}
 
@@ -42,17 +44,16 @@ func (TripConverter) ToDto(trip model.Trip) TripResponse {
 
 	events := make([]GetEvent, len(trip.Events))
 	for i, event := range trip.Events {
-		events[i] = GetEvent{
-			ID:        event.ID,
-			Name:      event.Name,
-			PlaceID:   event.PlaceID,
-			TripID:    event.TripID,
-			StartTime: event.StartTime,
-			EndTime:   event.EndTime,
-		}
+		events[i] = EventConverter{}.ToDto(event)
+	}
+
+	legs := make([]TravelLegResponse, len(trip.Legs))
+	for i, leg := range trip.Legs {
+		legs[i] = TravelLegConverter{}.ToDto(leg)
 	}
 
 	area := GooglePlaceConverter{}.ToDto(trip.Area.GooglePlace)
+	area.PlaceID = trip.Area.ID
 
 	return TripResponse{
 		ID:                trip.ID,
@@ -60,14 +61,63 @@ func (TripConverter) ToDto(trip model.Trip) TripResponse {
 		Users:             users,
 		StartTime:         trip.StartTime,
 		EndTime:           trip.EndTime,
+		TimeZone:          trip.TimeZone,
+		DatesUnknown:      trip.DatesUnknown,
+		TravelMode:        trip.GetTravelMode(),
 		AreaID:            trip.AreaID,
+		Version:           trip.Version,
 		Area:              area,
 		Places:            places,
 		Events:            events,
+		Legs:              legs,
 		RecommendedPlaces: recommendedPlaces,
 	}
 }
 
+type TravelLegConverter struct{}
+
+func (TravelLegConverter) ToDto(leg model.TravelLeg) TravelLegResponse {
+	return TravelLegResponse{
+		ID:              leg.ID,
+		FromEventID:     leg.FromEventID,
+		ToEventID:       leg.ToEventID,
+		Mode:            leg.Mode,
+		CustomMode:      leg.CustomMode,
+		DurationMinutes: int(leg.Duration.Round(time.Minute) / time.Minute),
+		DistanceMeters:  leg.Distance,
+	}
+}
+
+type PlaceConverter struct{}
+
+func (PlaceConverter) ToDto(place model.Place) PlaceGoogle {
+	placeDto := GooglePlaceConverter{}.ToDto(place.GooglePlace)
+	if place.ID != "" {
+		placeDto.PlaceID = place.ID
+	}
+	placeDto.RecommendedDuration = place.RecommendedVisitingDuration
+	placeDto.Custom = place.IsCustom()
+	placeDto.Notes = place.Notes
+
+	for _, ref := range place.ExternalRefs {
+		placeDto.ExternalRefs = append(placeDto.ExternalRefs, ExternalRef{
+			Provider:   ref.Provider,
+			ExternalID: ref.ExternalID,
+		})
+	}
+
+	placeDto.OpeningHours = make([]OpeningHours, len(place.OpeningHours))
+	for i, hours := range place.OpeningHours {
+		placeDto.OpeningHours[i] = OpeningHours{
+			Weekday: int(hours.Weekday),
+			Opening: hours.Opening,
+			Closing: hours.Closing,
+		}
+	}
+
+	return placeDto
+}
+
 type PhotoConverter struct{}
 
 func (PhotoConverter) ToDb(photo model.Photo) Photo {
@@ -124,6 +174,8 @@ func (EventConverter) ToDto(event model.Event) GetEvent {
 		TripID:    event.TripID,
 		StartTime: event.StartTime,
 		EndTime:   event.EndTime,
+		Version:   event.Version,
+		Pinned:    event.Pinned,
 	}
 }
 
@@ -147,3 +199,99 @@ func (AIChatConverter) ToDto(message model.ChatMessage) ChatMessageResponse {
 		CreatedAt: message.CreatedAt,
 	}
 }
+
+type ScheduleWarningConverter struct{}
+
+func (ScheduleWarningConverter) ToDto(warnings []model.ScheduleWarning) []ScheduleWarning {
+	warningsDto := make([]ScheduleWarning, len(warnings))
+	for i, warning := range warnings {
+		warningsDto[i] = ScheduleWarning{
+			PlaceID:   warning.PlaceID,
+			PlaceName: warning.PlaceName,
+			Reason:    warning.Reason,
+		}
+	}
+	return warningsDto
+}
+
+type PresenceConverter struct{}
+
+func (PresenceConverter) ToDto(presence model.Presence) PresenceResponse {
+	presenceDto := PresenceResponse{
+		ConnectionID: presence.ConnectionID,
+		UserID:       presence.UserID,
+		SeenAt:       presence.SeenAt,
+	}
+	if presence.Focus != nil {
+		presenceDto.Focus = &PresenceFocus{
+			Type: presence.Focus.Type,
+			ID:   presence.Focus.ID,
+		}
+	}
+
+	return presenceDto
+}
+
+type RevisionConverter struct{}
+
+func (RevisionConverter) ToDto(revision model.TripRevision, loc *time.Location) RevisionResponse {
+	return RevisionResponse{
+		ID:        revision.ID,
+		TripID:    revision.TripID,
+		AuthorID:  revision.AuthorID,
+		Action:    revision.Action,
+		Before:    snapshotToDto(revision.Before, loc),
+		After:     snapshotToDto(revision.After, loc),
+		CreatedAt: revision.CreatedAt.In(loc),
+	}
+}
+
+func snapshotToDto(snapshot model.TripSnapshot, loc *time.Location) TripSnapshotResponse {
+	customPlaces := make([]PlaceGoogle, len(snapshot.CustomPlaces))
+	for i, place := range snapshot.CustomPlaces {
+		customPlaces[i] = PlaceConverter{}.ToDto(place)
+	}
+
+	return TripSnapshotResponse{
+		PlaceIDs:     snapshot.PlaceIDs,
+		Events:       eventsToDto(snapshot.Events, loc),
+		CustomPlaces: customPlaces,
+	}
+}
+
+type SchedulePreviewConverter struct{}
+
+func (SchedulePreviewConverter) ToDto(preview model.SchedulePreview, loc *time.Location) SchedulePreviewResponse {
+	moved := make([]EventMove, len(preview.Diff.Moved))
+	for i, move := range preview.Diff.Moved {
+		moved[i] = EventMove{
+			Before: EventConverter{}.ToDto(move.Before.In(loc)),
+			After:  EventConverter{}.ToDto(move.After.In(loc)),
+		}
+	}
+
+	return SchedulePreviewResponse{
+		ID:       preview.ID,
+		TripID:   preview.TripID,
+		PlaceIDs: preview.PlaceIDs,
+		Events:   eventsToDto(preview.Events, loc),
+		Warnings: ScheduleWarningConverter{}.ToDto(preview.Warnings),
+		Diff: ScheduleDiff{
+			Added:               eventsToDto(preview.Diff.Added, loc),
+			Removed:             eventsToDto(preview.Diff.Removed, loc),
+			Moved:               moved,
+			Unchanged:           preview.Diff.Unchanged,
+			TravelBeforeMinutes: int(preview.Diff.TravelBefore.Minutes()),
+			TravelAfterMinutes:  int(preview.Diff.TravelAfter.Minutes()),
+		},
+		ExpiresAt: preview.ExpiresAt,
+	}
+}
+
+func eventsToDto(events []model.Event, loc *time.Location) []GetEvent {
+	eventsDto := make([]GetEvent, len(events))
+	for i, event := range events {
+		eventsDto[i] = EventConverter{}.ToDto(event.In(loc))
+	}
+	return eventsDto
+}
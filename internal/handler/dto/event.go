@@ -1,12 +1,18 @@
 package dto
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 type GetEvent struct {
 	ID        uuid.UUID `json:"id"`
 	Name      string    `json:"name"`
 	PlaceID   string    `json:"place_id"`
 	TripID    uuid.UUID `json:"trip_id"`
-	StartTime string    `json:"start_time"`
-	EndTime   string    `json:"end_time"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	Version   int64     `json:"version"`
+	Pinned    bool      `json:"pinned"`
 }
@@ -9,8 +9,25 @@ type PlaceGoogle struct {
 	Rating              float64  `json:"rating"`
 	Types               []string `json:"types"`
 	Vicinity            string   `json:"vicinity"`
-	EditorialSummary    string   `json:"editorial_summary"`
-	RecommendedDuration int      `json:"recommended_duration"`
+	EditorialSummary    string         `json:"editorial_summary"`
+	RecommendedDuration int            `json:"recommended_duration"`
+	OpeningHours        []OpeningHours `json:"opening_hours,omitempty"`
+	ExternalRefs        []ExternalRef  `json:"external_refs,omitempty"`
+	// Custom places are created by users of the trip.
+	Custom bool   `json:"custom,omitempty"`
+	Notes  string `json:"notes,omitempty"`
+}
+
+// ExternalRef is the id of a place at a geo provider, place_id of stored places is their own id.
+type ExternalRef struct {
+	Provider   string `json:"provider"`
+	ExternalID string `json:"external_id"`
+}
+
+type OpeningHours struct {
+	Weekday int    `json:"weekday"`
+	Opening string `json:"opening"`
+	Closing string `json:"closing"`
 }
 
 type Location struct {
@@ -0,0 +1,15 @@
+package dto
+
+import "time"
+
+type PresenceFocus struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+type PresenceResponse struct {
+	ConnectionID string         `json:"connection_id"`
+	UserID       int            `json:"user_id"`
+	Focus        *PresenceFocus `json:"focus,omitempty"`
+	SeenAt       time.Time      `json:"seen_at"`
+}
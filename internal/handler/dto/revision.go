@@ -0,0 +1,23 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type TripSnapshotResponse struct {
+	PlaceIDs     []string      `json:"place_ids"`
+	Events       []GetEvent    `json:"events"`
+	CustomPlaces []PlaceGoogle `json:"custom_places,omitempty"`
+}
+
+type RevisionResponse struct {
+	ID        int64                `json:"id"`
+	TripID    uuid.UUID            `json:"trip_id"`
+	AuthorID  int                  `json:"author_id"`
+	Action    string               `json:"action"`
+	Before    TripSnapshotResponse `json:"before"`
+	After     TripSnapshotResponse `json:"after"`
+	CreatedAt time.Time            `json:"created_at"`
+}
@@ -0,0 +1,37 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ScheduleWarning struct {
+	PlaceID   string `json:"place_id"`
+	PlaceName string `json:"place_name"`
+	Reason    string `json:"reason"`
+}
+
+type EventMove struct {
+	Before GetEvent `json:"before"`
+	After  GetEvent `json:"after"`
+}
+
+type ScheduleDiff struct {
+	Added               []GetEvent  `json:"added"`
+	Removed             []GetEvent  `json:"removed"`
+	Moved               []EventMove `json:"moved"`
+	Unchanged           int         `json:"unchanged"`
+	TravelBeforeMinutes int         `json:"travel_before_minutes"`
+	TravelAfterMinutes  int         `json:"travel_after_minutes"`
+}
+
+type SchedulePreviewResponse struct {
+	ID        uuid.UUID         `json:"id"`
+	TripID    uuid.UUID         `json:"trip_id"`
+	PlaceIDs  []string          `json:"place_ids"`
+	Events    []GetEvent        `json:"events"`
+	Warnings  []ScheduleWarning `json:"warnings"`
+	Diff      ScheduleDiff      `json:"diff"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
@@ -0,0 +1,14 @@
+package dto
+
+import "github.com/google/uuid"
+
+type TravelLegResponse struct {
+	ID          uuid.UUID `json:"id"`
+	FromEventID uuid.UUID `json:"from_event_id"`
+	ToEventID   uuid.UUID `json:"to_event_id"`
+	Mode        string    `json:"mode"`
+	// CustomMode is set when the mode was chosen for the leg instead of the trip one.
+	CustomMode      bool  `json:"custom_mode"`
+	DurationMinutes int   `json:"duration_minutes"`
+	DistanceMeters  int64 `json:"distance_meters"`
+}
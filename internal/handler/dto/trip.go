@@ -1,18 +1,25 @@
 package dto
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 )
 
 type TripResponse struct {
-	ID                uuid.UUID     `json:"id"`
-	Name              string        `json:"name"`
-	Users             []GetUser     `json:"users"`
-	StartTime         string        `json:"start_time"`
-	EndTime           string        `json:"end_time"`
-	AreaID            string        `json:"area_id"`
-	Area              PlaceGoogle   `json:"area"`
-	Places            []PlaceGoogle `json:"places"`
-	RecommendedPlaces []PlaceGoogle `json:"recommended_places"`
-	Events            []GetEvent    `json:"events"`
+	ID                uuid.UUID           `json:"id"`
+	Name              string              `json:"name"`
+	Users             []GetUser           `json:"users"`
+	StartTime         time.Time           `json:"start_time"`
+	EndTime           time.Time           `json:"end_time"`
+	TimeZone          string              `json:"time_zone"`
+	DatesUnknown      bool                `json:"dates_unknown"`
+	TravelMode        string              `json:"travel_mode"`
+	AreaID            string              `json:"area_id"`
+	Version           int64               `json:"version"`
+	Area              PlaceGoogle         `json:"area"`
+	Places            []PlaceGoogle       `json:"places"`
+	RecommendedPlaces []PlaceGoogle       `json:"recommended_places"`
+	Events            []GetEvent          `json:"events"`
+	Legs              []TravelLegResponse `json:"legs"`
 }
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setETag returns the version of the trip or event as its ETag,
+// clients send it back in If-Match when they update it.
+func setETag(c *gin.Context, version int64) {
+	c.Header("ETag", fmt.Sprintf("%q", strconv.FormatInt(version, 10)))
+}
+
+// ifMatchVersion parses the If-Match header of an update. No header and "*"
+// return 0: the update is applied to any version.
+func ifMatchVersion(c *gin.Context) (int64, error) {
+	value := strings.TrimSpace(c.GetHeader("If-Match"))
+	if value == "" || value == "*" {
+		return 0, nil
+	}
+
+	tag := strings.Trim(strings.TrimPrefix(value, "W/"), `"`)
+	version, err := strconv.ParseInt(tag, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, fmt.Errorf("invalid If-Match header %q, expected the ETag of the resource", value)
+	}
+
+	return version, nil
+}
+
+// ifNoneMatch reports whether the If-None-Match header matches the etag.
+// Tags are compared weakly as the header requires: "W/" is ignored, a list
+// matches if any of its tags does and "*" matches any.
+func ifNoneMatch(c *gin.Context, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(c.GetHeader("If-None-Match"), ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
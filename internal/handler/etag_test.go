@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestIfNoneMatch(t *testing.T) {
+	const etag = `"3f2a"`
+
+	tests := []struct {
+		name   string
+		header string
+		match  bool
+	}{
+		{name: "no header", header: "", match: false},
+		{name: "same tag", header: `"3f2a"`, match: true},
+		{name: "other tag", header: `"9b1c"`, match: false},
+		{name: "weak tag", header: `W/"3f2a"`, match: true},
+		{name: "list of tags", header: `"9b1c", W/"3f2a"`, match: true},
+		{name: "list without the tag", header: `"9b1c","7d4e"`, match: false},
+		{name: "any tag", header: "*", match: true},
+		{name: "unquoted tag", header: "3f2a", match: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/place/photo", nil)
+			if tt.header != "" {
+				c.Request.Header.Set("If-None-Match", tt.header)
+			}
+
+			if got := ifNoneMatch(c, etag); got != tt.match {
+				t.Errorf("match %v, want %v", got, tt.match)
+			}
+		})
+	}
+}
@@ -1,7 +1,9 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -44,6 +46,9 @@ func NewEventHandler(
 		tripEventGroup.PUT("/",
 			middleware.AccessTripByIdOfEventFromBody(tripService, middleware.ForOwnerAndEditor),
 			handler.UpdateEvent)
+		tripEventGroup.PUT("/pin",
+			middleware.AccessTripByIdOfEventFromBody(tripService, middleware.ForOwnerAndEditor),
+			handler.PinEvent)
 		tripEventGroup.DELETE("/",
 			middleware.AccessTripByEventIdFromQueryMiddleware(tripService, middleware.ForOwnerAndEditor),
 			handler.DeleteEvent)
@@ -60,8 +65,10 @@ type CreateEventRequest struct {
 	Name      string    `json:"name"`
 	PlaceID   string    `json:"place_id"`
 	TripID    uuid.UUID `json:"trip_id" binding:"required"`
-	StartTime string    `json:"start_time" binding:"required"`
-	EndTime   string    `json:"end_time" binding:"required"`
+	StartTime time.Time `json:"start_time" binding:"required"`
+	EndTime   time.Time `json:"end_time" binding:"required"`
+	// Pinned events are kept when the trip is scheduled again.
+	Pinned bool `json:"pinned"`
 }
 
 // @Summary Create event
@@ -70,7 +77,9 @@ type CreateEventRequest struct {
 // @Accept json
 // @Produce json
 // @Param event body CreateEventRequest true "Event data"
+// @Param tz query string false "Times in response: local (trip time zone, default) or utc"
 // @Success 201 {object} dto.GetEvent
+// @Header 201 {string} ETag "Event version"
 // @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /api/v1/trip/event [post]
@@ -83,6 +92,11 @@ func (h *EventHandler) CreateEvent(c *gin.Context) {
 		return
 	}
 
+	tz, ok := bindTimeZone(c, h.lg)
+	if !ok {
+		return
+	}
+
 	// todo: в конвертер
 	event := model.Event{
 		Name:      req.Name,
@@ -90,6 +104,7 @@ func (h *EventHandler) CreateEvent(c *gin.Context) {
 		TripID:    req.TripID,
 		StartTime: req.StartTime,
 		EndTime:   req.EndTime,
+		Pinned:    req.Pinned,
 	}
 	event, err := h.eventService.CreateEvent(c.Request.Context(), event)
 	if err != nil {
@@ -97,25 +112,31 @@ func (h *EventHandler) CreateEvent(c *gin.Context) {
 		c.JSON(domain.GetStatusCodeByError(err), gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusCreated, gin.H{"event": dto.EventConverter{}.ToDto(event)})
+	setETag(c, event.Version)
+	c.JSON(http.StatusCreated, gin.H{"event": h.eventToDto(c, tz, event)})
 }
 
 type UpdateEventRequest struct {
 	ID        uuid.UUID `json:"id" binding:"required"`
 	Name      string    `json:"name"`
-	StartTime string    `json:"start_time"`
-	EndTime   string    `json:"end_time"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
 }
 
 // @Summary Update event
-// @Description Update event data
+// @Description Update event data. With If-Match the event is updated only if its version
+// @Description is still the given ETag, otherwise 409 is returned with the current event.
 // @Tags event
 // @Accept json
 // @Produce json
 // @Param event body UpdateEventRequest true "Event data"
+// @Param If-Match header string false "ETag of the event being updated"
+// @Param tz query string false "Times in response: local (trip time zone, default) or utc"
 // @Success 200 {object} model.Event
+// @Header 200 {string} ETag "New event version"
 // @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]interface{} "Error and the current event"
 // @Failure 500 {object} map[string]string
 // @Router /api/v1/trip/event [put]
 func (h *EventHandler) UpdateEvent(c *gin.Context) {
@@ -127,19 +148,111 @@ func (h *EventHandler) UpdateEvent(c *gin.Context) {
 		return
 	}
 
+	tz, ok := bindTimeZone(c, h.lg)
+	if !ok {
+		return
+	}
+
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		h.lg.WithError(err).Errorf("failed to parse header")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	updatedEvent, err := h.eventService.UpdateEvent(c.Request.Context(), model.Event{
 		ID:        req.ID,
 		Name:      req.Name,
 		StartTime: req.StartTime,
 		EndTime:   req.EndTime,
+		Version:   version,
 	})
+	if errors.Is(err, domain.ErrVersionConflict) {
+		h.eventConflict(c, tz, req.ID, err)
+		return
+	}
 	if err != nil {
 		h.lg.WithError(err).Errorf("failed to update event %s", req.ID)
 		c.JSON(domain.GetStatusCodeByError(err), gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"event": dto.EventConverter{}.ToDto(updatedEvent)})
+	setETag(c, updatedEvent.Version)
+	c.JSON(http.StatusOK, gin.H{"event": h.eventToDto(c, tz, updatedEvent)})
+}
+
+type PinEventRequest struct {
+	ID     uuid.UUID `json:"id" binding:"required"`
+	Pinned bool      `json:"pinned"`
+}
+
+// @Summary Pin event
+// @Description Pin or unpin an event. Pinned events, e.g. booked slots, are kept when the trip
+// @Description is scheduled again and other places are scheduled around them.
+// @Tags event
+// @Accept json
+// @Produce json
+// @Param event body PinEventRequest true "Event ID and pinned flag"
+// @Param If-Match header string false "ETag of the event being updated"
+// @Param tz query string false "Times in response: local (trip time zone, default) or utc"
+// @Success 200 {object} dto.GetEvent
+// @Header 200 {string} ETag "New event version"
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]interface{} "Error and the current event"
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/trip/event/pin [put]
+func (h *EventHandler) PinEvent(c *gin.Context) {
+	var req PinEventRequest
+
+	if err := c.BindJSON(&req); err != nil {
+		h.lg.WithError(err).Errorf("failed to parse body")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tz, ok := bindTimeZone(c, h.lg)
+	if !ok {
+		return
+	}
+
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		h.lg.WithError(err).Errorf("failed to parse header")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	event, err := h.eventService.PinEvent(c.Request.Context(), req.ID, req.Pinned, version)
+	if errors.Is(err, domain.ErrVersionConflict) {
+		h.eventConflict(c, tz, req.ID, err)
+		return
+	}
+	if err != nil {
+		h.lg.WithError(err).Errorf("failed to pin event %s", req.ID)
+		c.JSON(domain.GetStatusCodeByError(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	setETag(c, event.Version)
+	c.JSON(http.StatusOK, gin.H{"event": h.eventToDto(c, tz, event)})
+}
+
+// eventConflict answers a stale update with the current event,
+// so the client can merge its changes and retry.
+func (h *EventHandler) eventConflict(c *gin.Context, tz TimeZoneQuery, eventID uuid.UUID, err error) {
+	event, getErr := h.eventService.GetEventByID(c.Request.Context(), eventID)
+	if getErr != nil {
+		h.lg.WithError(getErr).Errorf("failed to get event %s", eventID)
+		c.JSON(domain.GetStatusCodeByError(getErr), gin.H{"error": getErr.Error()})
+		return
+	}
+
+	setETag(c, event.Version)
+	c.JSON(http.StatusConflict, gin.H{
+		"error": err.Error(),
+		"event": h.eventToDto(c, tz, event),
+	})
 }
 
 // @Summary Delete event
@@ -175,7 +288,9 @@ func (h *EventHandler) DeleteEvent(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param event_id query string true "Event ID"
+// @Param tz query string false "Times in response: local (trip time zone, default) or utc"
 // @Success 200 {object} model.Event
+// @Header 200 {string} ETag "Event version"
 // @Failure 404 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /api/v1/trip/event [get]
@@ -187,6 +302,11 @@ func (h *EventHandler) GetEvent(c *gin.Context) {
 		return
 	}
 
+	tz, ok := bindTimeZone(c, h.lg)
+	if !ok {
+		return
+	}
+
 	event, err := h.eventService.GetEventByID(c.Request.Context(), eventID)
 	if err != nil {
 		h.lg.WithError(err).Errorf("failed to get event %s from trip %s", eventID, event.TripID)
@@ -194,11 +314,26 @@ func (h *EventHandler) GetEvent(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"event": dto.EventConverter{}.ToDto(event)})
+	setETag(c, event.Version)
+	c.JSON(http.StatusOK, gin.H{"event": h.eventToDto(c, tz, event)})
+}
+
+// eventToDto returns event times in the zone requested by tz.
+func (h *EventHandler) eventToDto(c *gin.Context, tz TimeZoneQuery, event model.Event) dto.GetEvent {
+	var trip model.Trip
+	if tz.TZ != "utc" {
+		var err error
+		trip, err = h.tripService.GetTripByEventID(c.Request.Context(), event.ID)
+		if err != nil {
+			h.lg.WithError(err).Errorf("failed to get trip of event %s", event.ID)
+		}
+	}
+
+	return dto.EventConverter{}.ToDto(event.In(tz.Location(trip)))
 }
 
 // @Summary Delete trip events
-// @Description Delete all events by trip ID
+// @Description Delete all events by trip ID, pinned ones included
 // @Tags event
 // @Accept json
 // @Produce json
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/ShelbyKS/Roamly-backend/internal/domain/service"
 	"github.com/ShelbyKS/Roamly-backend/internal/handler/dto"
@@ -18,14 +19,21 @@ import (
 type PlaceHandler struct {
 	lg           *logrus.Logger
 	placeService service.IPlaceService
+	photoService service.IPhotoService
 	client       googleapi.GoogleApiClient
+	// photoMaxAge is how long clients may cache photos, they never change
+	// for a reference and width.
+	photoMaxAge time.Duration
 }
 
-func NewPlaceHandler(router *gin.Engine, lg *logrus.Logger, placeService service.IPlaceService, client googleapi.GoogleApiClient) {
+func NewPlaceHandler(router *gin.Engine, lg *logrus.Logger, placeService service.IPlaceService, photoService service.IPhotoService,
+	client googleapi.GoogleApiClient, photoMaxAge time.Duration) {
 	handler := &PlaceHandler{
 		lg:           lg,
 		placeService: placeService,
+		photoService: photoService,
 		client:       client,
+		photoMaxAge:  photoMaxAge,
 	}
 
 	// api := router.Group("/api/v1")
@@ -114,11 +122,13 @@ func (h *PlaceHandler) GetPlaces(c *gin.Context) {
 }
 
 // @Summary Get place photo
-// @Description Get a photo of a place by photo reference
+// @Description Get a photo of a place by photo reference, the width is rounded up to one of 100, 200, 400, 800, 1600
 // @Tags place
 // @Produce image/jpeg
 // @Param reference query string true "Photo reference ID"
+// @Param width query int false "Minimal width in pixels, 200 by default"
 // @Success 200 {file} jpeg "Binary image data of the place photo"
+// @Success 304 "Not modified, the photo matches If-None-Match"
 // @Failure 400 {object} map[string]string "Bad request"
 // @Failure 404 {object} map[string]string "Not found"
 // @Failure 500 {object} map[string]string "Internal server error"
@@ -126,17 +136,32 @@ func (h *PlaceHandler) GetPlaces(c *gin.Context) {
 func (h *PlaceHandler) GetPhoto(c *gin.Context) {
 	reference := c.Query("reference")
 
-	file, err := h.client.GetPlacePhoto(c.Request.Context(), reference)
+	width := 0
+	if value, ok := c.GetQuery("width"); ok {
+		var err error
+		width, err = strconv.Atoi(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid width"})
+			return
+		}
+	}
+
+	photo, err := h.photoService.GetPlacePhoto(c.Request.Context(), reference, width)
 	if err != nil {
 		h.lg.WithError(err).Errorf("failed to get place %s photo", reference)
 		c.JSON(domain.GetStatusCodeByError(err), gin.H{"error": err.Error()})
 		return
 	}
 
-	fileName := "photo.jpg"
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d, immutable", int(h.photoMaxAge.Seconds())))
+	c.Header("ETag", photo.ETag)
+	if ifNoneMatch(c, photo.ETag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
 
-	c.Header("Content-Disposition", `inline; filename="`+fileName+`"`)
-	c.Data(http.StatusOK, "text/plain", file)
+	c.Header("Content-Disposition", "inline")
+	c.Data(http.StatusOK, photo.ContentType, photo.Data)
 }
 
 func (h *PlaceHandler) GetPlacesNearby(c *gin.Context) {
@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/ShelbyKS/Roamly-backend/internal/domain"
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/service"
+	"github.com/ShelbyKS/Roamly-backend/internal/handler/dto"
+	"github.com/ShelbyKS/Roamly-backend/internal/middleware"
+)
+
+type PresenceHandler struct {
+	lg              *logrus.Logger
+	presenceService service.IPresenceService
+}
+
+func NewPresenceHandler(
+	router *gin.Engine,
+	lg *logrus.Logger,
+	presenceService service.IPresenceService,
+	tripService service.ITripService,
+) {
+	handler := &PresenceHandler{
+		lg:              lg,
+		presenceService: presenceService,
+	}
+
+	tripGroup := router.Group("/api/v1/trip")
+	tripGroup.Use(middleware.Mw.AuthMiddleware())
+	{
+		tripGroup.GET("/:trip_id/presence",
+			middleware.AccessTripMiddleware(tripService, middleware.ForAll),
+			handler.GetTripPresence)
+	}
+}
+
+// @Summary Get trip presence
+// @Description Get members viewing the trip right now and what they have focused. Members join over the notifier websocket.
+// @Tags trip
+// @Produce json
+// @Param trip_id path string true "Trip ID"
+// @Success 200 {object} map[string][]dto.PresenceResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/trip/{trip_id}/presence [get]
+func (h *PresenceHandler) GetTripPresence(c *gin.Context) {
+	tripID, err := uuid.Parse(c.Param("trip_id"))
+	if err != nil {
+		h.lg.WithError(err).Errorf("failed to parse trip id")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid trip ID"})
+		return
+	}
+
+	presences, err := h.presenceService.GetTripPresence(c.Request.Context(), tripID)
+	if err != nil {
+		h.lg.WithError(err).Errorf("failed to get presence of trip %s", tripID)
+		c.JSON(domain.GetStatusCodeByError(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	presencesDto := make([]dto.PresenceResponse, len(presences))
+	for i, presence := range presences {
+		presencesDto[i] = dto.PresenceConverter{}.ToDto(presence)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"presence": presencesDto})
+}
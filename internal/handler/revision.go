@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/ShelbyKS/Roamly-backend/internal/domain"
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/service"
+	"github.com/ShelbyKS/Roamly-backend/internal/handler/dto"
+	"github.com/ShelbyKS/Roamly-backend/internal/middleware"
+)
+
+type RevisionHandler struct {
+	lg              *logrus.Logger
+	revisionService service.IRevisionService
+	tripService     service.ITripService
+}
+
+func NewRevisionHandler(
+	router *gin.Engine,
+	lg *logrus.Logger,
+	revisionService service.IRevisionService,
+	tripService service.ITripService,
+) {
+	handler := &RevisionHandler{
+		lg:              lg,
+		revisionService: revisionService,
+		tripService:     tripService,
+	}
+
+	tripGroup := router.Group("/api/v1/trip")
+	tripGroup.Use(middleware.Mw.AuthMiddleware())
+	{
+		tripGroup.GET("/:trip_id/revisions",
+			middleware.AccessTripMiddleware(tripService, middleware.ForAll),
+			handler.GetRevisions)
+
+		tripGroup.POST("/:trip_id/revisions/:revision_id/restore",
+			middleware.AccessTripMiddleware(tripService, middleware.ForOwnerAndEditor),
+			handler.RestoreRevision)
+	}
+}
+
+type GetRevisionsRequest struct {
+	TimeZoneQuery
+	Limit  int `form:"limit" binding:"omitempty,min=1,max=200"`
+	Offset int `form:"offset" binding:"omitempty,min=0"`
+}
+
+// @Summary Get trip revisions
+// @Description Get changes of the trip places and events with their state before and after, the latest first
+// @Tags trip
+// @Produce json
+// @Param trip_id path string true "Trip ID"
+// @Param limit query int false "Max revisions (default 50, max 200)"
+// @Param offset query int false "Revisions to skip"
+// @Param tz query string false "Times in response: local (trip time zone, default) or utc"
+// @Success 200 {object} map[string][]dto.RevisionResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/trip/{trip_id}/revisions [get]
+func (h *RevisionHandler) GetRevisions(c *gin.Context) {
+	tripID, err := uuid.Parse(c.Param("trip_id"))
+	if err != nil {
+		h.lg.WithError(err).Errorf("failed to parse trip id")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid trip ID"})
+		return
+	}
+
+	var req GetRevisionsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		h.lg.WithError(err).Errorf("failed to parse query")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = 50
+	}
+
+	trip, err := h.tripService.GetTripByID(c.Request.Context(), tripID)
+	if err != nil {
+		h.lg.WithError(err).Errorf("failed to get trip %s", tripID)
+		c.JSON(domain.GetStatusCodeByError(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	revisions, err := h.revisionService.GetRevisions(c.Request.Context(), tripID, req.Limit, req.Offset)
+	if err != nil {
+		h.lg.WithError(err).Errorf("failed to get revisions of trip %s", tripID)
+		c.JSON(domain.GetStatusCodeByError(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	loc := req.Location(trip)
+	revisionsDto := make([]dto.RevisionResponse, len(revisions))
+	for i, revision := range revisions {
+		revisionsDto[i] = dto.RevisionConverter{}.ToDto(revision, loc)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"revisions": revisionsDto})
+}
+
+// @Summary Restore trip revision
+// @Description Return the trip places and events to their state after the revision. The restore is recorded as a new revision and sent to the trip members.
+// @Tags trip
+// @Produce json
+// @Param trip_id path string true "Trip ID"
+// @Param revision_id path int true "Revision ID"
+// @Param tz query string false "Times in response: local (trip time zone, default) or utc"
+// @Success 200 {object} dto.TripResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/trip/{trip_id}/revisions/{revision_id}/restore [post]
+func (h *RevisionHandler) RestoreRevision(c *gin.Context) {
+	tripID, err := uuid.Parse(c.Param("trip_id"))
+	if err != nil {
+		h.lg.WithError(err).Errorf("failed to parse trip id")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid trip ID"})
+		return
+	}
+
+	revisionID, err := strconv.ParseInt(c.Param("revision_id"), 10, 64)
+	if err != nil {
+		h.lg.WithError(err).Errorf("failed to parse revision id")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid revision ID"})
+		return
+	}
+
+	tz, ok := bindTimeZone(c, h.lg)
+	if !ok {
+		return
+	}
+
+	trip, err := h.revisionService.RestoreRevision(c.Request.Context(), tripID, revisionID)
+	if err != nil {
+		h.lg.WithError(err).Errorf("failed to restore revision %d of trip %s", revisionID, tripID)
+		c.JSON(domain.GetStatusCodeByError(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	setETag(c, trip.Version)
+	c.JSON(http.StatusOK, gin.H{"trip": tz.Trip(trip)})
+}
@@ -2,7 +2,9 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -65,14 +67,34 @@ func NewTripHandler(
 			middleware.AccessTripMiddleware(tripService, middleware.ForOwnerAndEditor),
 			handler.DeletePlaceFromTrip)
 
+		tripGroup.DELETE("/:trip_id/place/:place_id/cache",
+			middleware.AccessTripMiddleware(tripService, middleware.ForOwnerAndEditor),
+			handler.InvalidatePlace)
+
 		tripGroup.POST("/place",
 			middleware.AccessTripByTripIdFromBodyMiddleware(tripService, middleware.ForOwnerAndEditor),
 			handler.AddPlaceToTrip)
 
+		tripGroup.POST("/:trip_id/place/custom",
+			middleware.AccessTripMiddleware(tripService, middleware.ForOwnerAndEditor),
+			handler.CreateCustomPlace)
+
+		tripGroup.PUT("/:trip_id/place/:place_id",
+			middleware.AccessTripMiddleware(tripService, middleware.ForOwnerAndEditor),
+			handler.UpdateCustomPlace)
+
 		tripGroup.POST("/:trip_id/schedule/auto",
 			middleware.AccessTripMiddleware(tripService, middleware.ForOwnerAndEditor),
 			handler.AutoScheduleTrip)
 
+		tripGroup.POST("/:trip_id/schedule/apply",
+			middleware.AccessTripMiddleware(tripService, middleware.ForOwnerAndEditor),
+			handler.ApplySchedulePreview)
+
+		tripGroup.PUT("/:trip_id/legs/:leg_id",
+			middleware.AccessTripMiddleware(tripService, middleware.ForOwnerAndEditor),
+			handler.SetLegMode)
+
 		tripGroup.DELETE("/:trip_id/user",
 			middleware.AccessTripMiddleware(tripService, middleware.ForAll),
 			handler.DeleteUserFromTrip,
@@ -81,11 +103,13 @@ func NewTripHandler(
 }
 
 // @Summary Get trip by ID
-// @Description Get data of a specific trip by its ID
+// @Description Get data of a specific trip by its ID, the ETag header is the trip version
 // @Tags trip
 // @Produce json
 // @Param trip_id path string true "Trip ID"
+// @Param tz query string false "Times in response: local (trip time zone, default) or utc"
 // @Success 200 {object} model.Trip
+// @Header 200 {string} ETag "Trip version"
 // @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
 // @Failure 500 {object} map[string]string
@@ -99,6 +123,11 @@ func (h *TripHandler) GetTripByID(c *gin.Context) {
 		return
 	}
 
+	tz, ok := bindTimeZone(c, h.lg)
+	if !ok {
+		return
+	}
+
 	trip, err := h.tripService.GetTripByID(c.Request.Context(), id)
 	if err != nil {
 		h.lg.WithError(err).Errorf("Fail to get trip with id=%d", id)
@@ -106,8 +135,9 @@ func (h *TripHandler) GetTripByID(c *gin.Context) {
 		return
 	}
 
+	setETag(c, trip.Version)
 	c.JSON(http.StatusOK, gin.H{
-		"trip": dto.TripConverter{}.ToDto(trip),
+		"trip": tz.Trip(trip),
 	})
 }
 
@@ -115,6 +145,7 @@ func (h *TripHandler) GetTripByID(c *gin.Context) {
 // @Description Get list trips
 // @Tags trip
 // @Produce json
+// @Param tz query string false "Times in response: local (trip time zone, default) or utc"
 // @Success 200 {object} []model.Trip
 // @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
@@ -134,6 +165,11 @@ func (h *TripHandler) GetTrips(c *gin.Context) {
 		return
 	}
 
+	tz, ok := bindTimeZone(c, h.lg)
+	if !ok {
+		return
+	}
+
 	trips, err := h.tripService.GetTrips(c.Request.Context(), id)
 	if err != nil {
 		h.lg.WithError(err).Errorf("Fail to get list trip")
@@ -143,7 +179,7 @@ func (h *TripHandler) GetTrips(c *gin.Context) {
 
 	tripsDto := make([]dto.TripResponse, len(trips))
 	for i, trip := range trips {
-		tripsDto[i] = dto.TripConverter{}.ToDto(trip)
+		tripsDto[i] = tz.Trip(trip)
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -179,10 +215,15 @@ func (h *TripHandler) DeleteTrip(c *gin.Context) {
 }
 
 type CreateTripRequest struct {
-	Name      string `json:"name" form:"name" binding:"required"`
-	StartTime string `json:"start_time" form:"start_time" binding:"required"`
-	EndTime   string `json:"end_time" form:"end_time" binding:"required"`
-	AreaID    string `json:"area_id" form:"area_id" binding:"required"`
+	Name      string    `json:"name" form:"name" binding:"required"`
+	StartTime time.Time `json:"start_time" form:"start_time" time_format:"2006-01-02T15:04:05Z07:00" binding:"required"`
+	EndTime   time.Time `json:"end_time" form:"end_time" time_format:"2006-01-02T15:04:05Z07:00" binding:"required"`
+	AreaID    string    `json:"area_id" form:"area_id" binding:"required"`
+	// TravelMode is driving (default), walking, bicycling or transit.
+	TravelMode string `json:"travel_mode" form:"travel_mode"`
+	// TimeZone is the IANA zone of the area, by default it is looked up by
+	// the geo provider. Required for local planning with the osm provider.
+	TimeZone string `json:"time_zone" form:"time_zone"`
 }
 
 // @Summary Create a new trip
@@ -220,10 +261,12 @@ func (h *TripHandler) CreateTrip(c *gin.Context) {
 	}
 
 	id, err := h.tripService.CreateTrip(c.Request.Context(), model.Trip{
-		Name:      tripReq.Name,
-		StartTime: tripReq.StartTime,
-		EndTime:   tripReq.EndTime,
-		AreaID:    tripReq.AreaID,
+		Name:       tripReq.Name,
+		StartTime:  tripReq.StartTime,
+		EndTime:    tripReq.EndTime,
+		AreaID:     tripReq.AreaID,
+		TravelMode: tripReq.TravelMode,
+		TimeZone:   tripReq.TimeZone,
 		Users: []*model.User{
 			{
 				ID: userIDInt,
@@ -251,19 +294,27 @@ func (h *TripHandler) CreateTrip(c *gin.Context) {
 type UpdateTripRequest struct {
 	ID        uuid.UUID `json:"id" binding:"required"`
 	Name      string    `json:"name" binding:"required"`
-	StartTime string    `json:"start_time" binding:"required"`
-	EndTime   string    `json:"end_time" binding:"required"`
+	StartTime time.Time `json:"start_time" binding:"required"`
+	EndTime   time.Time `json:"end_time" binding:"required"`
+	// TravelMode is driving, walking, bicycling or transit, empty keeps the current one.
+	TravelMode string `json:"travel_mode"`
+	// TimeZone is the IANA zone of the area, empty keeps the current one.
+	TimeZone string `json:"time_zone"`
 }
 
 // @Summary Update trip
-// @Description Update trip data
+// @Description Update trip data. With If-Match the trip is updated only if its version
+// @Description is still the given ETag, otherwise 409 is returned with the current trip.
 // @Tags trip
 // @Accept  json
 // @Produce  json
 // @Param trip body UpdateTripRequest true "Trip data"
-// @Success 200 {object} model.Trip
+// @Param If-Match header string false "ETag of the trip being updated"
+// @Success 200 {object} map[string]string
+// @Header 200 {string} ETag "New trip version"
 // @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]interface{} "Error and the current trip"
 // @Failure 500 {object} map[string]string
 // @Router /api/v1/trip [put]
 func (h *TripHandler) UpdateTrip(c *gin.Context) {
@@ -276,18 +327,95 @@ func (h *TripHandler) UpdateTrip(c *gin.Context) {
 		return
 	}
 
-	err = h.tripService.UpdateTrip(c.Request.Context(), model.Trip{
-		ID:        tripReq.ID,
-		Name:      tripReq.Name,
-		StartTime: tripReq.StartTime,
-		EndTime:   tripReq.EndTime,
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		h.lg.WithError(err).Errorf("failed to parse header")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	trip, err := h.tripService.UpdateTrip(c.Request.Context(), model.Trip{
+		ID:         tripReq.ID,
+		Name:       tripReq.Name,
+		StartTime:  tripReq.StartTime,
+		EndTime:    tripReq.EndTime,
+		TravelMode: tripReq.TravelMode,
+		TimeZone:   tripReq.TimeZone,
+		Version:    version,
 	})
+	if errors.Is(err, domain.ErrVersionConflict) {
+		h.tripConflict(c, tripReq.ID, err)
+		return
+	}
 	if err != nil {
 		h.lg.WithError(err).Errorf("failed to update trip with id=%d", tripReq.ID)
 		c.JSON(domain.GetStatusCodeByError(err), gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"id": tripReq.ID})
+
+	setETag(c, trip.Version)
+	c.JSON(http.StatusOK, gin.H{"id": tripReq.ID, "version": trip.Version})
+}
+
+// tripConflict answers a stale update with the current trip,
+// so the client can merge its changes and retry.
+func (h *TripHandler) tripConflict(c *gin.Context, tripID uuid.UUID, err error) {
+	trip, getErr := h.tripService.GetTripByID(c.Request.Context(), tripID)
+	if getErr != nil {
+		h.lg.WithError(getErr).Errorf("failed to get trip with id=%s", tripID)
+		c.JSON(domain.GetStatusCodeByError(getErr), gin.H{"error": getErr.Error()})
+		return
+	}
+
+	setETag(c, trip.Version)
+	c.JSON(http.StatusConflict, gin.H{
+		"error": err.Error(),
+		"trip":  TimeZoneQuery{}.Trip(trip),
+	})
+}
+
+// TimeZoneQuery selects how times are returned: in the trip time zone or in UTC.
+type TimeZoneQuery struct {
+	TZ string `form:"tz" binding:"omitempty,oneof=local utc"`
+}
+
+// bindTimeZone parses the tz query, replying with 400 when it is invalid.
+func bindTimeZone(c *gin.Context, lg *logrus.Logger) (TimeZoneQuery, bool) {
+	var tz TimeZoneQuery
+	if err := c.ShouldBindQuery(&tz); err != nil {
+		lg.WithError(err).Errorf("failed to parse query")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return TimeZoneQuery{}, false
+	}
+	return tz, true
+}
+
+func (q TimeZoneQuery) Location(trip model.Trip) *time.Location {
+	if q.TZ == "utc" {
+		return time.UTC
+	}
+	return trip.Location()
+}
+
+func (q TimeZoneQuery) Trip(trip model.Trip) dto.TripResponse {
+	return dto.TripConverter{}.ToDto(trip.In(q.Location(trip)))
+}
+
+type ScheduleTripRequest struct {
+	TimeZoneQuery
+	Engine          string `form:"engine" binding:"omitempty,oneof=solver llm"`
+	DayStart        string `form:"day_start"`
+	MaxPlacesPerDay int    `form:"max_places_per_day" binding:"omitempty,min=1"`
+	// DryRun returns a preview to review and apply instead of saving the schedule.
+	DryRun bool `form:"dry_run"`
+}
+
+func (req ScheduleTripRequest) toOptions() model.ScheduleOptions {
+	return model.ScheduleOptions{
+		Engine:          req.Engine,
+		DayStart:        req.DayStart,
+		MaxPlacesPerDay: req.MaxPlacesPerDay,
+	}
 }
 
 // @Summary Schedule trip
@@ -295,6 +423,11 @@ func (h *TripHandler) UpdateTrip(c *gin.Context) {
 // @Tags trip
 // @Produce json
 // @Param trip_id path string true "Trip ID"
+// @Param engine query string false "Schedule engine: solver (default) or llm"
+// @Param day_start query string false "Daily start time, HH:MM (default 10:00)"
+// @Param max_places_per_day query int false "Max places per day (default 3)"
+// @Param dry_run query bool false "Return a preview with the diff against the current events instead of saving"
+// @Param tz query string false "Times in response: local (trip time zone, default) or utc"
 // @Success 200 {object} model.Trip
 // @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
@@ -309,14 +442,29 @@ func (h *TripHandler) ScheduleTrip(c *gin.Context) {
 		return
 	}
 
-	trip, err := h.schedulerService.ScheduleTrip(c.Request.Context(), tripID)
+	var req ScheduleTripRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		h.lg.WithError(err).Errorf("failed to parse query")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.DryRun {
+		h.previewSchedule(c, tripID, req, false)
+		return
+	}
+
+	trip, warnings, err := h.schedulerService.ScheduleTrip(c.Request.Context(), tripID, req.toOptions())
 	if err != nil {
 		h.lg.WithError(err).Errorf("failed to schedule trip with id=%d", tripID)
 		c.JSON(domain.GetStatusCodeByError(err), gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"trip": dto.TripConverter{}.ToDto(trip)})
+	c.JSON(http.StatusOK, gin.H{
+		"trip":     req.Trip(trip),
+		"warnings": dto.ScheduleWarningConverter{}.ToDto(warnings),
+	})
 }
 
 // @Summary Add place to trip
@@ -325,6 +473,7 @@ func (h *TripHandler) ScheduleTrip(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param trip-place body AddPlaceToTripRequest true "JSON containing trip and place IDs"
+// @Param tz query string false "Times in response: local (trip time zone, default) or utc"
 // @Success 200 {object} dto.TripResponse
 // @Failure 400 {object} map[string]string "Bad request"
 // @Failure 404 {object} map[string]string "Not found"
@@ -347,6 +496,11 @@ func (h *TripHandler) AddPlaceToTrip(c *gin.Context) {
 		return
 	}
 
+	tz, ok := bindTimeZone(c, h.lg)
+	if !ok {
+		return
+	}
+
 	trip, err := h.placesService.AddPlaceToTrip(c.Request.Context(), tripUUID, req.PlaceID)
 	if err != nil {
 		h.lg.WithError(err).Errorf("failed to add place to trip")
@@ -354,7 +508,7 @@ func (h *TripHandler) AddPlaceToTrip(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"trip": dto.TripConverter{}.ToDto(trip)})
+	c.JSON(http.StatusOK, gin.H{"trip": tz.Trip(trip)})
 
 	go func() {
 		ctx := context.Background()
@@ -371,6 +525,7 @@ func (h *TripHandler) AddPlaceToTrip(c *gin.Context) {
 // @Tags place
 // @Accept json
 // @Produce json
+// @Param tz query string false "Times in response: local (trip time zone, default) or utc"
 // @Success 200 {object} dto.TripResponse
 // @Failure 400 {object} map[string]string "Bad request"
 // @Failure 404 {object} map[string]string "Not found"
@@ -387,6 +542,11 @@ func (h *TripHandler) DeletePlaceFromTrip(c *gin.Context) {
 		return
 	}
 
+	tz, ok := bindTimeZone(c, h.lg)
+	if !ok {
+		return
+	}
+
 	trip, err := h.placesService.DeletePlace(c.Request.Context(), tripUUID, placeID)
 	if err != nil {
 		h.lg.WithError(err).Errorf("failed to remove place from trip")
@@ -394,7 +554,150 @@ func (h *TripHandler) DeletePlaceFromTrip(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"trip": dto.TripConverter{}.ToDto(trip)})
+	c.JSON(http.StatusOK, gin.H{"trip": tz.Trip(trip)})
+}
+
+// @Summary Invalidate cached place
+// @Description Drop cached google api details and distances of the trip place, they are fetched again on the next request
+// @Tags place
+// @Param trip_id path string true "Trip ID"
+// @Param place_id path string true "Place ID"
+// @Success 204
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 404 {object} map[string]string "Not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /api/v1/trip/{trip_id}/place/{place_id}/cache [delete]
+func (h *TripHandler) InvalidatePlace(c *gin.Context) {
+	tripID := c.Param("trip_id")
+	placeID := c.Param("place_id")
+
+	tripUUID, err := uuid.Parse(tripID)
+	if err != nil {
+		h.lg.WithError(err).Errorf("invalid trip_id format")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid trip_id format"})
+		return
+	}
+
+	err = h.placesService.InvalidatePlace(c.Request.Context(), tripUUID, placeID)
+	if err != nil {
+		h.lg.WithError(err).Errorf("failed to invalidate place %s", placeID)
+		c.JSON(domain.GetStatusCodeByError(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+type CustomPlaceRequest struct {
+	Name    string   `json:"name" binding:"required"`
+	Lat     *float64 `json:"lat" binding:"required"`
+	Lng     *float64 `json:"lng" binding:"required"`
+	Address string   `json:"address"`
+	Notes   string   `json:"notes"`
+	// Duration of the visit in minutes, 0 lets the scheduler choose.
+	Duration int `json:"duration"`
+}
+
+func (req CustomPlaceRequest) Place() model.Place {
+	return model.Place{
+		GooglePlace: model.GooglePlace{
+			Name:             req.Name,
+			FormattedAddress: req.Address,
+			Geometry:         model.Geometry{Location: model.Location{Lat: *req.Lat, Lng: *req.Lng}},
+		},
+		Notes:                       req.Notes,
+		RecommendedVisitingDuration: req.Duration,
+	}
+}
+
+// @Summary Create custom place
+// @Description Add a place unknown to maps, e.g. a hotel or a meeting point, the place is seen only in this trip
+// @Tags place
+// @Accept json
+// @Produce json
+// @Param trip_id path string true "Trip ID"
+// @Param place body CustomPlaceRequest true "Custom place"
+// @Param tz query string false "Times in response: local (trip time zone, default) or utc"
+// @Success 200 {object} dto.TripResponse
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 404 {object} map[string]string "Not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /api/v1/trip/{trip_id}/place/custom [post]
+func (h *TripHandler) CreateCustomPlace(c *gin.Context) {
+	tripID, err := uuid.Parse(c.Param("trip_id"))
+	if err != nil {
+		h.lg.WithError(err).Errorf("invalid trip_id format")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid trip_id format"})
+		return
+	}
+
+	var req CustomPlaceRequest
+	if err := c.BindJSON(&req); err != nil {
+		h.lg.WithError(err).Errorf("failed to parse body")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tz, ok := bindTimeZone(c, h.lg)
+	if !ok {
+		return
+	}
+
+	trip, err := h.placesService.CreateCustomPlace(c.Request.Context(), tripID, req.Place())
+	if err != nil {
+		h.lg.WithError(err).Errorf("failed to create custom place in trip %s", tripID)
+		c.JSON(domain.GetStatusCodeByError(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"trip": tz.Trip(trip)})
+}
+
+// @Summary Update custom place
+// @Description Change a custom place of the trip, places found on maps can't be changed
+// @Tags place
+// @Accept json
+// @Produce json
+// @Param trip_id path string true "Trip ID"
+// @Param place_id path string true "Place ID"
+// @Param place body CustomPlaceRequest true "Custom place"
+// @Param tz query string false "Times in response: local (trip time zone, default) or utc"
+// @Success 200 {object} dto.TripResponse
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 404 {object} map[string]string "Not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /api/v1/trip/{trip_id}/place/{place_id} [put]
+func (h *TripHandler) UpdateCustomPlace(c *gin.Context) {
+	tripID, err := uuid.Parse(c.Param("trip_id"))
+	if err != nil {
+		h.lg.WithError(err).Errorf("invalid trip_id format")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid trip_id format"})
+		return
+	}
+
+	var req CustomPlaceRequest
+	if err := c.BindJSON(&req); err != nil {
+		h.lg.WithError(err).Errorf("failed to parse body")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tz, ok := bindTimeZone(c, h.lg)
+	if !ok {
+		return
+	}
+
+	place := req.Place()
+	place.ID = c.Param("place_id")
+
+	trip, err := h.placesService.UpdateCustomPlace(c.Request.Context(), tripID, place)
+	if err != nil {
+		h.lg.WithError(err).Errorf("failed to update custom place %s", place.ID)
+		c.JSON(domain.GetStatusCodeByError(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"trip": tz.Trip(trip)})
 }
 
 // @Summary Delete user from trip
@@ -446,6 +749,11 @@ func (h *TripHandler) DeleteUserFromTrip(c *gin.Context) {
 // @Tags trip
 // @Produce json
 // @Param trip_id path string true "Trip ID"
+// @Param engine query string false "Schedule engine: solver (default) or llm"
+// @Param day_start query string false "Daily start time, HH:MM (default 10:00)"
+// @Param max_places_per_day query int false "Max places per day (default 3)"
+// @Param dry_run query bool false "Return a preview with the diff against the current events instead of saving"
+// @Param tz query string false "Times in response: local (trip time zone, default) or utc"
 // @Success 200 {object} model.Trip
 // @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
@@ -460,12 +768,153 @@ func (h *TripHandler) AutoScheduleTrip(c *gin.Context) {
 		return
 	}
 
-	trip, err := h.schedulerService.AutoScheduleTrip(c.Request.Context(), tripID)
+	var req ScheduleTripRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		h.lg.WithError(err).Errorf("failed to parse query")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.DryRun {
+		h.previewSchedule(c, tripID, req, true)
+		return
+	}
+
+	trip, warnings, err := h.schedulerService.AutoScheduleTrip(c.Request.Context(), tripID, req.toOptions())
 	if err != nil {
 		h.lg.WithError(err).Errorf("failed to auto schedule trip with id=%d", tripID)
 		c.JSON(domain.GetStatusCodeByError(err), gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"trip": dto.TripConverter{}.ToDto(trip)})
+	c.JSON(http.StatusOK, gin.H{
+		"trip":     req.Trip(trip),
+		"warnings": dto.ScheduleWarningConverter{}.ToDto(warnings),
+	})
+}
+
+func (h *TripHandler) previewSchedule(c *gin.Context, tripID uuid.UUID, req ScheduleTripRequest, auto bool) {
+	preview, err := h.schedulerService.PreviewSchedule(c.Request.Context(), tripID, req.toOptions(), auto)
+	if err != nil {
+		h.lg.WithError(err).Errorf("failed to preview schedule of trip with id=%s", tripID)
+		c.JSON(domain.GetStatusCodeByError(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	trip, err := h.tripService.GetTripByID(c.Request.Context(), tripID)
+	if err != nil {
+		h.lg.WithError(err).Errorf("failed to get trip with id=%s", tripID)
+		c.JSON(domain.GetStatusCodeByError(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"preview": dto.SchedulePreviewConverter{}.ToDto(preview, req.Location(trip)),
+	})
+}
+
+type ApplySchedulePreviewRequest struct {
+	PreviewID uuid.UUID `json:"preview_id" binding:"required"`
+}
+
+// @Summary Apply schedule preview
+// @Description Save a schedule made with dry_run. Fails with 409 and the current trip if the trip places or events changed since the preview was made.
+// @Tags trip
+// @Accept json
+// @Produce json
+// @Param trip_id path string true "Trip ID"
+// @Param preview body ApplySchedulePreviewRequest true "Preview ID"
+// @Param tz query string false "Times in response: local (trip time zone, default) or utc"
+// @Success 200 {object} model.Trip
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]interface{} "Error and the current trip"
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/trip/{trip_id}/schedule/apply [post]
+func (h *TripHandler) ApplySchedulePreview(c *gin.Context) {
+	tripID, err := uuid.Parse(c.Param("trip_id"))
+	if err != nil {
+		h.lg.WithError(err).Errorf("failed to parse query")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req ApplySchedulePreviewRequest
+	if err := c.BindJSON(&req); err != nil {
+		h.lg.WithError(err).Errorf("failed to parse body")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tz, ok := bindTimeZone(c, h.lg)
+	if !ok {
+		return
+	}
+
+	trip, warnings, err := h.schedulerService.ApplySchedulePreview(c.Request.Context(), tripID, req.PreviewID)
+	if errors.Is(err, domain.ErrVersionConflict) {
+		h.tripConflict(c, tripID, err)
+		return
+	}
+	if err != nil {
+		h.lg.WithError(err).Errorf("failed to apply schedule preview %s", req.PreviewID)
+		c.JSON(domain.GetStatusCodeByError(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	setETag(c, trip.Version)
+	c.JSON(http.StatusOK, gin.H{
+		"trip":     tz.Trip(trip),
+		"warnings": dto.ScheduleWarningConverter{}.ToDto(warnings),
+	})
+}
+
+type SetLegModeRequest struct {
+	// Mode is driving, walking, bicycling or transit, empty follows the trip travel mode.
+	Mode string `json:"mode"`
+}
+
+// @Summary Set travel leg mode
+// @Description Choose the travel mode of the leg between two consecutive events, the leg is measured again
+// @Tags trip
+// @Accept json
+// @Produce json
+// @Param trip_id path string true "Trip ID"
+// @Param leg_id path string true "Travel leg ID"
+// @Param leg body SetLegModeRequest true "Travel mode"
+// @Success 200 {object} dto.TravelLegResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/trip/{trip_id}/legs/{leg_id} [put]
+func (h *TripHandler) SetLegMode(c *gin.Context) {
+	tripID, err := uuid.Parse(c.Param("trip_id"))
+	if err != nil {
+		h.lg.WithError(err).Errorf("failed to parse query")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	legID, err := uuid.Parse(c.Param("leg_id"))
+	if err != nil {
+		h.lg.WithError(err).Errorf("failed to parse query")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req SetLegModeRequest
+	if err := c.BindJSON(&req); err != nil {
+		h.lg.WithError(err).Errorf("failed to parse body")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	leg, err := h.tripService.SetLegMode(c.Request.Context(), tripID, legID, req.Mode)
+	if err != nil {
+		h.lg.WithError(err).Errorf("failed to set mode of travel leg %s", legID)
+		c.JSON(domain.GetStatusCodeByError(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"leg": dto.TravelLegConverter{}.ToDto(leg)})
 }
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"errors"
+	"github.com/ShelbyKS/Roamly-backend/internal/domain"
 	"github.com/ShelbyKS/Roamly-backend/internal/domain/storage"
 	"github.com/gin-gonic/gin"
 	"net/http"
@@ -34,6 +35,7 @@ func (mw *Middleware) AuthMiddleware() gin.HandlerFunc {
 		}
 
 		c.Set("user_id", session.UserID)
+		c.Request = c.Request.WithContext(domain.ContextWithUserID(c.Request.Context(), session.UserID))
 		c.Next()
 	}
 }
@@ -67,7 +69,8 @@ func (mw *Middleware) CORSMiddleware() gin.HandlerFunc {
 		}
 
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Content-Length, X-Requested-With, Origin, X-CSRF-TOKEN")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Content-Length, X-Requested-With, Origin, X-CSRF-TOKEN, If-Match")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "ETag")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 
 		// Для preflight-запросов
@@ -0,0 +1,171 @@
+// Package outbox publishes notifications saved to the outbox table
+// to the message broker.
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/clients"
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/model"
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/storage"
+)
+
+const (
+	minBackoff      = time.Second
+	maxBackoff      = 5 * time.Minute
+	cleanupInterval = time.Hour
+
+	defaultSendTimeout = 10 * time.Second
+)
+
+type Options struct {
+	PollInterval time.Duration
+	BatchSize    int
+	Retention    time.Duration
+	// SendTimeout limits publishing of a single message, the batch
+	// transaction stays open meanwhile.
+	SendTimeout time.Duration
+}
+
+// Relay delivers outbox messages at least once: a message is marked as sent
+// only after the broker acknowledged it, failed ones are retried with backoff.
+// Several relays may run at once, each message is locked by one of them.
+type Relay struct {
+	transactor      storage.ITransactor
+	outboxStorage   storage.IOutboxStorage
+	messageProducer clients.IMessageProdcuer
+	lg              *logrus.Logger
+	opts            Options
+}
+
+func NewRelay(
+	transactor storage.ITransactor,
+	outboxStorage storage.IOutboxStorage,
+	messageProducer clients.IMessageProdcuer,
+	lg *logrus.Logger,
+	opts Options,
+) *Relay {
+	if opts.SendTimeout <= 0 {
+		opts.SendTimeout = defaultSendTimeout
+	}
+
+	return &Relay{
+		transactor:      transactor,
+		outboxStorage:   outboxStorage,
+		messageProducer: messageProducer,
+		lg:              lg,
+		opts:            opts,
+	}
+}
+
+// Run publishes pending messages until ctx is done.
+func (r *Relay) Run(ctx context.Context) {
+	poll := time.NewTicker(r.opts.PollInterval)
+	defer poll.Stop()
+
+	cleanup := time.NewTicker(cleanupInterval)
+	defer cleanup.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-cleanup.C:
+			r.cleanup(ctx)
+		case <-poll.C:
+			// drain the backlog without waiting for the next tick
+			for ctx.Err() == nil {
+				sent, err := r.relayBatch(ctx)
+				if err != nil {
+					r.lg.WithError(err).Error("Failed to relay outbox messages")
+					break
+				}
+				if sent < r.opts.BatchSize {
+					break
+				}
+			}
+		}
+	}
+}
+
+// relayBatch sends the oldest pending messages in order. After a failure the
+// rest of the trip messages stay pending, a later seq must never reach the
+// notifier before an earlier one.
+func (r *Relay) relayBatch(ctx context.Context) (int, error) {
+	var sent []int64
+
+	err := r.transactor.InTx(ctx, func(ctx context.Context) error {
+		messages, err := r.outboxStorage.LockPending(ctx, r.opts.BatchSize)
+		if err != nil {
+			return fmt.Errorf("failed to lock pending messages: %w", err)
+		}
+
+		failedTrips := make(map[uuid.UUID]struct{})
+		for _, message := range messages {
+			tripID := message.Message.Payload.TripID
+			if _, failed := failedTrips[tripID]; failed {
+				continue
+			}
+
+			err = r.send(ctx, message.Message)
+			if err != nil {
+				r.lg.WithError(err).Warnf("Failed to send outbox message %d, attempt %d", message.ID, message.Attempts+1)
+
+				err = r.outboxStorage.MarkFailed(ctx, message.ID, time.Now().Add(backoff(message)), err.Error())
+				if err != nil {
+					return fmt.Errorf("failed to mark message %d as failed: %w", message.ID, err)
+				}
+				failedTrips[tripID] = struct{}{}
+				continue
+			}
+
+			sent = append(sent, message.ID)
+		}
+
+		err = r.outboxStorage.MarkSent(ctx, sent)
+		if err != nil {
+			return fmt.Errorf("failed to mark messages as sent: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(sent), nil
+}
+
+func (r *Relay) send(ctx context.Context, message model.NotifyMessage) error {
+	ctx, cancel := context.WithTimeout(ctx, r.opts.SendTimeout)
+	defer cancel()
+
+	return r.messageProducer.SendMessage(ctx, message)
+}
+
+func (r *Relay) cleanup(ctx context.Context) {
+	deleted, err := r.outboxStorage.DeleteSent(ctx, time.Now().Add(-r.opts.Retention))
+	if err != nil {
+		r.lg.WithError(err).Error("Failed to delete sent outbox messages")
+		return
+	}
+
+	if deleted > 0 {
+		r.lg.Infof("Deleted %d sent outbox messages", deleted)
+	}
+}
+
+// backoff doubles the delay with every failed attempt up to maxBackoff.
+func backoff(message model.OutboxMessage) time.Duration {
+	delay := minBackoff
+	for i := 0; i < message.Attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+
+	return min(delay, maxBackoff)
+}
@@ -20,7 +20,7 @@ type AIChatService struct {
 	tripStorage    storage.ITripStorage
 	sessionStorage storage.ISessionStorage
 	notifyUtils    utils.NotifyUtils
-	openAIClient   clients.IChatClient
+	llmClient      clients.IChatClient
 	googleApi      clients.IGoogleApiClient
 }
 
@@ -29,7 +29,7 @@ func NewAIChatService(
 	tripStorage storage.ITripStorage,
 	sessionStorage storage.ISessionStorage,
 	notifyUtils utils.NotifyUtils,
-	openAIClient clients.IChatClient,
+	llmClient clients.IChatClient,
 	googleApi clients.IGoogleApiClient,
 ) service.IAIChatService {
 	return &AIChatService{
@@ -37,7 +37,7 @@ func NewAIChatService(
 		tripStorage:    tripStorage,
 		sessionStorage: sessionStorage,
 		notifyUtils:    notifyUtils,
-		openAIClient:   openAIClient,
+		llmClient:      llmClient,
 		googleApi:      googleApi,
 	}
 }
@@ -85,7 +85,7 @@ func (s *AIChatService) SentMessage(ctx context.Context, message model.ChatMessa
 		Content: prompt,
 	})
 
-	promptResp, err := s.openAIClient.PostPrompt(ctx, messageHistory, clients.ModelChatGPT4o)
+	promptResp, err := s.llmClient.PostPrompt(ctx, messageHistory, clients.LLMUseChat)
 	if err != nil {
 		s.sendEventIfFailed(ctx, message, userID)
 		return fmt.Errorf("failed to post prompt: %w", err)
@@ -4,36 +4,41 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
 
 	"github.com/ShelbyKS/Roamly-backend/internal/domain"
 
-	"github.com/ShelbyKS/Roamly-backend/internal/domain/clients"
 	"github.com/ShelbyKS/Roamly-backend/internal/domain/model"
 	"github.com/ShelbyKS/Roamly-backend/internal/domain/service"
 	"github.com/ShelbyKS/Roamly-backend/internal/domain/storage"
+	"github.com/ShelbyKS/Roamly-backend/internal/utils"
 	"github.com/google/uuid"
 )
 
 type EventService struct {
-	eventStorage    storage.IEventStorage
-	tripStorage     storage.ITripStorage
-	placeStorage    storage.IPlaceStorage
-	sessionStorage  storage.ISessionStorage
-	messageProducer clients.IMessageProdcuer
+	eventStorage  storage.IEventStorage
+	tripStorage   storage.ITripStorage
+	placeStorage  storage.IPlaceStorage
+	transactor    storage.ITransactor
+	notifyUtils   utils.NotifyUtils
+	revisionUtils utils.RevisionUtils
+	travelUtils   utils.TravelUtils
 }
 
 func NewEventService(eventStorage storage.IEventStorage,
 	tripStorage storage.ITripStorage,
 	placeStorage storage.IPlaceStorage,
-	sessionStorage storage.ISessionStorage,
-	messageProducer clients.IMessageProdcuer) service.IEventService {
+	transactor storage.ITransactor,
+	notifyUtils utils.NotifyUtils,
+	revisionUtils utils.RevisionUtils,
+	travelUtils utils.TravelUtils) service.IEventService {
 	return &EventService{
-		eventStorage:    eventStorage,
-		tripStorage:     tripStorage,
-		placeStorage:    placeStorage,
-		sessionStorage:  sessionStorage,
-		messageProducer: messageProducer,
+		eventStorage:  eventStorage,
+		tripStorage:   tripStorage,
+		placeStorage:  placeStorage,
+		transactor:    transactor,
+		notifyUtils:   notifyUtils,
+		revisionUtils: revisionUtils,
+		travelUtils:   travelUtils,
 	}
 }
 
@@ -50,131 +55,266 @@ func (service *EventService) GetEventByID(ctx context.Context, eventID uuid.UUID
 }
 
 func (service *EventService) DeleteEvent(ctx context.Context, eventID uuid.UUID) error {
-	err := service.eventStorage.DeleteEvent(ctx, eventID)
+	event, err := service.eventStorage.GetEventByID(ctx, eventID)
 	if errors.Is(err, domain.ErrEventNotFound) {
 		return err
 	}
 	if err != nil {
-		return fmt.Errorf("fail to delete event from storage: %w", err)
+		return fmt.Errorf("fail to get event from storage: %w", err)
 	}
 
-	tripFound, err := service.tripStorage.GetTripByEventID(ctx, eventID)
+	err = service.transactor.InTx(ctx, func(ctx context.Context) error {
+		err := service.revisionUtils.Record(ctx, event.TripID, model.RevisionEventDelete, domain.UserIDFromContext(ctx),
+			func(ctx context.Context) error {
+				err := service.eventStorage.DeleteEvent(ctx, eventID)
+				if errors.Is(err, domain.ErrEventNotFound) {
+					return err
+				}
+				if err != nil {
+					return fmt.Errorf("fail to delete event from storage: %w", err)
+				}
+
+				return nil
+			})
+		if err != nil {
+			return err
+		}
 
-	users := tripFound.Users
-	for _, user := range users {
-		cooks, _ := service.sessionStorage.GetTokensByUserID(ctx, user.ID)
-		// cookies = append(cookies, cooks...)
-		var message model.NotifyMessage
-		message.Payload.Action = "trip_events_update"
-		message.Payload.TripID = tripFound.ID
-		message.Payload.Author = fmt.Sprintf("%d", user.ID)
-		message.Payload.Message = "Из поездки удалено событие"
-		message.Clients = cooks
-		service.messageProducer.SendMessage(message)
+		err = service.travelUtils.RefreshLegs(ctx, event.TripID, nil)
+		if err != nil {
+			return err
+		}
+
+		return service.notifyUtils.FormAndSendNotifyMessage(ctx, event.TripID,
+			"trip_events_update", "Из поездки удалено событие", domain.UserIDFromContext(ctx))
+	})
+	if err != nil {
+		return err
 	}
 
+	service.travelUtils.MeasureLegs(ctx, event.TripID)
+
 	return nil
 }
 
 func (service *EventService) CreateEvent(ctx context.Context, event model.Event) (model.Event, error) {
-	//_, err := service.placeStorage.GetPlaceByID(ctx, event.PlaceID)
-	//if err != nil && !errors.Is(err, domain.ErrPlaceNotFound) {
-	//	return fmt.Errorf("fail to get place from storage: %w", err)
-	//}
-	//
-	//_, err = service.tripStorage.GetTripByID(ctx, event.TripID)
-	//if err != nil && !errors.Is(err, domain.ErrTripNotFound) {
-	//	return fmt.Errorf("fail to get trip from storage: %w", err)
-	//}
-	//todo: check that event time is between trip date
+	if !event.EndTime.After(event.StartTime) {
+		return model.Event{}, domain.ErrInvalidEventTime
+	}
+
+	trip, err := service.tripStorage.GetTripByID(ctx, event.TripID)
+	if errors.Is(err, domain.ErrTripNotFound) {
+		return model.Event{}, err
+	}
+	if err != nil {
+		return model.Event{}, fmt.Errorf("fail to get trip from storage: %w", err)
+	}
+	if !trip.Contains(event.StartTime, event.EndTime) {
+		return model.Event{}, domain.ErrEventOutsideTrip
+	}
+
+	if event.PlaceID != "" {
+		place, err := service.placeStorage.GetPlaceByID(ctx, event.PlaceID)
+		if errors.Is(err, domain.ErrPlaceNotFound) {
+			return model.Event{}, err
+		}
+		if err != nil {
+			return model.Event{}, fmt.Errorf("fail to get place from storage: %w", err)
+		}
+		if !place.VisibleIn(event.TripID) {
+			// custom places of other trips are not shared
+			return model.Event{}, domain.ErrPlaceNotFound
+		}
+	}
 
 	event.ID = uuid.New()
+	event.Version = 1
+
+	err = service.transactor.InTx(ctx, func(ctx context.Context) error {
+		err := service.revisionUtils.Record(ctx, event.TripID, model.RevisionEventCreate, domain.UserIDFromContext(ctx),
+			func(ctx context.Context) error {
+				err := service.eventStorage.CreateEvent(ctx, event)
+				if err != nil {
+					return fmt.Errorf("fail to create event in storage: %w", err)
+				}
+
+				return nil
+			})
+		if err != nil {
+			return err
+		}
+
+		err = service.travelUtils.RefreshLegs(ctx, event.TripID, nil)
+		if err != nil {
+			return err
+		}
 
-	err := service.eventStorage.CreateEvent(ctx, event)
+		return service.notifyUtils.FormAndSendVersionNotifyMessage(ctx, event.TripID,
+			"trip_events_update", "В поездке создано новое событие", domain.UserIDFromContext(ctx),
+			utils.NotifyVersion{EventID: &event.ID, Version: event.Version})
+	})
 	if err != nil {
-		return model.Event{}, fmt.Errorf("fail to create event in storage: %w", err)
+		return model.Event{}, err
 	}
 
-	tripFound, _ := service.tripStorage.GetTripByID(ctx, event.TripID)
-	users := tripFound.Users
-	// var cookies []string
-	for _, user := range users {
-		cooks, _ := service.sessionStorage.GetTokensByUserID(ctx, user.ID)
-		// cookies = append(cookies, cooks...)
-		var message model.NotifyMessage
-		message.Payload.Action = "trip_events_update"
-		message.Payload.TripID = event.TripID
-		message.Payload.Author = fmt.Sprintf("%d", user.ID)
-		message.Payload.Message = "В поездке создано новое событие"
-		message.Clients = cooks
-		service.messageProducer.SendMessage(message)
-	}
+	service.travelUtils.MeasureLegs(ctx, event.TripID)
 
 	return event, nil
 }
 
 func (service *EventService) UpdateEvent(ctx context.Context, event model.Event) (model.Event, error) {
-	log.Println("START_UPDATING_EVENT: ")
-	updatedEvent, err := service.eventStorage.UpdateEvent(ctx, event)
-	if errors.Is(err, domain.ErrEventNotFound) {
-		log.Println("START_UPDATING_EVENT: NOT FOUND")
+	err := service.validateEventUpdate(ctx, event)
+	if err != nil {
 		return model.Event{}, err
 	}
+
+	var updatedEvent model.Event
+	err = service.transactor.InTx(ctx, func(ctx context.Context) error {
+		current, err := service.eventStorage.GetEventByID(ctx, event.ID)
+		if errors.Is(err, domain.ErrEventNotFound) {
+			return err
+		}
+		if err != nil {
+			return fmt.Errorf("fail to get event from storage: %w", err)
+		}
+
+		err = service.revisionUtils.Record(ctx, current.TripID, model.RevisionEventUpdate, domain.UserIDFromContext(ctx),
+			func(ctx context.Context) error {
+				_, err := service.eventStorage.UpdateEvent(ctx, event)
+				if errors.Is(err, domain.ErrEventNotFound) || errors.Is(err, domain.ErrVersionConflict) {
+					return err
+				}
+				if err != nil {
+					return fmt.Errorf("fail to update event in storage: %w", err)
+				}
+
+				return nil
+			})
+		if err != nil {
+			return err
+		}
+
+		err = service.travelUtils.RefreshLegs(ctx, current.TripID, nil)
+		if err != nil {
+			return err
+		}
+
+		updatedEvent, err = service.eventStorage.GetEventByID(ctx, event.ID)
+		if errors.Is(err, domain.ErrEventNotFound) {
+			return err
+		}
+		if err != nil {
+			return fmt.Errorf("fail to get event from storage: %w", err)
+		}
+
+		return service.notifyUtils.FormAndSendVersionNotifyMessage(ctx, updatedEvent.TripID,
+			"trip_events_update", "Событие поездки обновлено", domain.UserIDFromContext(ctx),
+			utils.NotifyVersion{EventID: &updatedEvent.ID, Version: updatedEvent.Version})
+	})
 	if err != nil {
-		log.Println("START_UPDATING_EVENT: ERR:", err)
-		return model.Event{}, fmt.Errorf("fail to update event in storage: %w", err)
+		return model.Event{}, err
 	}
 
-	updatedEvent, err = service.eventStorage.GetEventByID(ctx, updatedEvent.ID)
+	service.travelUtils.MeasureLegs(ctx, updatedEvent.TripID)
+
+	return updatedEvent, nil
+}
+
+func (service *EventService) PinEvent(ctx context.Context, eventID uuid.UUID, pinned bool, version int64) (model.Event, error) {
+	event, err := service.eventStorage.GetEventByID(ctx, eventID)
 	if errors.Is(err, domain.ErrEventNotFound) {
-		log.Println("START_UPDATING_EVENT: NOT FOUND 2x")
 		return model.Event{}, err
 	}
 	if err != nil {
-		log.Println("START_UPDATING_EVENT: ERR 2x", err)
 		return model.Event{}, fmt.Errorf("fail to get event from storage: %w", err)
 	}
 
-	tripFound, err := service.tripStorage.GetTripByEventID(ctx, event.ID)
-	log.Println("TRIP_FOUND: ", tripFound, "error:", err, "TRIP_ID: ", tripFound.ID)
-	users := tripFound.Users
-	log.Println("USERS: ", tripFound.Users)
-	// var cookies []string
-	for _, user := range users {
-		cooks, _ := service.sessionStorage.GetTokensByUserID(ctx, user.ID)
-		// cookies = append(cookies, cooks...)
-		var message model.NotifyMessage
-		message.Payload.Action = "trip_events_update"
-		message.Payload.TripID = tripFound.ID
-		message.Payload.Author = fmt.Sprintf("%d", user.ID)
-		message.Payload.Message = "Событие поездки обновлено"
-		message.Clients = cooks
-		service.messageProducer.SendMessage(message)
+	var pinnedEvent model.Event
+	err = service.transactor.InTx(ctx, func(ctx context.Context) error {
+		err := service.revisionUtils.Record(ctx, event.TripID, model.RevisionEventUpdate, domain.UserIDFromContext(ctx),
+			func(ctx context.Context) error {
+				var err error
+				pinnedEvent, err = service.eventStorage.SetEventPinned(ctx, eventID, pinned, version)
+				if errors.Is(err, domain.ErrEventNotFound) || errors.Is(err, domain.ErrVersionConflict) {
+					return err
+				}
+				if err != nil {
+					return fmt.Errorf("fail to pin event in storage: %w", err)
+				}
+
+				return nil
+			})
+		if err != nil {
+			return err
+		}
+
+		message := "Событие поездки закреплено"
+		if !pinned {
+			message = "Событие поездки откреплено"
+		}
+		return service.notifyUtils.FormAndSendVersionNotifyMessage(ctx, event.TripID,
+			"trip_events_update", message, domain.UserIDFromContext(ctx),
+			utils.NotifyVersion{EventID: &pinnedEvent.ID, Version: pinnedEvent.Version})
+	})
+	if err != nil {
+		return model.Event{}, err
 	}
 
-	return updatedEvent, nil
+	return pinnedEvent, nil
 }
 
-func (service *EventService) DeleteEventsByTrip(ctx context.Context, tripID uuid.UUID) error {
-	err := service.eventStorage.DeleteEventsByTrip(ctx, tripID)
+// validateEventUpdate checks the event times after the update is applied:
+// fields left empty keep their stored values.
+func (service *EventService) validateEventUpdate(ctx context.Context, event model.Event) error {
+	if event.StartTime.IsZero() && event.EndTime.IsZero() {
+		return nil
+	}
+
+	current, err := service.eventStorage.GetEventByID(ctx, event.ID)
+	if errors.Is(err, domain.ErrEventNotFound) {
+		return err
+	}
 	if err != nil {
-		return fmt.Errorf("fail to delete events by trip ID: %w", err)
+		return fmt.Errorf("fail to get event from storage: %w", err)
+	}
+
+	if !event.StartTime.IsZero() {
+		current.StartTime = event.StartTime
+	}
+	if !event.EndTime.IsZero() {
+		current.EndTime = event.EndTime
+	}
+	if !current.EndTime.After(current.StartTime) {
+		return domain.ErrInvalidEventTime
 	}
 
-	tripFound, _ := service.tripStorage.GetTripByID(ctx, tripID)
-	users := tripFound.Users
-	// var cookies []string
-	for _, user := range users {
-		cooks, _ := service.sessionStorage.GetTokensByUserID(ctx, user.ID)
-		// cookies = append(cookies, cooks...)
-		var message model.NotifyMessage
-		message.Payload.Action = "trip_events_update"
-		message.Payload.TripID = tripID
-		message.Payload.Author = fmt.Sprintf("%d", user.ID)
-		message.Payload.Message = "Из поездки удалено событие"
-		message.Clients = cooks
-		service.messageProducer.SendMessage(message)
+	trip, err := service.tripStorage.GetTripByEventID(ctx, event.ID)
+	if err != nil {
+		return fmt.Errorf("fail to get trip from storage: %w", err)
+	}
+	if !trip.Contains(current.StartTime, current.EndTime) {
+		return domain.ErrEventOutsideTrip
 	}
 
 	return nil
 }
+
+func (service *EventService) DeleteEventsByTrip(ctx context.Context, tripID uuid.UUID) error {
+	return service.transactor.InTx(ctx, func(ctx context.Context) error {
+		err := service.revisionUtils.Record(ctx, tripID, model.RevisionEventsDelete, domain.UserIDFromContext(ctx),
+			func(ctx context.Context) error {
+				err := service.eventStorage.DeleteEventsByTrip(ctx, tripID, false)
+				if err != nil {
+					return fmt.Errorf("fail to delete events by trip ID: %w", err)
+				}
+
+				return nil
+			})
+		if err != nil {
+			return err
+		}
+
+		return service.notifyUtils.FormAndSendNotifyMessage(ctx, tripID,
+			"trip_events_update", "Из поездки удалено событие", domain.UserIDFromContext(ctx))
+	})
+}
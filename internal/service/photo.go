@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/ShelbyKS/Roamly-backend/internal/domain"
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/clients"
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/model"
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/service"
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/storage"
+)
+
+const defaultPhotoWidth = 200
+
+// photoWidths are the widths photos are fetched and stored at, requested
+// widths are rounded up to them so clients can't fill the store with
+// variants of the same photo. Google doesn't scale beyond 1600.
+var photoWidths = []int{100, 200, 400, 800, 1600}
+
+type PhotoService struct {
+	photoClient  clients.IPlacePhotoClient
+	blobStorage  storage.IBlobStorage
+	fetches      singleflight.Group
+	fetchTimeout time.Duration
+}
+
+func NewPhotoService(
+	photoClient clients.IPlacePhotoClient,
+	blobStorage storage.IBlobStorage,
+	fetchTimeout time.Duration,
+) service.IPhotoService {
+	return &PhotoService{
+		photoClient:  photoClient,
+		blobStorage:  blobStorage,
+		fetchTimeout: fetchTimeout,
+	}
+}
+
+func (s *PhotoService) GetPlacePhoto(ctx context.Context, reference string, width int) (model.PhotoFile, error) {
+	if reference == "" || width < 0 {
+		return model.PhotoFile{}, domain.ErrInvalidPhotoRequest
+	}
+	width = photoWidth(width)
+	key := photoKey(reference, width)
+
+	data, err := s.blobStorage.Get(ctx, key)
+	if err == nil {
+		return newPhoto(data), nil
+	}
+	if !errors.Is(err, domain.ErrBlobNotFound) {
+		// the store is only a cache, the photo can still be fetched
+		log.Printf("failed to get stored photo %s: %v", key, err)
+	}
+
+	// concurrent requests of the same photo wait for a single fetch, which
+	// outlives the request that started it but not fetchTimeout
+	fetched, err, _ := s.fetches.Do(key, func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.fetchTimeout)
+		defer cancel()
+
+		return s.fetchPhoto(ctx, reference, width, key)
+	})
+	if err != nil {
+		return model.PhotoFile{}, err
+	}
+
+	return fetched.(model.PhotoFile), nil
+}
+
+func (s *PhotoService) fetchPhoto(ctx context.Context, reference string, width int, key string) (model.PhotoFile, error) {
+	data, err := s.photoClient.GetPlacePhoto(ctx, reference, width)
+	if err != nil {
+		return model.PhotoFile{}, fmt.Errorf("failed to fetch photo: %w", err)
+	}
+
+	photo := newPhoto(data)
+	err = s.blobStorage.Put(ctx, key, photo.Data, photo.ContentType)
+	if err != nil {
+		log.Printf("failed to store photo %s: %v", key, err)
+	}
+
+	return photo, nil
+}
+
+// photoWidth rounds the width up to the nearest stored one.
+func photoWidth(width int) int {
+	if width == 0 {
+		return defaultPhotoWidth
+	}
+	for _, w := range photoWidths {
+		if width <= w {
+			return w
+		}
+	}
+	return photoWidths[len(photoWidths)-1]
+}
+
+// photoKey hashes the reference, google references are long and not
+// limited to safe characters.
+func photoKey(reference string, width int) string {
+	return fmt.Sprintf("places/%s/%d", hashHex(reference), width)
+}
+
+func newPhoto(data []byte) model.PhotoFile {
+	return model.PhotoFile{
+		Data:        data,
+		ContentType: http.DetectContentType(data),
+		ETag:        `"` + hashHex(string(data))[:32] + `"`,
+	}
+}
+
+func hashHex(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
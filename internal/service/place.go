@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -16,36 +17,46 @@ import (
 	"github.com/ShelbyKS/Roamly-backend/internal/domain/model"
 	"github.com/ShelbyKS/Roamly-backend/internal/domain/service"
 	"github.com/ShelbyKS/Roamly-backend/internal/domain/storage"
+	"github.com/ShelbyKS/Roamly-backend/internal/utils"
 )
 
 type PlaceService struct {
-	placeStorage    storage.IPlaceStorage
-	tripStorage     storage.ITripStorage
-	eventStorage    storage.IEventStorage
-	googleApi       clients.IGoogleApiClient
-	openAIClient    clients.IChatClient
-	sessionStorage  storage.ISessionStorage
-	messageProducer clients.IMessageProdcuer
+	placeStorage  storage.IPlaceStorage
+	tripStorage   storage.ITripStorage
+	eventStorage  storage.IEventStorage
+	googleApi     clients.IGoogleApiClient
+	googleCache   clients.IGoogleApiCache
+	llmClient     clients.IChatClient
+	transactor    storage.ITransactor
+	notifyUtils   utils.NotifyUtils
+	revisionUtils utils.RevisionUtils
+	travelUtils   utils.TravelUtils
 }
 
 func NewPlaceService(
 	placeStorage storage.IPlaceStorage,
 	tripStorage storage.ITripStorage,
 	googleApi clients.IGoogleApiClient,
+	googleCache clients.IGoogleApiCache,
 	eventStorage storage.IEventStorage,
-	openAIClient clients.IChatClient,
-	sessionStorage storage.ISessionStorage,
-	messageProducer clients.IMessageProdcuer,
+	llmClient clients.IChatClient,
+	transactor storage.ITransactor,
+	notifyUtils utils.NotifyUtils,
+	revisionUtils utils.RevisionUtils,
+	travelUtils utils.TravelUtils,
 ) service.IPlaceService {
 
 	return &PlaceService{
-		placeStorage:    placeStorage,
-		tripStorage:     tripStorage,
-		googleApi:       googleApi,
-		eventStorage:    eventStorage,
-		openAIClient:    openAIClient,
-		sessionStorage:  sessionStorage,
-		messageProducer: messageProducer,
+		placeStorage:  placeStorage,
+		tripStorage:   tripStorage,
+		googleApi:     googleApi,
+		googleCache:   googleCache,
+		eventStorage:  eventStorage,
+		llmClient:     llmClient,
+		transactor:    transactor,
+		notifyUtils:   notifyUtils,
+		revisionUtils: revisionUtils,
+		travelUtils:   travelUtils,
 	}
 }
 
@@ -61,11 +72,12 @@ func (service *PlaceService) FindPlace(ctx context.Context, searchString string)
 		return []model.Place{}, fmt.Errorf("fail to find place: %w", err)
 	}
 
+	// found places have no ids until they are added to a trip
 	placesDomain := make([]model.Place, len(places))
 	for i, place := range places {
 		placesDomain[i] = model.Place{
-			ID:          place.PlaceID,
-			GooglePlace: place,
+			ExternalRefs: []model.ExternalRef{{Provider: service.googleApi.Provider(), ExternalID: place.PlaceID}},
+			GooglePlace:  place,
 		}
 	}
 
@@ -78,36 +90,44 @@ func (service *PlaceService) DeletePlace(ctx context.Context, tripID uuid.UUID,
 		return model.Trip{}, fmt.Errorf("trip not found: %w", err)
 	}
 
-	err = service.placeStorage.DeletePlace(ctx, tripID, placeID)
-	if err != nil {
-		return model.Trip{}, fmt.Errorf("can't delete place: %w", err)
-	}
+	err = service.transactor.InTx(ctx, func(ctx context.Context) error {
+		err := service.revisionUtils.Record(ctx, tripID, model.RevisionPlaceDelete, domain.UserIDFromContext(ctx),
+			func(ctx context.Context) error {
+				err := service.placeStorage.DeletePlace(ctx, tripID, placeID)
+				if err != nil {
+					return fmt.Errorf("can't delete place: %w", err)
+				}
+
+				err = service.eventStorage.DeleteEventsByPlace(ctx, tripID, placeID)
+				if err != nil {
+					return fmt.Errorf("can't delete related events: %w", err)
+				}
+
+				return nil
+			})
+		if err != nil {
+			return err
+		}
 
-	err = service.eventStorage.DeleteEventsByPlace(ctx, tripID, placeID)
+		err = service.travelUtils.RefreshLegs(ctx, tripID, nil)
+		if err != nil {
+			return err
+		}
+
+		return service.notifyUtils.FormAndSendNotifyMessage(ctx, tripID,
+			"trip_places_update", "Из поездки удалено место", domain.UserIDFromContext(ctx))
+	})
 	if err != nil {
-		return model.Trip{}, fmt.Errorf("can't delete related events: %w", err)
+		return model.Trip{}, err
 	}
 
+	service.travelUtils.MeasureLegs(ctx, tripID)
+
 	trip, err = service.tripStorage.GetTripByID(ctx, tripID)
 	if err != nil {
 		return model.Trip{}, fmt.Errorf("trip after deleting not found: %w", err)
 	}
 
-	// trip, _ := service.tripStorage.GetTripByID(ctx, trip.ID)
-	users := trip.Users
-	// var cookies []string
-	for _, user := range users {
-		cooks, _ := service.sessionStorage.GetTokensByUserID(ctx, user.ID)
-		// cookies = append(cookies, cooks...)
-		var message model.NotifyMessage
-		message.Payload.Action = "trip_places_update"
-		message.Payload.TripID = trip.ID
-		message.Payload.Author = fmt.Sprintf("%d", user.ID)
-		message.Payload.Message = "Из поездки удалено место"
-		message.Clients = cooks
-		service.messageProducer.SendMessage(message)
-	}
-
 	return trip, nil
 }
 
@@ -117,15 +137,36 @@ func (service *PlaceService) AddPlaceToTrip(ctx context.Context, tripID uuid.UUI
 		return model.Trip{}, fmt.Errorf("fail to get trip from storage: %w", err)
 	}
 
-	place, err := service.placeStorage.GetPlaceByID(ctx, placeID)
+	place, err := getStoredPlace(ctx, service.placeStorage, service.googleApi, placeID)
 	if err != nil && !errors.Is(err, domain.ErrPlaceNotFound) {
 		return model.Trip{}, fmt.Errorf("can't get place by id %w", err)
 	}
 
+	if err == nil && !place.VisibleIn(trip.ID) {
+		// custom places of other trips are not shared
+		return model.Trip{}, domain.ErrPlaceNotFound
+	}
+
 	if !errors.Is(err, domain.ErrPlaceNotFound) {
-		err := service.placeStorage.AppendPlaceToTrip(ctx, place.ID, trip.ID)
+		err := service.transactor.InTx(ctx, func(ctx context.Context) error {
+			err := service.revisionUtils.Record(ctx, trip.ID, model.RevisionPlaceAdd, domain.UserIDFromContext(ctx),
+				func(ctx context.Context) error {
+					err := service.placeStorage.AppendPlaceToTrip(ctx, place.ID, trip.ID)
+					if err != nil {
+						return fmt.Errorf("can't append place to trip: %w", err)
+					}
+
+					return nil
+				})
+			if err != nil {
+				return err
+			}
+
+			return service.notifyUtils.FormAndSendNotifyMessage(ctx, trip.ID,
+				"trip_places_update", "В поездку добавлено новое место", domain.UserIDFromContext(ctx))
+		})
 		if err != nil {
-			return model.Trip{}, fmt.Errorf("can't append place to trip: %w", err)
+			return model.Trip{}, err
 		}
 
 		trip.Places = append(trip.Places, &place)
@@ -138,43 +179,150 @@ func (service *PlaceService) AddPlaceToTrip(ctx context.Context, tripID uuid.UUI
 		"rating",
 		"geometry",
 		"photo",
+		"opening_hours",
 	})
 	if err != nil {
 		return model.Trip{}, fmt.Errorf("can't get place from api: %w", err)
 	}
-	place = model.Place{
-		ID:          placeID,
-		GooglePlace: googlePlace,
-		// Opening:     time.Now(),
-		// Closing:     time.Now(),
-	}
+	googlePlace.PlaceID = placeID
+	place = model.NewPlace(service.googleApi.Provider(), googlePlace)
+	place.OpeningHours = googlePlace.WeeklyOpeningHours()
 
 	place.Trips = []*model.Trip{&trip}
 
-	newPlace, err := service.placeStorage.CreatePlace(ctx, &place)
+	var newPlace model.Place
+	err = service.transactor.InTx(ctx, func(ctx context.Context) error {
+		err := service.revisionUtils.Record(ctx, trip.ID, model.RevisionPlaceAdd, domain.UserIDFromContext(ctx),
+			func(ctx context.Context) error {
+				var err error
+				newPlace, err = service.placeStorage.CreatePlace(ctx, &place)
+				if err != nil {
+					return fmt.Errorf("fail to add new place: %w", err)
+				}
+
+				return nil
+			})
+		if err != nil {
+			return err
+		}
+
+		return service.notifyUtils.FormAndSendNotifyMessage(ctx, trip.ID,
+			"trip_places_update", "В поездку добавлено новое место", domain.UserIDFromContext(ctx))
+	})
 	if err != nil {
-		return model.Trip{}, fmt.Errorf("fail to add new place: %w", err)
+		return model.Trip{}, err
 	}
 
 	trip.Places = append(trip.Places, &newPlace)
 
-	// tripFound, _ := service.tripStorage.GetTripByID(ctx, trip.ID)
-	users := trip.Users
-	// var cookies []string
-	for _, user := range users {
-		cooks, _ := service.sessionStorage.GetTokensByUserID(ctx, user.ID)
-		// cookies = append(cookies, cooks...)
-		var message model.NotifyMessage
-		message.Payload.Action = "trip_places_update"
-		message.Payload.TripID = trip.ID
-		message.Payload.Author = fmt.Sprintf("%d", user.ID)
-		message.Payload.Message = "В поездку добавлено новое место"
-		message.Clients = cooks
-		service.messageProducer.SendMessage(message)
+	return trip, nil
+
+}
+
+// CreateCustomPlace adds a place created by users, e.g. their hotel, to the
+// trip. It is unknown to geo providers, so travel to it is estimated by its
+// coordinates, and it is seen only in this trip.
+func (service *PlaceService) CreateCustomPlace(ctx context.Context, tripID uuid.UUID, place model.Place) (model.Trip, error) {
+	if !isValidCustomPlace(place) {
+		return model.Trip{}, domain.ErrInvalidCustomPlace
+	}
+
+	trip, err := service.tripStorage.GetTripByID(ctx, tripID)
+	if err != nil {
+		return model.Trip{}, fmt.Errorf("fail to get trip from storage: %w", err)
+	}
+
+	place.ID = uuid.NewString()
+	place.OwnerTripID = trip.ID
+	// custom places have no known hours and are visited any time
+	place.OpeningHours = []model.OpeningHours{}
+
+	err = service.transactor.InTx(ctx, func(ctx context.Context) error {
+		err := service.revisionUtils.Record(ctx, trip.ID, model.RevisionPlaceAdd, domain.UserIDFromContext(ctx),
+			func(ctx context.Context) error {
+				_, err := service.placeStorage.CreatePlace(ctx, &place)
+				if err != nil {
+					return fmt.Errorf("fail to add custom place: %w", err)
+				}
+
+				err = service.placeStorage.AppendPlaceToTrip(ctx, place.ID, trip.ID)
+				if err != nil {
+					return fmt.Errorf("can't append place to trip: %w", err)
+				}
+
+				return nil
+			})
+		if err != nil {
+			return err
+		}
+
+		return service.notifyUtils.FormAndSendNotifyMessage(ctx, trip.ID,
+			"trip_places_update", "В поездку добавлено новое место", domain.UserIDFromContext(ctx))
+	})
+	if err != nil {
+		return model.Trip{}, err
+	}
+
+	trip, err = service.tripStorage.GetTripByID(ctx, tripID)
+	if err != nil {
+		return model.Trip{}, fmt.Errorf("trip after adding place not found: %w", err)
 	}
 
 	return trip, nil
+}
 
+// UpdateCustomPlace changes a custom place of the trip, travel legs to and
+// from it are measured again if it moved.
+func (service *PlaceService) UpdateCustomPlace(ctx context.Context, tripID uuid.UUID, place model.Place) (model.Trip, error) {
+	if !isValidCustomPlace(place) {
+		return model.Trip{}, domain.ErrInvalidCustomPlace
+	}
+
+	current, err := service.placeStorage.GetPlaceByID(ctx, place.ID)
+	if err != nil {
+		return model.Trip{}, err
+	}
+	if current.OwnerTripID != tripID {
+		// places of geo providers are shared and can't be changed
+		return model.Trip{}, domain.ErrPlaceNotFound
+	}
+	place.OwnerTripID = tripID
+
+	err = service.transactor.InTx(ctx, func(ctx context.Context) error {
+		err := service.revisionUtils.Record(ctx, tripID, model.RevisionPlaceUpdate, domain.UserIDFromContext(ctx),
+			func(ctx context.Context) error {
+				return service.placeStorage.UpdateCustomPlace(ctx, place)
+			})
+		if err != nil {
+			return err
+		}
+
+		return service.notifyUtils.FormAndSendNotifyMessage(ctx, tripID,
+			"trip_places_update", "Место в поездке изменено", domain.UserIDFromContext(ctx))
+	})
+	if err != nil {
+		return model.Trip{}, err
+	}
+
+	if place.GooglePlace.Geometry != current.GooglePlace.Geometry {
+		service.travelUtils.MeasureLegs(ctx, tripID, place.ID)
+	}
+
+	trip, err := service.tripStorage.GetTripByID(ctx, tripID)
+	if err != nil {
+		return model.Trip{}, fmt.Errorf("trip after updating place not found: %w", err)
+	}
+
+	return trip, nil
+}
+
+func isValidCustomPlace(place model.Place) bool {
+	location := place.GooglePlace.Geometry.Location
+
+	return strings.TrimSpace(place.GooglePlace.Name) != "" &&
+		location.Lat >= -90 && location.Lat <= 90 &&
+		location.Lng >= -180 && location.Lng <= 180 &&
+		place.RecommendedVisitingDuration >= 0
 }
 
 func (service *PlaceService) GetPlacesNearby(ctx context.Context,
@@ -228,21 +376,52 @@ func (service *PlaceService) GetTimeMatrix(ctx context.Context, places []*model.
 	return timeMatrix
 }
 
+func (service *PlaceService) InvalidatePlace(ctx context.Context, tripID uuid.UUID, placeID string) error {
+	trip, err := service.tripStorage.GetTripByID(ctx, tripID)
+	if err != nil {
+		return fmt.Errorf("trip not found: %w", err)
+	}
+
+	index := slices.IndexFunc(trip.Places, func(place *model.Place) bool {
+		return place.ID == placeID
+	})
+	if index < 0 {
+		return domain.ErrPlaceNotFound
+	}
+
+	externalID := trip.Places[index].ExternalID(service.googleApi.Provider())
+	if externalID == "" {
+		// the provider doesn't know the place, nothing is cached
+		return nil
+	}
+
+	err = service.googleCache.InvalidatePlace(ctx, externalID)
+	if err != nil {
+		return fmt.Errorf("fail to invalidate cached place: %w", err)
+	}
+
+	return nil
+}
+
 func (service *PlaceService) DetermineRecommendedDuration(ctx context.Context, placeID string) error {
-	place, err := service.placeStorage.GetPlaceByID(ctx, placeID)
+	place, err := getStoredPlace(ctx, service.placeStorage, service.googleApi, placeID)
 	if err != nil {
 		return fmt.Errorf("can't get place by id %w", err)
 	}
+	if place.IsCustom() {
+		// users set the duration of their places
+		return nil
+	}
 
 	//todo: сделать какой-то отдельный файл для промптов
 	var prompt strings.Builder
 	prompt.WriteString(fmt.Sprintf("Определи оптимальное время для посещения %s\n", place.GooglePlace.Name))
 	prompt.WriteString("Напиши только  число - время в минутах")
 
-	recommendedDurationStr, err := service.openAIClient.PostPrompt(ctx, []model.ChatMessage{{
+	recommendedDurationStr, err := service.llmClient.PostPrompt(ctx, []model.ChatMessage{{
 		Role:    model.RoleUser,
 		Content: prompt.String(),
-	}}, clients.ModelChatGPT4oMini)
+	}}, clients.LLMUseDuration)
 	if err != nil {
 		return fmt.Errorf("can't get recommended duration: %w", err)
 	}
@@ -260,3 +439,19 @@ func (service *PlaceService) DetermineRecommendedDuration(ctx context.Context, p
 
 	return nil
 }
+
+// getStoredPlace returns the stored place by its id or by its id at the geo
+// provider, so places found by search can be passed before they are stored.
+func getStoredPlace(
+	ctx context.Context,
+	placeStorage storage.IPlaceStorage,
+	googleApi clients.IGoogleApiClient,
+	id string,
+) (model.Place, error) {
+	place, err := placeStorage.GetPlaceByID(ctx, id)
+	if !errors.Is(err, domain.ErrPlaceNotFound) {
+		return place, err
+	}
+
+	return placeStorage.GetPlaceByExternalID(ctx, googleApi.Provider(), id)
+}
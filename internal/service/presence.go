@@ -0,0 +1,31 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/model"
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/service"
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/storage"
+)
+
+type PresenceService struct {
+	presenceStorage storage.IPresenceStorage
+}
+
+func NewPresenceService(presenceStorage storage.IPresenceStorage) service.IPresenceService {
+	return &PresenceService{
+		presenceStorage: presenceStorage,
+	}
+}
+
+func (s *PresenceService) GetTripPresence(ctx context.Context, tripID uuid.UUID) ([]model.Presence, error) {
+	presences, err := s.presenceStorage.GetByTrip(ctx, tripID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get presence of trip %v: %w", tripID, err)
+	}
+
+	return presences, nil
+}
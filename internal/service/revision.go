@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+
+	"github.com/google/uuid"
+
+	"github.com/ShelbyKS/Roamly-backend/internal/domain"
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/model"
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/service"
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/storage"
+	"github.com/ShelbyKS/Roamly-backend/internal/utils"
+)
+
+type RevisionService struct {
+	revisionStorage storage.IRevisionStorage
+	tripStorage     storage.ITripStorage
+	placeStorage    storage.IPlaceStorage
+	eventStorage    storage.IEventStorage
+	transactor      storage.ITransactor
+	notifyUtils     utils.NotifyUtils
+	revisionUtils   utils.RevisionUtils
+	travelUtils     utils.TravelUtils
+}
+
+func NewRevisionService(
+	revisionStorage storage.IRevisionStorage,
+	tripStorage storage.ITripStorage,
+	placeStorage storage.IPlaceStorage,
+	eventStorage storage.IEventStorage,
+	transactor storage.ITransactor,
+	notifyUtils utils.NotifyUtils,
+	revisionUtils utils.RevisionUtils,
+	travelUtils utils.TravelUtils,
+) service.IRevisionService {
+	return &RevisionService{
+		revisionStorage: revisionStorage,
+		tripStorage:     tripStorage,
+		placeStorage:    placeStorage,
+		eventStorage:    eventStorage,
+		transactor:      transactor,
+		notifyUtils:     notifyUtils,
+		revisionUtils:   revisionUtils,
+		travelUtils:     travelUtils,
+	}
+}
+
+func (s *RevisionService) GetRevisions(ctx context.Context, tripID uuid.UUID, limit, offset int) ([]model.TripRevision, error) {
+	revisions, err := s.revisionStorage.GetByTrip(ctx, tripID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("fail to get revisions from storage: %w", err)
+	}
+
+	return revisions, nil
+}
+
+func (s *RevisionService) RestoreRevision(ctx context.Context, tripID uuid.UUID, revisionID int64) (model.Trip, error) {
+	revision, err := s.revisionStorage.GetByID(ctx, tripID, revisionID)
+	if errors.Is(err, domain.ErrRevisionNotFound) {
+		return model.Trip{}, err
+	}
+	if err != nil {
+		return model.Trip{}, fmt.Errorf("fail to get revision from storage: %w", err)
+	}
+
+	var moved []string
+	err = s.transactor.InTx(ctx, func(ctx context.Context) error {
+		err := s.revisionUtils.Record(ctx, tripID, model.RevisionRestore, domain.UserIDFromContext(ctx),
+			func(ctx context.Context) error {
+				var err error
+				moved, err = s.restore(ctx, tripID, revision.After)
+				return err
+			})
+		if err != nil {
+			return err
+		}
+
+		err = s.travelUtils.RefreshLegs(ctx, tripID, nil)
+		if err != nil {
+			return err
+		}
+
+		return s.notifyUtils.FormAndSendNotifyMessage(ctx, tripID,
+			"trip_restore", "Поездка восстановлена из истории изменений", domain.UserIDFromContext(ctx))
+	})
+	if err != nil {
+		return model.Trip{}, err
+	}
+
+	s.travelUtils.MeasureLegs(ctx, tripID, moved...)
+
+	trip, err := s.tripStorage.GetTripByID(ctx, tripID)
+	if err != nil {
+		return model.Trip{}, fmt.Errorf("fail to get trip from storage: %w", err)
+	}
+
+	return trip, nil
+}
+
+// restore replaces the trip places and events with the snapshot ones and
+// changes custom places back. Restored events keep their ids and get versions
+// newer than any seen before, so stale updates of them are still rejected.
+// It returns the custom places that moved.
+func (s *RevisionService) restore(ctx context.Context, tripID uuid.UUID, snapshot model.TripSnapshot) ([]string, error) {
+	trip, err := s.tripStorage.GetTripByID(ctx, tripID)
+	if err != nil {
+		return nil, fmt.Errorf("fail to get trip from storage: %w", err)
+	}
+
+	current := trip.Snapshot()
+	for _, placeID := range current.PlaceIDs {
+		if slices.Contains(snapshot.PlaceIDs, placeID) {
+			continue
+		}
+		err := s.placeStorage.DeletePlace(ctx, tripID, placeID)
+		if err != nil {
+			return nil, fmt.Errorf("fail to delete place %s: %w", placeID, err)
+		}
+	}
+	for _, placeID := range snapshot.PlaceIDs {
+		if slices.Contains(current.PlaceIDs, placeID) {
+			continue
+		}
+		err := s.placeStorage.AppendPlaceToTrip(ctx, placeID, tripID)
+		if err != nil {
+			return nil, fmt.Errorf("fail to append place %s: %w", placeID, err)
+		}
+	}
+
+	versions := make(map[uuid.UUID]int64, len(current.Events))
+	for _, event := range current.Events {
+		versions[event.ID] = event.Version
+	}
+
+	err = s.eventStorage.DeleteEventsByTrip(ctx, tripID, false)
+	if err != nil {
+		return nil, fmt.Errorf("fail to delete events by trip ID: %w", err)
+	}
+
+	for _, event := range snapshot.Events {
+		event.TripID = tripID
+		event.Version = max(event.Version, versions[event.ID]) + 1
+
+		err := s.eventStorage.CreateEvent(ctx, event)
+		if err != nil {
+			return nil, fmt.Errorf("fail to create event in storage: %w", err)
+		}
+	}
+
+	var moved []string
+	for _, place := range snapshot.CustomPlaces {
+		// places appended back above are not in trip.Places
+		i := slices.IndexFunc(trip.Places, func(current *model.Place) bool { return current.ID == place.ID })
+		if i >= 0 && reflect.DeepEqual(trip.Places[i].CustomFields(), place.CustomFields()) {
+			continue
+		}
+
+		err := s.placeStorage.UpdateCustomPlace(ctx, place)
+		if err != nil {
+			return nil, fmt.Errorf("fail to restore place %s: %w", place.ID, err)
+		}
+		if i >= 0 && trip.Places[i].GooglePlace.Geometry != place.GooglePlace.Geometry {
+			moved = append(moved, place.ID)
+		}
+	}
+
+	return moved, nil
+}
@@ -0,0 +1,212 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ShelbyKS/Roamly-backend/internal/domain"
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/model"
+)
+
+// defaultTravel is the travel time between places missing from the matrix.
+const defaultTravel = 30 * time.Minute
+
+func (s *SchedulerService) PreviewSchedule(
+	ctx context.Context,
+	tripID uuid.UUID,
+	opts model.ScheduleOptions,
+	auto bool,
+) (model.SchedulePreview, error) {
+	trip, err := s.tripStorage.GetTripByID(ctx, tripID)
+	if err != nil {
+		return model.SchedulePreview{}, fmt.Errorf("failed to get trip for schedule: %w", err)
+	}
+
+	// taken before scheduling: changes made while it runs make the preview stale
+	baseRevisionID, err := s.revisionStorage.LatestID(ctx, tripID)
+	if err != nil {
+		return model.SchedulePreview{}, fmt.Errorf("failed to get latest revision: %w", err)
+	}
+
+	places := trip.Places
+	var placeIDs []string
+	if auto {
+		_, places = trip.GetTopRecommendations()
+		placeIDs = recommendedPlaceIDs(trip)
+	}
+
+	// the zone is saved on apply, previews don't change the trip
+	timeZone := s.resolveTimeZone(ctx, &trip)
+
+	events, warnings, matrix, err := s.buildEvents(ctx, &trip, places, opts)
+	if err != nil {
+		return model.SchedulePreview{}, err
+	}
+
+	preview := model.SchedulePreview{
+		ID:             uuid.New(),
+		TripID:         trip.ID,
+		PlaceIDs:       placeIDs,
+		TimeZone:       timeZone,
+		Auto:           auto,
+		Events:         events,
+		Warnings:       warnings,
+		Diff:           diffSchedule(trip.Events, events, matrix, trip.Location()),
+		BaseRevisionID: baseRevisionID,
+		TripVersion:    trip.Version,
+		ExpiresAt:      time.Now().Add(s.previewTTL),
+	}
+
+	err = s.previewStorage.Add(ctx, preview)
+	if err != nil {
+		return model.SchedulePreview{}, fmt.Errorf("failed to save schedule preview: %w", err)
+	}
+
+	return preview, nil
+}
+
+func (s *SchedulerService) ApplySchedulePreview(
+	ctx context.Context,
+	tripID uuid.UUID,
+	previewID uuid.UUID,
+) (model.Trip, []model.ScheduleWarning, error) {
+	preview, err := s.previewStorage.Get(ctx, previewID)
+	if err != nil {
+		return model.Trip{}, nil, err
+	}
+	if preview.TripID != tripID {
+		return model.Trip{}, nil, domain.ErrSchedulePreviewNotFound
+	}
+
+	action := model.RevisionSchedule
+	if preview.Auto {
+		action = model.RevisionAutoSchedule
+	}
+
+	err = s.saveSchedule(ctx, tripID, action, preview.PlaceIDs, preview.TimeZone, &preview.Events, nil, func(ctx context.Context) error {
+		return s.checkPreviewBase(ctx, preview)
+	})
+	if err != nil {
+		return model.Trip{}, nil, err
+	}
+
+	err = s.previewStorage.Delete(ctx, previewID)
+	if err != nil {
+		log.Printf("failed to delete applied schedule preview %s: %v", previewID, err)
+	}
+
+	trip, err := s.tripStorage.GetTripByID(ctx, tripID)
+	if err != nil {
+		return model.Trip{}, nil, fmt.Errorf("failed to get trip after schedule: %w", err)
+	}
+
+	return trip, preview.Warnings, nil
+}
+
+// checkPreviewBase rejects previews of a trip changed since they were made.
+// The trip row is locked, so concurrent applies are checked one by one.
+func (s *SchedulerService) checkPreviewBase(ctx context.Context, preview model.SchedulePreview) error {
+	err := s.tripStorage.LockTrip(ctx, preview.TripID)
+	if err != nil {
+		return err
+	}
+
+	trip, err := s.tripStorage.GetTripByID(ctx, preview.TripID)
+	if err != nil {
+		return fmt.Errorf("failed to get trip for schedule: %w", err)
+	}
+
+	revisionID, err := s.revisionStorage.LatestID(ctx, preview.TripID)
+	if err != nil {
+		return fmt.Errorf("failed to get latest revision: %w", err)
+	}
+
+	if trip.Version != preview.TripVersion || revisionID != preview.BaseRevisionID {
+		return domain.ErrVersionConflict
+	}
+
+	return nil
+}
+
+// diffSchedule matches current and proposed events by their place:
+// events of places only in one of them are removed or added.
+func diffSchedule(current, proposed []model.Event, matrix model.DistanceMatrix, loc *time.Location) model.ScheduleDiff {
+	diff := model.ScheduleDiff{
+		TravelBefore: travelTime(current, matrix, loc),
+		TravelAfter:  travelTime(proposed, matrix, loc),
+	}
+
+	currentByPlace := make(map[string][]model.Event)
+	for _, event := range current {
+		currentByPlace[event.PlaceID] = append(currentByPlace[event.PlaceID], event)
+	}
+
+	matched := make(map[uuid.UUID]bool, len(current))
+	for _, event := range proposed {
+		matches := currentByPlace[event.PlaceID]
+		if event.PlaceID == "" || len(matches) == 0 {
+			diff.Added = append(diff.Added, event)
+			continue
+		}
+
+		before := matches[0]
+		currentByPlace[event.PlaceID] = matches[1:]
+		matched[before.ID] = true
+		if before.StartTime.Equal(event.StartTime) && before.EndTime.Equal(event.EndTime) {
+			diff.Unchanged++
+			continue
+		}
+		diff.Moved = append(diff.Moved, model.EventMove{Before: before, After: event})
+	}
+
+	for _, event := range current {
+		if !matched[event.ID] {
+			diff.Removed = append(diff.Removed, event)
+		}
+	}
+
+	return diff
+}
+
+// travelTime sums travel between consecutive events of the same local day.
+func travelTime(events []model.Event, matrix model.DistanceMatrix, loc *time.Location) time.Duration {
+	sorted := make([]model.Event, 0, len(events))
+	for _, event := range events {
+		if event.PlaceID != "" {
+			sorted = append(sorted, event)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].StartTime.Before(sorted[j].StartTime)
+	})
+
+	var total time.Duration
+	for i := 1; i < len(sorted); i++ {
+		prev, next := sorted[i-1], sorted[i]
+		if prev.StartTime.In(loc).YearDay() != next.StartTime.In(loc).YearDay() ||
+			prev.StartTime.In(loc).Year() != next.StartTime.In(loc).Year() {
+			continue
+		}
+		total += travelBetween(matrix, prev.PlaceID, next.PlaceID)
+	}
+
+	return total
+}
+
+func travelBetween(matrix model.DistanceMatrix, from, to string) time.Duration {
+	if from == to {
+		return 0
+	}
+
+	duration, ok := matrix[from][to]["duration"]
+	if !ok {
+		return defaultTravel
+	}
+
+	return time.Duration(duration * float64(time.Minute))
+}
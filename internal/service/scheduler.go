@@ -3,187 +3,541 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/ShelbyKS/Roamly-backend/internal/domain/storage"
 	"github.com/google/uuid"
 
+	"github.com/ShelbyKS/Roamly-backend/internal/domain"
 	"github.com/ShelbyKS/Roamly-backend/internal/domain/clients"
 	"github.com/ShelbyKS/Roamly-backend/internal/domain/model"
 	"github.com/ShelbyKS/Roamly-backend/internal/domain/service"
+	"github.com/ShelbyKS/Roamly-backend/internal/utils"
+	"github.com/ShelbyKS/Roamly-backend/pkg/solver"
 )
 
 type SchedulerService struct {
-	openAIClient    clients.IChatClient
-	googleApi       clients.IGoogleApiClient
-	tripStorage     storage.ITripStorage
-	eventStorage    storage.IEventStorage
-	placeStorage    storage.IPlaceStorage
-	sessionStorage  storage.ISessionStorage
-	messageProducer clients.IMessageProdcuer
+	llmClient     clients.IChatClient
+	googleApi     clients.IGoogleApiClient
+	tripStorage   storage.ITripStorage
+	eventStorage  storage.IEventStorage
+	placeStorage  storage.IPlaceStorage
+	transactor    storage.ITransactor
+	notifyUtils   utils.NotifyUtils
+	revisionUtils utils.RevisionUtils
+	travelUtils   utils.TravelUtils
+
+	revisionStorage storage.IRevisionStorage
+	previewStorage  storage.ISchedulePreviewStorage
+	// previewTTL is how long a schedule preview can be applied
+	previewTTL time.Duration
 }
 
 func NewShedulerService(
-	openAIClient clients.IChatClient,
+	llmClient clients.IChatClient,
 	googleApi clients.IGoogleApiClient,
 	tripStorage storage.ITripStorage,
 	eventStorage storage.IEventStorage,
 	placeStorage storage.IPlaceStorage,
-	sessionStorage storage.ISessionStorage,
-	messageProducer clients.IMessageProdcuer,
+	transactor storage.ITransactor,
+	notifyUtils utils.NotifyUtils,
+	revisionUtils utils.RevisionUtils,
+	travelUtils utils.TravelUtils,
+	revisionStorage storage.IRevisionStorage,
+	previewStorage storage.ISchedulePreviewStorage,
+	previewTTL time.Duration,
 ) service.ISchedulerService {
 	return &SchedulerService{
-		openAIClient:    openAIClient,
-		googleApi:       googleApi,
-		tripStorage:     tripStorage,
-		eventStorage:    eventStorage,
-		placeStorage:    placeStorage,
-		sessionStorage:  sessionStorage,
-		messageProducer: messageProducer,
+		llmClient:     llmClient,
+		googleApi:     googleApi,
+		tripStorage:   tripStorage,
+		eventStorage:  eventStorage,
+		placeStorage:  placeStorage,
+		transactor:    transactor,
+		notifyUtils:   notifyUtils,
+		revisionUtils: revisionUtils,
+		travelUtils:   travelUtils,
+
+		revisionStorage: revisionStorage,
+		previewStorage:  previewStorage,
+		previewTTL:      previewTTL,
 	}
 }
 
-func (s *SchedulerService) ScheduleTrip(ctx context.Context, tripID uuid.UUID) (model.Trip, error) {
+func (s *SchedulerService) ScheduleTrip(
+	ctx context.Context,
+	tripID uuid.UUID,
+	opts model.ScheduleOptions,
+) (model.Trip, []model.ScheduleWarning, error) {
 	trip, err := s.tripStorage.GetTripByID(ctx, tripID)
 	if err != nil {
-		return model.Trip{}, fmt.Errorf("failed to get trip for schedule: %w", err)
+		return model.Trip{}, nil, fmt.Errorf("failed to get trip for schedule: %w", err)
 	}
 
-	timeDistMatrix, err := s.googleApi.GetTimeDistanceMatrix(ctx, trip.GetTripPlaceIDs())
+	timeZone := s.resolveTimeZone(ctx, &trip)
+
+	events, warnings, matrix, err := s.buildEvents(ctx, &trip, trip.Places, opts)
 	if err != nil {
-		return model.Trip{}, fmt.Errorf("failed to get time distance matrix: %w", err)
+		return model.Trip{}, nil, err
 	}
 
-	prompt := s.generateRequestString(trip, trip.Places, timeDistMatrix)
-
-	//fmt.Println("PROMT: ", prompt)
-
-	resp, err := s.openAIClient.PostPrompt(ctx, []model.ChatMessage{{
-		Role:    model.RoleUser,
-		Content: prompt,
-	}}, clients.ModelChatGPT4o)
+	err = s.saveSchedule(ctx, trip.ID, model.RevisionSchedule, nil, timeZone, &events, matrix, nil)
 	if err != nil {
-		return model.Trip{}, fmt.Errorf("failed to get openai response: %w", err)
+		return model.Trip{}, nil, err
 	}
+	trip.Events = events
+
+	return trip, warnings, nil
+}
 
-	events, err := ParseSchedule(resp)
+func (s *SchedulerService) AutoScheduleTrip(
+	ctx context.Context,
+	tripID uuid.UUID,
+	opts model.ScheduleOptions,
+) (model.Trip, []model.ScheduleWarning, error) {
+	trip, err := s.tripStorage.GetTripByID(ctx, tripID)
 	if err != nil {
-		return model.Trip{}, fmt.Errorf("failed to parse schedule: %w", err)
+		return model.Trip{}, nil, fmt.Errorf("failed to get trip for schedule: %w", err)
 	}
 
-	err = s.eventStorage.DeleteEventsByTrip(ctx, trip.ID)
+	_, places := trip.GetTopRecommendations()
+	timeZone := s.resolveTimeZone(ctx, &trip)
+
+	events, warnings, matrix, err := s.buildEvents(ctx, &trip, places, opts)
 	if err != nil {
-		return model.Trip{}, fmt.Errorf("failed to delete current events: %w", err)
+		return model.Trip{}, nil, err
 	}
 
-	err = s.eventStorage.CreateBatchEvents(ctx, &events)
+	err = s.saveSchedule(ctx, trip.ID, model.RevisionAutoSchedule, recommendedPlaceIDs(trip), timeZone, &events, matrix, nil)
 	if err != nil {
-		return model.Trip{}, fmt.Errorf("failed to save events: %w", err)
+		return model.Trip{}, nil, err
 	}
+	trip.Places = trip.RecommendedPlaces
 	trip.Events = events
 
-	users := trip.Users
-	// var cookies []string
-	for _, user := range users {
-		cooks, _ := s.sessionStorage.GetTokensByUserID(ctx, user.ID)
-		// cookies = append(cookies, cooks...)
-		var message model.NotifyMessage
-		message.Payload.Action = "trip_events_update"
-		message.Payload.TripID = trip.ID
-		message.Payload.Author = fmt.Sprintf("%d", user.ID)
-		message.Payload.Message = "Поездка спланирована"
-		message.Clients = cooks
-		s.messageProducer.SendMessage(message)
+	return trip, warnings, nil
+}
+
+func recommendedPlaceIDs(trip model.Trip) []string {
+	placeIDs := make([]string, len(trip.RecommendedPlaces))
+	for i, place := range trip.RecommendedPlaces {
+		placeIDs[i] = place.ID
 	}
+	return placeIDs
+}
 
-	return trip, nil
+// saveSchedule adds placeIDs to the trip places and replaces the trip events
+// with the scheduled ones as a single revision. timeZone, if set, is saved as
+// the zone the schedule was made in. Travel legs are measured by matrix if it
+// is set, the rest after commit. check, if set, runs in the transaction before
+// anything is changed and may reject the schedule.
+func (s *SchedulerService) saveSchedule(
+	ctx context.Context,
+	tripID uuid.UUID,
+	action string,
+	placeIDs []string,
+	timeZone string,
+	events *[]model.Event,
+	matrix model.DistanceMatrix,
+	check func(ctx context.Context) error,
+) error {
+	err := s.transactor.InTx(ctx, func(ctx context.Context) error {
+		if check != nil {
+			if err := check(ctx); err != nil {
+				return err
+			}
+		}
+
+		if timeZone != "" {
+			err := s.tripStorage.UpdateTrip(ctx, model.Trip{ID: tripID, TimeZone: timeZone})
+			if err != nil {
+				return fmt.Errorf("failed to save time zone: %w", err)
+			}
+		}
+
+		err := s.revisionUtils.Record(ctx, tripID, action, domain.UserIDFromContext(ctx),
+			func(ctx context.Context) error {
+				//todo: batch
+				for _, placeID := range placeIDs {
+					err := s.placeStorage.AppendPlaceToTrip(ctx, placeID, tripID)
+					if err != nil {
+						return fmt.Errorf("failed to append place: %w", err)
+					}
+				}
+
+				return s.replaceEvents(ctx, tripID, events)
+			})
+		if err != nil {
+			return err
+		}
+
+		err = s.travelUtils.RefreshLegs(ctx, tripID, matrix)
+		if err != nil {
+			return err
+		}
+
+		return s.notifyScheduled(ctx, tripID)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.travelUtils.MeasureLegs(ctx, tripID)
+
+	return nil
 }
 
-func (s *SchedulerService) AutoScheduleTrip(ctx context.Context, tripID uuid.UUID) (model.Trip, error) {
-	trip, err := s.tripStorage.GetTripByID(ctx, tripID)
+// replaceEvents swaps the trip schedule for events. Pinned events are
+// kept in the storage as they are, the others get new ids.
+// Must be called within a transaction.
+func (s *SchedulerService) replaceEvents(ctx context.Context, tripID uuid.UUID, events *[]model.Event) error {
+	err := s.eventStorage.DeleteEventsByTrip(ctx, tripID, true)
 	if err != nil {
-		return model.Trip{}, fmt.Errorf("failed to get trip for schedule: %w", err)
+		return fmt.Errorf("failed to delete current events: %w", err)
 	}
 
-	placesIds, places := trip.GetTopRecommendations()
+	var scheduled []model.Event
+	var positions []int
+	for i, event := range *events {
+		if event.Pinned {
+			continue
+		}
+		scheduled = append(scheduled, event)
+		positions = append(positions, i)
+	}
+	if len(scheduled) == 0 {
+		return nil
+	}
 
-	timeDistMatrix, err := s.googleApi.GetTimeDistanceMatrix(ctx, placesIds)
+	err = s.eventStorage.CreateBatchEvents(ctx, &scheduled)
 	if err != nil {
-		return model.Trip{}, fmt.Errorf("failed to get time distance matrix: %w", err)
+		return fmt.Errorf("failed to save events: %w", err)
+	}
+	for i, position := range positions {
+		(*events)[position] = scheduled[i]
 	}
 
-	prompt := s.generateRequestString(trip, places, timeDistMatrix)
+	return nil
+}
 
-	resp, err := s.openAIClient.PostPrompt(ctx, []model.ChatMessage{{
-		Role:    model.RoleUser,
-		Content: prompt,
-	}}, clients.ModelChatGPT4o)
+func (s *SchedulerService) notifyScheduled(ctx context.Context, tripID uuid.UUID) error {
+	return s.notifyUtils.FormAndSendNotifyMessage(ctx, tripID,
+		"trip_events_update", "Поездка спланирована", domain.UserIDFromContext(ctx))
+}
+
+// buildEvents schedules places within the trip around its pinned events,
+// places of pinned events are not scheduled again. The returned events
+// include the pinned ones. The returned matrix also covers the places
+// of the current trip events, so schedules can be compared.
+func (s *SchedulerService) buildEvents(
+	ctx context.Context,
+	trip *model.Trip,
+	places []*model.Place,
+	opts model.ScheduleOptions,
+) ([]model.Event, []model.ScheduleWarning, model.DistanceMatrix, error) {
+	opts = opts.WithDefaults()
+
+	pinned := trip.PinnedEvents()
+	places = slices.DeleteFunc(slices.Clone(places), func(place *model.Place) bool {
+		return slices.ContainsFunc(pinned, func(event model.Event) bool {
+			return event.PlaceID == place.ID
+		})
+	})
+	s.ensureOpeningHours(ctx, places)
+
+	matrixPlaces := slices.Clone(places)
+	for _, place := range trip.Places {
+		scheduled := slices.ContainsFunc(matrixPlaces, func(p *model.Place) bool { return p.ID == place.ID })
+		visited := slices.ContainsFunc(trip.Events, func(event model.Event) bool { return event.PlaceID == place.ID })
+		if !scheduled && visited {
+			matrixPlaces = append(matrixPlaces, place)
+		}
+	}
+
+	timeDistMatrix, err := s.travelUtils.Matrix(ctx, matrixPlaces, trip.GetTravelMode())
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var events []model.Event
+	var warnings []model.ScheduleWarning
+	switch opts.Engine {
+	case model.ScheduleEngineSolver:
+		events, warnings, err = s.solveSchedule(*trip, places, timeDistMatrix, opts)
+	case model.ScheduleEngineLLM:
+		events, warnings, err = s.askScheduleLLM(ctx, *trip, places, timeDistMatrix, opts)
+	default:
+		err = fmt.Errorf("%w: unknown engine %q", domain.ErrInvalidScheduleOptions, opts.Engine)
+	}
 	if err != nil {
-		return model.Trip{}, fmt.Errorf("failed to get openai response: %w", err)
+		return nil, nil, nil, err
+	}
+
+	events = append(events, pinned...)
+	slices.SortStableFunc(events, func(a, b model.Event) int {
+		return a.StartTime.Compare(b.StartTime)
+	})
+
+	return events, warnings, timeDistMatrix, nil
+}
+
+// resolveTimeZone looks up the zone of trips created before it was stored,
+// so they are planned in local time. The zone is set on the trip and returned
+// to be saved with the schedule, it is empty if the trip already has a zone
+// or it is unknown. Failures are not fatal: such trips are planned in UTC.
+func (s *SchedulerService) resolveTimeZone(ctx context.Context, trip *model.Trip) string {
+	if trip.TimeZone != "" || trip.Area == nil {
+		return ""
 	}
 
-	events, err := ParseSchedule(resp)
+	location := trip.Area.GooglePlace.Geometry.Location
+	timeZone, err := s.googleApi.GetTimeZone(ctx, location.Lat, location.Lng)
+	if errors.Is(err, clients.ErrNoTimeZone) {
+		return ""
+	}
 	if err != nil {
-		return model.Trip{}, fmt.Errorf("failed to parse schedule: %w", err)
+		log.Printf("failed to get time zone of trip %s: %v", trip.ID, err)
+		return ""
 	}
 
-	//todo: batch
-	for _, place := range trip.RecommendedPlaces {
-		err = s.placeStorage.AppendPlaceToTrip(ctx, place.ID, trip.ID)
+	trip.TimeZone = timeZone
+	return timeZone
+}
+
+// ensureOpeningHours loads opening hours for places saved before they were fetched.
+// Failures are not fatal: such places are treated as always open.
+func (s *SchedulerService) ensureOpeningHours(ctx context.Context, places []*model.Place) {
+	for _, place := range places {
+		if place.OpeningHours != nil {
+			continue
+		}
+
+		externalID := place.ExternalID(s.googleApi.Provider())
+		if externalID == "" {
+			continue
+		}
+
+		googlePlace, err := s.googleApi.GetPlaceByID(ctx, externalID, []string{"opening_hours"})
 		if err != nil {
-			return model.Trip{}, fmt.Errorf("failed to append place: %w", err)
+			log.Printf("failed to get opening hours of place %s: %v", place.ID, err)
+			continue
+		}
+
+		place.OpeningHours = googlePlace.WeeklyOpeningHours()
+		err = s.placeStorage.UpdatePlace(ctx, *place)
+		if err != nil {
+			log.Printf("failed to save opening hours of place %s: %v", place.ID, err)
 		}
 	}
-	trip.Places = trip.RecommendedPlaces
+}
 
-	err = s.eventStorage.DeleteEventsByTrip(ctx, trip.ID)
+func (s *SchedulerService) solveSchedule(
+	trip model.Trip,
+	places []*model.Place,
+	timeMatrix model.DistanceMatrix,
+	opts model.ScheduleOptions,
+) ([]model.Event, []model.ScheduleWarning, error) {
+	dayStart, err := parseClock(opts.DayStart)
 	if err != nil {
-		return model.Trip{}, fmt.Errorf("failed to delete current events: %w", err)
+		return nil, nil, fmt.Errorf("%w: invalid day start: %v", domain.ErrInvalidScheduleOptions, err)
 	}
+	dayEnd, _ := parseClock(model.DefaultScheduleDayEnd)
+	if dayStart >= dayEnd {
+		return nil, nil, fmt.Errorf("%w: day start must be before %s", domain.ErrInvalidScheduleOptions, model.DefaultScheduleDayEnd)
+	}
+
+	// trip days and opening hours are local to the trip area
+	loc := trip.Location()
+
+	result := solver.Solve(solver.Input{
+		TripID: trip.ID,
+		Start:  trip.StartTime.In(loc),
+		End:    trip.EndTime.In(loc),
+		Places: places,
+		Fixed:  trip.PinnedEvents(),
+		Matrix: timeMatrix,
+	}, solver.Config{
+		DayStart:        dayStart,
+		DayEnd:          dayEnd,
+		MaxPlacesPerDay: opts.MaxPlacesPerDay,
+		Slot:            30 * time.Minute,
+		DefaultDuration: model.DefaultVisitingDuration * time.Minute,
+		DefaultTravel:   30 * time.Minute,
+	})
+
+	return result.Events, result.Warnings, nil
+}
+
+func (s *SchedulerService) askScheduleLLM(
+	ctx context.Context,
+	trip model.Trip,
+	places []*model.Place,
+	timeMatrix model.DistanceMatrix,
+	opts model.ScheduleOptions,
+) ([]model.Event, []model.ScheduleWarning, error) {
+	prompt := s.generateRequestString(trip, places, timeMatrix, opts)
 
-	err = s.eventStorage.CreateBatchEvents(ctx, &events)
+	resp, err := s.llmClient.PostPrompt(ctx, []model.ChatMessage{{
+		Role:    model.RoleUser,
+		Content: prompt,
+	}}, clients.LLMUseSchedule)
 	if err != nil {
-		return model.Trip{}, fmt.Errorf("failed to save events: %w", err)
+		return nil, nil, fmt.Errorf("failed to get llm response: %w", err)
+	}
+
+	events, err := ParseSchedule(resp, trip.Location())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse schedule: %w", err)
+	}
+
+	events, warnings := filterScheduledEvents(trip, places, events)
+
+	return events, warnings, nil
+}
+
+// filterScheduledEvents drops events with place ids that are not part of the
+// request, outside of the trip dates or place opening hours, overlapping pinned
+// events and fixes trip id, so hallucinated values never reach the storage.
+func filterScheduledEvents(
+	trip model.Trip,
+	places []*model.Place,
+	events []model.Event,
+) ([]model.Event, []model.ScheduleWarning) {
+	placesByID := make(map[string]*model.Place, len(places))
+	for _, place := range places {
+		placesByID[place.ID] = place
+	}
+
+	pinned := trip.PinnedEvents()
+
+	var warnings []model.ScheduleWarning
+	scheduled := make(map[string]bool, len(places))
+	filtered := make([]model.Event, 0, len(events))
+	for _, event := range events {
+		place, ok := placesByID[event.PlaceID]
+		if !ok || scheduled[place.ID] {
+			continue
+		}
+
+		if !event.EndTime.After(event.StartTime) || !trip.Contains(event.StartTime, event.EndTime) {
+			continue
+		}
+
+		if overlapsAny(event, pinned) {
+			continue
+		}
+
+		if !place.IsOpenDuring(event.StartTime, event.EndTime, trip.Location()) {
+			scheduled[place.ID] = true
+			warnings = append(warnings, model.ScheduleWarning{
+				PlaceID:   place.ID,
+				PlaceName: place.GooglePlace.Name,
+				Reason:    model.WarningOutsideOpeningHours,
+			})
+			continue
+		}
+
+		event.TripID = trip.ID
+		if event.Name == "" {
+			event.Name = place.GooglePlace.Name
+		}
+		scheduled[place.ID] = true
+		filtered = append(filtered, event)
 	}
-	trip.Events = events
 
-	users := trip.Users
-	for _, user := range users {
-		cooks, _ := s.sessionStorage.GetTokensByUserID(ctx, user.ID)
-		var message model.NotifyMessage
-		message.Payload.Action = "trip_events_update"
-		message.Payload.TripID = trip.ID
-		message.Payload.Author = fmt.Sprintf("%d", user.ID)
-		message.Payload.Message = "Поездка спланирована"
-		message.Clients = cooks
-		s.messageProducer.SendMessage(message)
+	for _, place := range places {
+		if scheduled[place.ID] {
+			continue
+		}
+		warnings = append(warnings, model.ScheduleWarning{
+			PlaceID:   place.ID,
+			PlaceName: place.GooglePlace.Name,
+			Reason:    model.WarningNoFreeTime,
+		})
 	}
 
-	return trip, nil
+	return filtered, warnings
+}
+
+func overlapsAny(event model.Event, events []model.Event) bool {
+	for _, other := range events {
+		if event.StartTime.Before(other.EndTime) && event.EndTime.After(other.StartTime) {
+			return true
+		}
+	}
+	return false
 }
 
-func (s *SchedulerService) generateRequestString(trip model.Trip, places []*model.Place, timeMatrix model.DistanceMatrix) string {
+// parseScheduleTime parses time with or without an offset,
+// times without an offset are taken in loc.
+func parseScheduleTime(value string, loc *time.Location) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02T15:04"} {
+		t, err := time.ParseInLocation(layout, value, loc)
+		if err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unsupported time format %q", value)
+}
+
+func parseClock(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func (s *SchedulerService) generateRequestString(
+	trip model.Trip,
+	places []*model.Place,
+	timeMatrix model.DistanceMatrix,
+	opts model.ScheduleOptions,
+) string {
 	var sb strings.Builder
 
 	sb.WriteString(fmt.Sprintf("TripID: %s\n", trip.ID.String()))
 
-	sb.WriteString("\nДата поездки:\n")
-	sb.WriteString(fmt.Sprintf("С: %s\n", trip.StartTime))
-	sb.WriteString(fmt.Sprintf("По: %s\n", trip.EndTime))
+	loc := trip.Location()
+
+	sb.WriteString("\nДата поездки (местное время):\n")
+	sb.WriteString(fmt.Sprintf("С: %s\n", trip.StartTime.In(loc).Format(time.RFC3339)))
+	sb.WriteString(fmt.Sprintf("По: %s\n", trip.EndTime.In(loc).Format(time.RFC3339)))
+	sb.WriteString(fmt.Sprintf("Часовой пояс: %s\n", loc.String()))
+	sb.WriteString(fmt.Sprintf("Способ передвижения: %s\n", trip.GetTravelMode()))
 
 	sb.WriteString("\nМеста поездки - Название:PlaceID:Время на посещение в минутах\n")
 	for i, place := range places {
 		sb.WriteString(fmt.Sprintf("%d. %s:%s:%d\n", i+1,
 			place.GooglePlace.Name,
-			place.GooglePlace.PlaceID,
+			place.ID,
 			place.RecommendedVisitingDuration,
 		))
 	}
-	//sb.WriteString("Время работы:\n")
-	//for i, place := range places {
-	//	sb.WriteString(fmt.Sprintf("%d. %s - %s\n", i+1, place.Opening, place.Closing))
-	//}
+
+	sb.WriteString("\nВремя работы мест (местное время) - PlaceID:день недели (0 - воскресенье):открытие-закрытие\n")
+	for _, place := range places {
+		if len(place.OpeningHours) == 0 {
+			sb.WriteString(fmt.Sprintf("%s:круглосуточно\n", place.ID))
+			continue
+		}
+		for _, hours := range place.OpeningHours {
+			sb.WriteString(fmt.Sprintf("%s:%d:%s-%s\n", place.ID, hours.Weekday, hours.Opening, hours.Closing))
+		}
+	}
+
+	if pinned := trip.PinnedEvents(); len(pinned) > 0 {
+		sb.WriteString("\nЗакреплённые события (местное время) - Название:PlaceID:начало-конец\n")
+		for _, event := range pinned {
+			sb.WriteString(fmt.Sprintf("%s:%s:%s-%s\n", event.Name, event.PlaceID,
+				event.StartTime.In(loc).Format(time.RFC3339), event.EndTime.In(loc).Format(time.RFC3339)))
+		}
+	}
 
 	sb.WriteString("\nМатрица времени и расстояния между местами:\n")
 	for origin, destinations := range timeMatrix {
@@ -204,22 +558,53 @@ func (s *SchedulerService) generateRequestString(trip model.Trip, places []*mode
 		"    \"EndTime\" string\n" +
 		"}\n\n" +
 		"НУЖНО ВЕРНУТЬ []Event (массив Event)\n" +
+		"StartTime И EndTime УКАЗЫВАЙ В МЕСТНОМ ВРЕМЕНИ С ДАТОЙ И СМЕЩЕНИЕМ ЧАСОВОГО ПОЯСА В ФОРМАТЕ RFC3339, НАПРИМЕР 2024-05-01T10:00:00+03:00. " +
+		"СОБЫТИЯ ДОЛЖНЫ ПОПАДАТЬ В ДАТЫ ПОЕЗДКИ.\n" +
 		//"В ОТВЕТЕ ВЕРНИ ТОЛЬКО СПЛАНИРОВАННОЕ РАСПИСАНИЕ. БЕЗ ЛИШНИХ КОММЕНТАРИЕВ И БЕЗ ФОРМАТИРОВАНИЯ.\n" +
 		"ОКРУГЛЯЙ ВРЕМЯ НАЧАЛА СОБЫТИЯ И КОНЦА ДО ЦЕЛЫХ ЧАСА ИЛИ ПОЛОВИНЫ, ДАВАЯ ЗАПАС НА ПЕРЕМЕЩЕНИЕ МЕЖДУ ОБЪЕКТАМИ.\n" +
-		"НЕ ПЛАНИРУЙ ПОСЕЩЕНИЕ МЕСТ РАНЕЕ 10 УТРА И НЕ СТАВЬ БОЛЬШЕ ТРЁХ МЕСТ В ДЕНЬ\n" +
+		fmt.Sprintf("НЕ ПЛАНИРУЙ ПОСЕЩЕНИЕ МЕСТ РАНЕЕ %s И НЕ СТАВЬ БОЛЬШЕ %d МЕСТ В ДЕНЬ\n", opts.DayStart, opts.MaxPlacesPerDay) +
 		"ТАКЖЕ В РАСПИСАНИИ НУЖНО УЧИТЫВАТЬ ВРЕМЯ НА ПРИЁМЫ ПИЩИ И ПОХОДЫ В ТУАЛЕТ.\n" +
 		"НУЖНО РАСПРЕДЕЛЯТЬ РАВНОМЕРНО ПОСЕЩЕНИЕ МЕСТ ПО ДАТАМ ПОЕЗДКИ. " +
 		"ОДНОМ МЕСТО МОЖНО ПОСЕТИТЬ ТОЛЬКО 1 РАЗ ЗА ПОЕЗДКУ.\n" +
+		"СОБЫТИЕ ДОЛЖНО ЦЕЛИКОМ ПОПАДАТЬ ВО ВРЕМЯ РАБОТЫ МЕСТА. ЕСЛИ МЕСТО НЕ ПОМЕЩАЕТСЯ, НЕ ДОБАВЛЯЙ ЕГО.\n" +
+		"ЗАКРЕПЛЁННЫЕ СОБЫТИЯ УЖЕ В РАСПИСАНИИ: НЕ ВОЗВРАЩАЙ ИХ И НЕ СТАВЬ ДРУГИЕ СОБЫТИЯ НА ИХ ВРЕМЯ, ОСТАВЛЯЯ ВРЕМЯ НА ДОРОГУ.\n" +
 		"БЕЗ ЛИШНИХ КОММЕНТАРИЕВ И БЕЗ ФОРМАТИРОВАНИЯ ПО ТИПУ \\`\\`\\`json\\`\\`\\`.\n")
 	return sb.String()
 }
 
-func ParseSchedule(response string) ([]model.Event, error) {
-	var events []model.Event
+type scheduledEvent struct {
+	PlaceID   string
+	TripID    uuid.UUID
+	StartTime string
+	EndTime   string
+}
+
+// ParseSchedule decodes events from the model response. Times are accepted
+// with or without an offset (then they are local to loc); events with
+// unparsable times are skipped.
+func ParseSchedule(response string, loc *time.Location) ([]model.Event, error) {
+	var scheduled []scheduledEvent
 
-	err := json.Unmarshal([]byte(response), &events)
+	err := json.Unmarshal([]byte(response), &scheduled)
 	if err != nil {
 		return nil, err
 	}
+
+	events := make([]model.Event, 0, len(scheduled))
+	for _, event := range scheduled {
+		start, errStart := parseScheduleTime(event.StartTime, loc)
+		end, errEnd := parseScheduleTime(event.EndTime, loc)
+		if errStart != nil || errEnd != nil {
+			continue
+		}
+
+		events = append(events, model.Event{
+			PlaceID:   event.PlaceID,
+			TripID:    event.TripID,
+			StartTime: start.In(loc),
+			EndTime:   end.In(loc),
+		})
+	}
+
 	return events, nil
 }
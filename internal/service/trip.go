@@ -8,41 +8,46 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/ShelbyKS/Roamly-backend/internal/domain"
 	"github.com/ShelbyKS/Roamly-backend/internal/domain/clients"
 	"github.com/ShelbyKS/Roamly-backend/internal/domain/model"
 	"github.com/ShelbyKS/Roamly-backend/internal/domain/service"
 	"github.com/ShelbyKS/Roamly-backend/internal/domain/storage"
+	"github.com/ShelbyKS/Roamly-backend/internal/utils"
 )
 
 type TripService struct {
 	tripStorage     storage.ITripStorage
 	placeStorage    storage.IPlaceStorage
 	googleApiClient clients.IGoogleApiClient
-	openAIClient    clients.IChatClient
-	sessionStorage  storage.ISessionStorage
-	messageProducer clients.IMessageProdcuer
+	llmClient       clients.IChatClient
 	aiChatStorage   storage.IAIChatStorage
+	transactor      storage.ITransactor
+	notifyUtils     utils.NotifyUtils
+	travelUtils     utils.TravelUtils
 }
 
 func NewTripService(
 	tripStorage storage.ITripStorage,
 	placeStorage storage.IPlaceStorage,
 	googleApiClient clients.IGoogleApiClient,
-	openAIClient clients.IChatClient,
-	sessionStorage storage.ISessionStorage,
-	messageProducer clients.IMessageProdcuer,
+	llmClient clients.IChatClient,
 	aiChatStorage storage.IAIChatStorage,
+	transactor storage.ITransactor,
+	notifyUtils utils.NotifyUtils,
+	travelUtils utils.TravelUtils,
 ) service.ITripService {
 	return &TripService{
 		tripStorage:     tripStorage,
 		placeStorage:    placeStorage,
 		googleApiClient: googleApiClient,
-		openAIClient:    openAIClient,
-		sessionStorage:  sessionStorage,
-		messageProducer: messageProducer,
+		llmClient:       llmClient,
 		aiChatStorage:   aiChatStorage,
+		transactor:      transactor,
+		notifyUtils:     notifyUtils,
+		travelUtils:     travelUtils,
 	}
 }
 
@@ -80,7 +85,20 @@ func (service *TripService) DeleteTrip(ctx context.Context, id uuid.UUID) error
 }
 
 func (service *TripService) CreateTrip(ctx context.Context, trip model.Trip) (uuid.UUID, error) {
-	area, err := service.placeStorage.GetPlaceByID(ctx, trip.AreaID)
+	if trip.EndTime.Before(trip.StartTime) {
+		return uuid.Nil, domain.ErrInvalidTripDates
+	}
+	if trip.TravelMode == "" {
+		trip.TravelMode = model.DefaultTravelMode
+	}
+	if !model.IsTravelMode(trip.TravelMode) {
+		return uuid.Nil, domain.ErrInvalidTravelMode
+	}
+	if !isTimeZone(trip.TimeZone) {
+		return uuid.Nil, domain.ErrInvalidTimeZone
+	}
+
+	area, err := getStoredPlace(ctx, service.placeStorage, service.googleApiClient, trip.AreaID)
 	if err != nil && !errors.Is(err, domain.ErrPlaceNotFound) {
 		return uuid.Nil, fmt.Errorf("fail to get area from storage: %w", err)
 	}
@@ -94,16 +112,19 @@ func (service *TripService) CreateTrip(ctx context.Context, trip model.Trip) (uu
 			"photo",
 		})
 
-		area, err = service.placeStorage.CreatePlace(ctx, &model.Place{
-			ID:          trip.AreaID,
-			GooglePlace: areaGoogle,
-		})
+		areaGoogle.PlaceID = trip.AreaID
+		newArea := model.NewPlace(service.googleApiClient.Provider(), areaGoogle)
+		area, err = service.placeStorage.CreatePlace(ctx, &newArea)
 		if err != nil {
 			return uuid.Nil, fmt.Errorf("fail to create area from storage: %w", err)
 		}
 	}
 
+	trip.AreaID = area.ID
 	trip.Area = &area
+	if trip.TimeZone == "" {
+		trip.TimeZone = service.resolveTimeZone(ctx, area)
+	}
 	trip.ID = uuid.New()
 
 	err = service.tripStorage.CreateTrip(ctx, trip, model.Owner)
@@ -116,7 +137,7 @@ func (service *TripService) CreateTrip(ctx context.Context, trip model.Trip) (uu
 		Role:    model.RoleSystem,
 		Content: "Ты помощник для планирования путешествия",
 	}
-	_, err = service.openAIClient.PostPrompt(ctx, []model.ChatMessage{aiChatMsg}, clients.ModelChatGPT4o)
+	_, err = service.llmClient.PostPrompt(ctx, []model.ChatMessage{aiChatMsg}, clients.LLMUseChat)
 
 	err = service.aiChatStorage.SaveAIChatMessage(ctx, aiChatMsg)
 	if err != nil {
@@ -134,7 +155,7 @@ func (service *TripService) DetermineRecommendedPlaces(ctx context.Context, trip
 
 	recommendedPlacesNames, err := service.getRecommendedPlacesNames(ctx, trip.Area.GooglePlace.Name)
 	if err != nil {
-		return fmt.Errorf("fail to get recommended places names from openai: %w", err)
+		return fmt.Errorf("fail to get recommended places names from llm: %w", err)
 	}
 
 	recommendedPlacesDomain, err := service.GetRecommendedPlacesDomain(ctx, recommendedPlacesNames, trip.Area.GooglePlace.Name)
@@ -142,26 +163,17 @@ func (service *TripService) DetermineRecommendedPlaces(ctx context.Context, trip
 		return fmt.Errorf("fail to get recommended places domains from google: %w", err)
 	}
 
-	trip.RecommendedPlaces = recommendedPlacesDomain
-
-	err = service.tripStorage.UpdateTrip(ctx, trip)
-	if err != nil {
-		return fmt.Errorf("fail to update trip from storage: %w", err)
-	}
-
-	users := trip.Users
-	for _, user := range users {
-		cooks, _ := service.sessionStorage.GetTokensByUserID(ctx, user.ID)
-		var message model.NotifyMessage
-		message.Payload.Action = "trip_auto_planning_enable"
-		message.Payload.TripID = trip.ID
-		message.Payload.Author = fmt.Sprintf("%d", user.ID)
-		message.Payload.Message = "Вам доступно экспресс планирование!"
-		message.Clients = cooks
-		service.messageProducer.SendMessage(message)
-	}
+	// the llm and google calls are slow, the trip may be edited meanwhile:
+	// only recommendations are written, without the version of the trip read above
+	return service.transactor.InTx(ctx, func(ctx context.Context) error {
+		err := service.tripStorage.AddRecommendedPlaces(ctx, trip.ID, recommendedPlacesDomain)
+		if err != nil {
+			return fmt.Errorf("fail to save recommended places: %w", err)
+		}
 
-	return nil
+		return service.notifyUtils.FormAndSendNotifyMessage(ctx, trip.ID,
+			"trip_auto_planning_enable", "Вам доступно экспресс планирование!", domain.UserIDFromContext(ctx))
+	})
 }
 
 func (service *TripService) getRecommendedPlacesNames(ctx context.Context, area string) ([]string, error) {
@@ -170,10 +182,10 @@ func (service *TripService) getRecommendedPlacesNames(ctx context.Context, area
 	prompt.WriteString(fmt.Sprintf("Какие главные достопримечательности нужно посетить в %s\n", area))
 	prompt.WriteString("Без описания, через запятую, 9 штук")
 
-	recommendedPlacesStr, err := service.openAIClient.PostPrompt(ctx, []model.ChatMessage{{
+	recommendedPlacesStr, err := service.llmClient.PostPrompt(ctx, []model.ChatMessage{{
 		Role:    model.RoleUser,
 		Content: prompt.String(),
-	}}, clients.ModelChatGPT4oMini)
+	}}, clients.LLMUsePlaces)
 
 	if err != nil {
 		return nil, fmt.Errorf("can't get recommended duration: %w", err)
@@ -205,7 +217,7 @@ func (service *TripService) GetRecommendedPlacesDomain(ctx context.Context, reco
 				"place_id",
 			})
 			if err != nil {
-				fmt.Println("fail to find place %s: %w", recommendedPlace, err)
+				fmt.Printf("fail to find place %s: %v\n", recommendedPlace, err)
 				return
 			}
 
@@ -214,30 +226,38 @@ func (service *TripService) GetRecommendedPlacesDomain(ctx context.Context, reco
 			prompt.WriteString(fmt.Sprintf("Определи оптимальное время для посещения %s\n", places[0].Name))
 			prompt.WriteString("Напиши только  число - время в минутах")
 
-			recommendedDurationStr, err := service.openAIClient.PostPrompt(ctx, []model.ChatMessage{{
+			recommendedDurationStr, err := service.llmClient.PostPrompt(ctx, []model.ChatMessage{{
 				Role:    model.RoleUser,
 				Content: prompt.String(),
-			}}, clients.ModelChatGPT4oMini)
+			}}, clients.LLMUseDuration)
 
 			if err != nil {
-				fmt.Println("can't get recommended duration: %w", err)
+				fmt.Printf("can't get recommended duration: %v\n", err)
 				return
 			}
 			recommendedDurationInt, err := strconv.Atoi(recommendedDurationStr)
 			if err != nil {
-				fmt.Println("recommended duration has wrong format: %w", err)
+				fmt.Printf("recommended duration has wrong format: %v\n", err)
 				return
 			}
 
-			placeDomain := model.Place{
-				ID:                          places[0].PlaceID,
-				GooglePlace:                 places[0],
-				RecommendedVisitingDuration: recommendedDurationInt,
+			var openingHours []model.OpeningHours
+			placeDetails, err := service.googleApiClient.GetPlaceByID(ctx, places[0].PlaceID, []string{"opening_hours"})
+			if err == nil {
+				openingHours = placeDetails.WeeklyOpeningHours()
 			}
 
+			placeDomain := model.NewPlace(service.googleApiClient.Provider(), places[0])
+			placeDomain.OpeningHours = openingHours
+			placeDomain.RecommendedVisitingDuration = recommendedDurationInt
+
 			_, err = service.placeStorage.CreatePlace(ctx, &placeDomain)
-			if err != nil && !errors.Is(err, domain.ErrPlaceAlreadyExists) {
-				fmt.Println("fail to create place: %s: %w", recommendedPlace, err)
+			if errors.Is(err, domain.ErrPlaceAlreadyExists) {
+				placeDomain, err = service.placeStorage.GetPlaceByExternalID(ctx,
+					service.googleApiClient.Provider(), places[0].PlaceID)
+			}
+			if err != nil {
+				fmt.Printf("fail to create place: %s: %v\n", recommendedPlace, err)
 				return
 			}
 
@@ -252,30 +272,117 @@ func (service *TripService) GetRecommendedPlacesDomain(ctx context.Context, reco
 	return recommendedPlacesDomain, nil
 }
 
-func (service *TripService) UpdateTrip(ctx context.Context, trip model.Trip) error {
-	err := service.tripStorage.UpdateTrip(ctx, trip)
-	if errors.Is(err, domain.ErrTripNotFound) {
-		return err
+// resolveTimeZone returns the IANA zone of the trip area. Trips of areas
+// with unknown zone keep it empty and are planned in UTC.
+func (service *TripService) resolveTimeZone(ctx context.Context, area model.Place) string {
+	location := area.GooglePlace.Geometry.Location
+
+	timeZone, err := service.googleApiClient.GetTimeZone(ctx, location.Lat, location.Lng)
+	if errors.Is(err, clients.ErrNoTimeZone) {
+		return ""
 	}
 	if err != nil {
-		return fmt.Errorf("fail to update trip from storage: %w", err)
+		fmt.Printf("fail to get time zone of area %s: %v\n", area.ID, err)
+		return ""
 	}
 
-	tripFound, _ := service.tripStorage.GetTripByID(ctx, trip.ID)
-	users := tripFound.Users
-	for _, user := range users {
-		cooks, _ := service.sessionStorage.GetTokensByUserID(ctx, user.ID)
-		// cookies = append(cookies, cooks...)
-		var message model.NotifyMessage
-		message.Payload.Action = "trip_update"
-		message.Payload.TripID = trip.ID
-		message.Payload.Author = fmt.Sprintf("%d", user.ID)
-		message.Payload.Message = "Поездка обновилась"
-		message.Clients = cooks
-		service.messageProducer.SendMessage(message)
+	return timeZone
+}
+
+// isTimeZone checks an IANA zone given by users, empty zones are left to the provider.
+func isTimeZone(name string) bool {
+	if name == "" {
+		return true
 	}
+	_, err := time.LoadLocation(name)
+	return err == nil && name != "Local"
+}
 
-	return nil
+func (service *TripService) UpdateTrip(ctx context.Context, trip model.Trip) (model.Trip, error) {
+	if trip.EndTime.Before(trip.StartTime) {
+		return model.Trip{}, domain.ErrInvalidTripDates
+	}
+	if trip.TravelMode != "" && !model.IsTravelMode(trip.TravelMode) {
+		return model.Trip{}, domain.ErrInvalidTravelMode
+	}
+	if !isTimeZone(trip.TimeZone) {
+		return model.Trip{}, domain.ErrInvalidTimeZone
+	}
+
+	var updatedTrip model.Trip
+	err := service.transactor.InTx(ctx, func(ctx context.Context) error {
+		err := service.tripStorage.UpdateTrip(ctx, trip)
+		if errors.Is(err, domain.ErrTripNotFound) || errors.Is(err, domain.ErrVersionConflict) {
+			return err
+		}
+		if err != nil {
+			return fmt.Errorf("fail to update trip from storage: %w", err)
+		}
+
+		// legs that follow the trip mode are measured again after commit
+		if trip.TravelMode != "" {
+			err = service.travelUtils.RefreshLegs(ctx, trip.ID, nil)
+			if err != nil {
+				return err
+			}
+		}
+
+		updatedTrip, err = service.tripStorage.GetTripByID(ctx, trip.ID)
+		if err != nil {
+			return fmt.Errorf("fail to get trip from storage: %w", err)
+		}
+
+		// shrunk dates must still hold every event of the trip
+		for _, event := range updatedTrip.Events {
+			if !updatedTrip.Contains(event.StartTime, event.EndTime) {
+				return domain.ErrEventOutsideTrip
+			}
+		}
+
+		return service.notifyUtils.FormAndSendVersionNotifyMessage(ctx, trip.ID,
+			"trip_update", "Поездка обновилась", domain.UserIDFromContext(ctx),
+			utils.NotifyVersion{Version: updatedTrip.Version})
+	})
+	if err != nil {
+		return model.Trip{}, err
+	}
+
+	if trip.TravelMode != "" {
+		service.travelUtils.MeasureLegs(ctx, trip.ID)
+
+		updatedTrip, err = service.tripStorage.GetTripByID(ctx, trip.ID)
+		if err != nil {
+			return model.Trip{}, fmt.Errorf("fail to get trip from storage: %w", err)
+		}
+	}
+
+	return updatedTrip, nil
+}
+
+func (service *TripService) SetLegMode(ctx context.Context, tripID uuid.UUID, legID uuid.UUID, mode string) (model.TravelLeg, error) {
+	if mode != "" && !model.IsTravelMode(mode) {
+		return model.TravelLeg{}, domain.ErrInvalidTravelMode
+	}
+
+	leg, err := service.travelUtils.MeasureLeg(ctx, tripID, legID, mode)
+	if err != nil {
+		return model.TravelLeg{}, err
+	}
+
+	err = service.transactor.InTx(ctx, func(ctx context.Context) error {
+		err := service.travelUtils.SaveLeg(ctx, leg)
+		if err != nil {
+			return err
+		}
+
+		return service.notifyUtils.FormAndSendNotifyMessage(ctx, tripID,
+			"trip_legs_update", "Изменён способ передвижения", domain.UserIDFromContext(ctx))
+	})
+	if err != nil {
+		return model.TravelLeg{}, err
+	}
+
+	return leg, nil
 }
 
 func (service *TripService) GetUserRole(ctx context.Context, userID int, tripID uuid.UUID) (model.UserTripRole, error) {
@@ -6,35 +6,52 @@ import (
 
 	"github.com/google/uuid"
 
-	"github.com/ShelbyKS/Roamly-backend/internal/domain/clients"
 	"github.com/ShelbyKS/Roamly-backend/internal/domain/model"
 	"github.com/ShelbyKS/Roamly-backend/internal/domain/storage"
 )
 
 type NotifyUtils struct {
-	tripStorage     storage.ITripStorage
-	sessionStorage  storage.ISessionStorage
-	messageProducer clients.IMessageProdcuer
+	tripStorage   storage.ITripStorage
+	outboxStorage storage.IOutboxStorage
 }
 
 func NewNotifyUtils(
 	tripStorage storage.ITripStorage,
-	sessionStorage storage.ISessionStorage,
-	messageProducer clients.IMessageProdcuer,
+	outboxStorage storage.IOutboxStorage,
 ) NotifyUtils {
 	return NotifyUtils{
-		tripStorage:     tripStorage,
-		sessionStorage:  sessionStorage,
-		messageProducer: messageProducer,
+		tripStorage:   tripStorage,
+		outboxStorage: outboxStorage,
 	}
 }
 
+// NotifyVersion is the new version of the changed trip, or of the event if EventID is set.
+type NotifyVersion struct {
+	EventID *uuid.UUID
+	Version int64
+}
+
+// FormAndSendNotifyMessage saves the notification for the trip members to the outbox.
+// Called within a transaction it is published only if the transaction is committed.
 func (utils *NotifyUtils) FormAndSendNotifyMessage(
 	ctx context.Context,
 	tripID uuid.UUID,
 	action string,
 	message string,
 	authorID int,
+) error {
+	return utils.FormAndSendVersionNotifyMessage(ctx, tripID, action, message, authorID, NotifyVersion{})
+}
+
+// FormAndSendVersionNotifyMessage is FormAndSendNotifyMessage for changes of
+// versioned trips and events: clients compare the version with their copy.
+func (utils *NotifyUtils) FormAndSendVersionNotifyMessage(
+	ctx context.Context,
+	tripID uuid.UUID,
+	action string,
+	message string,
+	authorID int,
+	version NotifyVersion,
 ) error {
 	trip, err := utils.tripStorage.GetTripByID(ctx, tripID)
 	if err != nil {
@@ -43,21 +60,24 @@ func (utils *NotifyUtils) FormAndSendNotifyMessage(
 
 	var notifyMessage model.NotifyMessage
 	for _, user := range trip.Users {
-		sessionTokens, err := utils.sessionStorage.GetTokensByUserID(ctx, user.ID)
-		if err != nil {
-			return fmt.Errorf("failed to get user session tokens: %w", err)
-		}
+		notifyMessage.Members = append(notifyMessage.Members, user.ID)
+	}
 
-		notifyMessage.Clients = append(notifyMessage.Clients, sessionTokens...)
+	seq, err := utils.tripStorage.NextNotifySeq(ctx, tripID)
+	if err != nil {
+		return fmt.Errorf("failed to get notification sequence: %w", err)
 	}
 
 	notifyMessage.Payload.Action = action
+	notifyMessage.Payload.Seq = seq
 	notifyMessage.Payload.TripID = trip.ID
 	notifyMessage.Payload.Author = fmt.Sprintf("%d", authorID)
 	notifyMessage.Payload.Message = message
-	err = utils.messageProducer.SendMessage(notifyMessage)
+	notifyMessage.Payload.EventID = version.EventID
+	notifyMessage.Payload.Version = version.Version
+	err = utils.outboxStorage.Add(ctx, notifyMessage)
 	if err != nil {
-		return fmt.Errorf("failed to send action %s: %w", action, err)
+		return fmt.Errorf("failed to save action %s to outbox: %w", action, err)
 	}
 
 	return nil
@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/model"
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/storage"
+)
+
+type RevisionUtils struct {
+	tripStorage     storage.ITripStorage
+	revisionStorage storage.IRevisionStorage
+}
+
+func NewRevisionUtils(
+	tripStorage storage.ITripStorage,
+	revisionStorage storage.IRevisionStorage,
+) RevisionUtils {
+	return RevisionUtils{
+		tripStorage:     tripStorage,
+		revisionStorage: revisionStorage,
+	}
+}
+
+// Record runs mutate and saves the trip places and events before and after it
+// as a revision. It must be called within a transaction: the revision is saved
+// only together with the change.
+func (utils *RevisionUtils) Record(
+	ctx context.Context,
+	tripID uuid.UUID,
+	action string,
+	authorID int,
+	mutate func(ctx context.Context) error,
+) error {
+	before, err := utils.tripStorage.GetTripByID(ctx, tripID)
+	if err != nil {
+		return fmt.Errorf("failed to get trip before %s: %w", action, err)
+	}
+
+	err = mutate(ctx)
+	if err != nil {
+		return err
+	}
+
+	after, err := utils.tripStorage.GetTripByID(ctx, tripID)
+	if err != nil {
+		return fmt.Errorf("failed to get trip after %s: %w", action, err)
+	}
+
+	_, err = utils.revisionStorage.Add(ctx, model.TripRevision{
+		TripID:   tripID,
+		AuthorID: authorID,
+		Action:   action,
+		Before:   before.Snapshot(),
+		After:    after.Snapshot(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save revision of %s: %w", action, err)
+	}
+
+	return nil
+}
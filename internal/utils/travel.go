@@ -0,0 +1,342 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ShelbyKS/Roamly-backend/internal/domain"
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/clients"
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/model"
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/storage"
+)
+
+type TravelUtils struct {
+	tripStorage      storage.ITripStorage
+	travelLegStorage storage.ITravelLegStorage
+	googleApi        clients.IGoogleApiClient
+	transactor       storage.ITransactor
+	notifyUtils      NotifyUtils
+}
+
+func NewTravelUtils(
+	tripStorage storage.ITripStorage,
+	travelLegStorage storage.ITravelLegStorage,
+	googleApi clients.IGoogleApiClient,
+	transactor storage.ITransactor,
+	notifyUtils NotifyUtils,
+) TravelUtils {
+	return TravelUtils{
+		tripStorage:      tripStorage,
+		travelLegStorage: travelLegStorage,
+		googleApi:        googleApi,
+		transactor:       transactor,
+		notifyUtils:      notifyUtils,
+	}
+}
+
+type legKind struct {
+	mode   string
+	custom bool
+}
+
+// RefreshLegs recomputes travel legs between consecutive events of the trip
+// days after its events were changed. Legs that are still there keep their
+// values and chosen modes. known, if set, is a matrix in the trip travel mode
+// new legs are measured by. The maps api is never called here: legs known
+// doesn't have are left out until MeasureLegs.
+// It must be called within a transaction together with the change.
+func (utils *TravelUtils) RefreshLegs(ctx context.Context, tripID uuid.UUID, known model.DistanceMatrix) error {
+	trip, err := utils.tripStorage.GetTripByID(ctx, tripID)
+	if err != nil {
+		return fmt.Errorf("failed to get trip for travel legs: %w", err)
+	}
+
+	legs, _ := planLegs(trip, known, nil, nil)
+
+	err = utils.travelLegStorage.ReplaceLegs(ctx, trip.ID, legs)
+	if err != nil {
+		return fmt.Errorf("failed to save travel legs: %w", err)
+	}
+
+	return nil
+}
+
+// MeasureLegs asks the maps api for the legs of the trip RefreshLegs left
+// out, and for legs to and from movedPlaceIDs. It must be called
+// after the change is committed, never within a transaction: the measured
+// legs are saved by a short transaction of their own, those whose events or
+// modes changed in the meantime are dropped. Failures are not fatal and only
+// logged, the legs stay missing until the next change.
+func (utils *TravelUtils) MeasureLegs(ctx context.Context, tripID uuid.UUID, movedPlaceIDs ...string) {
+	trip, err := utils.tripStorage.GetTripByID(ctx, tripID)
+	if err != nil {
+		log.Printf("failed to get trip %s for travel legs: %v", tripID, err)
+		return
+	}
+
+	_, missing := planLegs(trip, nil, nil, movedPlaceIDs)
+	if len(missing) == 0 {
+		return
+	}
+
+	measured := make(map[[2]uuid.UUID]model.TravelLeg)
+	for kind, pairs := range missing {
+		legs, err := utils.measure(ctx, trip, pairs, kind.mode, kind.custom)
+		if err != nil {
+			log.Printf("failed to measure travel legs of trip %s: %v", trip.ID, err)
+			continue
+		}
+		for _, leg := range legs {
+			measured[[2]uuid.UUID{leg.FromEventID, leg.ToEventID}] = leg
+		}
+	}
+	if len(measured) == 0 {
+		return
+	}
+
+	err = utils.transactor.InTx(ctx, func(ctx context.Context) error {
+		err := utils.tripStorage.LockTrip(ctx, tripID)
+		if err != nil {
+			return err
+		}
+
+		trip, err := utils.tripStorage.GetTripByID(ctx, tripID)
+		if err != nil {
+			return fmt.Errorf("failed to get trip for travel legs: %w", err)
+		}
+
+		legs, _ := planLegs(trip, nil, measured, nil)
+
+		err = utils.travelLegStorage.ReplaceLegs(ctx, trip.ID, legs)
+		if err != nil {
+			return fmt.Errorf("failed to save travel legs: %w", err)
+		}
+
+		return utils.notifyUtils.FormAndSendNotifyMessage(ctx, trip.ID,
+			"trip_legs_update", "Маршруты между местами обновлены", domain.UserIDFromContext(ctx))
+	})
+	if err != nil {
+		log.Printf("failed to save measured travel legs of trip %s: %v", tripID, err)
+	}
+}
+
+// planLegs matches the trip legs to the current leg pairs. A pair keeps its
+// leg unless the leg mode is stale or the pair has a moved place, otherwise
+// it gets a leg from measured, which must be of the mode the pair wants, or
+// from known, a matrix in the trip travel mode. The rest are returned as
+// missing grouped by the mode they are to be measured by.
+func planLegs(
+	trip model.Trip,
+	known model.DistanceMatrix,
+	measured map[[2]uuid.UUID]model.TravelLeg,
+	movedPlaceIDs []string,
+) ([]model.TravelLeg, map[legKind][][2]model.Event) {
+	current := make(map[[2]uuid.UUID]model.TravelLeg, len(trip.Legs))
+	for _, leg := range trip.Legs {
+		current[[2]uuid.UUID{leg.FromEventID, leg.ToEventID}] = leg
+	}
+
+	var legs []model.TravelLeg
+	missing := make(map[legKind][][2]model.Event)
+	for _, pair := range trip.LegPairs() {
+		key := [2]uuid.UUID{pair[0].ID, pair[1].ID}
+
+		kind := legKind{trip.GetTravelMode(), false}
+		leg, ok := current[key]
+		if ok && leg.CustomMode {
+			kind = legKind{leg.Mode, true}
+		}
+
+		if fresh, found := measured[key]; found && fresh.Mode == kind.mode && fresh.CustomMode == kind.custom {
+			if ok {
+				fresh.ID = leg.ID
+			}
+			legs = append(legs, fresh)
+			continue
+		}
+
+		moved := slices.Contains(movedPlaceIDs, pair[0].PlaceID) || slices.Contains(movedPlaceIDs, pair[1].PlaceID)
+		if ok && leg.Mode == kind.mode && !moved {
+			legs = append(legs, leg)
+			continue
+		}
+
+		if !kind.custom {
+			leg = newLeg(trip.ID, pair, kind.mode, false)
+			if measureFromMatrix(&leg, known, pair) {
+				legs = append(legs, leg)
+				continue
+			}
+		}
+		missing[kind] = append(missing[kind], pair)
+	}
+
+	return legs, missing
+}
+
+// MeasureLeg measures the leg by mode chosen for it, an empty mode returns
+// the leg to the trip travel mode. It calls the maps api and must not be
+// called within a transaction, the leg is saved by SaveLeg.
+func (utils *TravelUtils) MeasureLeg(ctx context.Context, tripID uuid.UUID, legID uuid.UUID, mode string) (model.TravelLeg, error) {
+	trip, err := utils.tripStorage.GetTripByID(ctx, tripID)
+	if err != nil {
+		return model.TravelLeg{}, fmt.Errorf("failed to get trip for travel legs: %w", err)
+	}
+
+	var leg model.TravelLeg
+	for _, current := range trip.Legs {
+		if current.ID == legID {
+			leg = current
+		}
+	}
+
+	for _, pair := range trip.LegPairs() {
+		if pair[0].ID != leg.FromEventID || pair[1].ID != leg.ToEventID {
+			continue
+		}
+
+		customMode := mode != ""
+		if !customMode {
+			mode = trip.GetTravelMode()
+		}
+
+		measured, err := utils.measure(ctx, trip, [][2]model.Event{pair}, mode, customMode)
+		if err != nil {
+			return model.TravelLeg{}, err
+		}
+		if len(measured) == 0 {
+			return model.TravelLeg{}, domain.ErrNoTravelRoute
+		}
+
+		measured[0].ID = leg.ID
+		return measured[0], nil
+	}
+
+	return model.TravelLeg{}, domain.ErrTravelLegNotFound
+}
+
+// SaveLeg saves the leg measured by MeasureLeg if its events are still
+// consecutive, the leg is rejected otherwise.
+// It must be called within a transaction.
+func (utils *TravelUtils) SaveLeg(ctx context.Context, leg model.TravelLeg) error {
+	err := utils.tripStorage.LockTrip(ctx, leg.TripID)
+	if err != nil {
+		return err
+	}
+
+	trip, err := utils.tripStorage.GetTripByID(ctx, leg.TripID)
+	if err != nil {
+		return fmt.Errorf("failed to get trip for travel legs: %w", err)
+	}
+
+	if !slices.ContainsFunc(trip.Legs, func(current model.TravelLeg) bool {
+		return current.ID == leg.ID && current.FromEventID == leg.FromEventID && current.ToEventID == leg.ToEventID
+	}) {
+		return domain.ErrTravelLegNotFound
+	}
+
+	err = utils.travelLegStorage.UpdateLeg(ctx, leg)
+	if err != nil {
+		return fmt.Errorf("failed to save travel leg: %w", err)
+	}
+
+	return nil
+}
+
+// Matrix returns travel between the places by mode keyed by their ids. Pairs
+// without a route and places the geo provider doesn't know are estimated.
+func (utils *TravelUtils) Matrix(ctx context.Context, places []*model.Place, mode string) (model.DistanceMatrix, error) {
+	provider := utils.googleApi.Provider()
+
+	placeIDs := make(map[string]string, len(places))
+	externalIDs := make([]string, 0, len(places))
+	for _, place := range places {
+		externalID := place.ExternalID(provider)
+		if externalID == "" || placeIDs[externalID] != "" {
+			continue
+		}
+		placeIDs[externalID] = place.ID
+		externalIDs = append(externalIDs, externalID)
+	}
+
+	external, err := utils.googleApi.GetTimeDistanceMatrix(ctx, externalIDs, mode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get time distance matrix: %w", err)
+	}
+
+	matrix := make(model.DistanceMatrix, len(external))
+	for origin, destinations := range external {
+		row := make(map[string]map[string]float64, len(destinations))
+		for destination, metrics := range destinations {
+			row[placeIDs[destination]] = metrics
+		}
+		matrix[placeIDs[origin]] = row
+	}
+
+	// pairs without a route are estimated, otherwise they are taken as far away
+	estimated := matrix.FillMissing(model.PlaceLocations(places), mode)
+	if estimated > 0 {
+		log.Printf("estimated travel for %d place pairs", estimated)
+	}
+
+	return matrix, nil
+}
+
+func (utils *TravelUtils) measure(
+	ctx context.Context,
+	trip model.Trip,
+	pairs [][2]model.Event,
+	mode string,
+	customMode bool,
+) ([]model.TravelLeg, error) {
+	var places []*model.Place
+	for _, place := range trip.Places {
+		if slices.ContainsFunc(pairs, func(pair [2]model.Event) bool {
+			return pair[0].PlaceID == place.ID || pair[1].PlaceID == place.ID
+		}) {
+			places = append(places, place)
+		}
+	}
+
+	matrix, err := utils.Matrix(ctx, places, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	legs := make([]model.TravelLeg, 0, len(pairs))
+	for _, pair := range pairs {
+		leg := newLeg(trip.ID, pair, mode, customMode)
+		if measureFromMatrix(&leg, matrix, pair) {
+			legs = append(legs, leg)
+		}
+	}
+
+	return legs, nil
+}
+
+func newLeg(tripID uuid.UUID, pair [2]model.Event, mode string, customMode bool) model.TravelLeg {
+	return model.TravelLeg{
+		ID:          uuid.New(),
+		TripID:      tripID,
+		FromEventID: pair[0].ID,
+		ToEventID:   pair[1].ID,
+		Mode:        mode,
+		CustomMode:  customMode,
+	}
+}
+
+func measureFromMatrix(leg *model.TravelLeg, matrix model.DistanceMatrix, pair [2]model.Event) bool {
+	metrics, ok := matrix[pair[0].PlaceID][pair[1].PlaceID]
+	if !ok {
+		return false
+	}
+
+	leg.Duration = time.Duration(metrics["duration"] * float64(time.Minute))
+	leg.Distance = int64(metrics["distance"] * 1000)
+	return true
+}
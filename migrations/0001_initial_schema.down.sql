@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS ai_chat_messages;
+DROP TABLE IF EXISTS invites;
+DROP TABLE IF EXISTS events;
+DROP TABLE IF EXISTS trip_recommended_place;
+DROP TABLE IF EXISTS trip_place;
+DROP TABLE IF EXISTS trip_users;
+DROP TABLE IF EXISTS trips;
+DROP TABLE IF EXISTS places;
+DROP TABLE IF EXISTS users;
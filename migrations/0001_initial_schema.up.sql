@@ -0,0 +1,171 @@
+-- Schema previously created by gorm AutoMigrate.
+-- Every statement is idempotent so the migration can be applied to databases
+-- that were created by AutoMigrate before migrations were introduced.
+
+CREATE TABLE IF NOT EXISTS users
+(
+    id         bigserial PRIMARY KEY,
+    login      text,
+    email      text,
+    password   text,
+    created_at timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS places
+(
+    id                            text PRIMARY KEY,
+    opening_hours                 text,
+    formatted_address             text,
+    lat                           decimal,
+    lng                           decimal,
+    name                          text,
+    photos                        text,
+    place_id                      text,
+    rating                        decimal,
+    types                         text,
+    recommended_visiting_duration bigint
+);
+
+ALTER TABLE places ADD COLUMN IF NOT EXISTS opening_hours text;
+
+CREATE TABLE IF NOT EXISTS trips
+(
+    id         text PRIMARY KEY,
+    name       text,
+    start_time timestamptz,
+    end_time   timestamptz,
+    time_zone  text,
+    area_id    text,
+    CONSTRAINT fk_trips_area FOREIGN KEY (area_id) REFERENCES places (id)
+);
+
+ALTER TABLE trips ADD COLUMN IF NOT EXISTS time_zone text;
+
+CREATE TABLE IF NOT EXISTS trip_users
+(
+    user_id   bigint,
+    trip_id   text,
+    user_role bigint,
+    PRIMARY KEY (user_id, trip_id),
+    CONSTRAINT fk_trip_users_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT fk_trip_users_trip FOREIGN KEY (trip_id) REFERENCES trips (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS trip_place
+(
+    trip_id  text,
+    place_id text,
+    PRIMARY KEY (trip_id, place_id),
+    CONSTRAINT fk_trip_place_trip FOREIGN KEY (trip_id) REFERENCES trips (id) ON DELETE CASCADE,
+    CONSTRAINT fk_trip_place_place FOREIGN KEY (place_id) REFERENCES places (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS trip_recommended_place
+(
+    trip_id  text,
+    place_id text,
+    PRIMARY KEY (trip_id, place_id),
+    CONSTRAINT fk_trip_recommended_place_trip FOREIGN KEY (trip_id) REFERENCES trips (id) ON DELETE CASCADE,
+    CONSTRAINT fk_trip_recommended_place_place FOREIGN KEY (place_id) REFERENCES places (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS events
+(
+    id         text PRIMARY KEY,
+    name       text,
+    place_id   text DEFAULT NULL,
+    trip_id    text,
+    start_time timestamptz,
+    end_time   timestamptz,
+    CONSTRAINT fk_events_place FOREIGN KEY (place_id) REFERENCES places (id),
+    CONSTRAINT fk_trips_events FOREIGN KEY (trip_id) REFERENCES trips (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_events_trip_id ON events (trip_id);
+
+CREATE TABLE IF NOT EXISTS invites
+(
+    token   text PRIMARY KEY,
+    trip_id text NOT NULL,
+    access  text NOT NULL,
+    enable  boolean,
+    CONSTRAINT chk_invites_access CHECK (access IN ('reader', 'editor')),
+    CONSTRAINT fk_trips_invites FOREIGN KEY (trip_id) REFERENCES trips (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_trip_id ON invites (trip_id);
+CREATE INDEX IF NOT EXISTS idx_trip_id_access ON invites (access);
+
+CREATE TABLE IF NOT EXISTS ai_chat_messages
+(
+    id         bigserial PRIMARY KEY,
+    trip_id    text        NOT NULL,
+    role       text        NOT NULL,
+    content    text        NOT NULL,
+    created_at timestamptz NOT NULL,
+    CONSTRAINT fk_trips_messages FOREIGN KEY (trip_id) REFERENCES trips (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_ai_chat_messages_trip_id ON ai_chat_messages (trip_id);
+
+-- Trip and event times used to be TIME columns. TIME values have no date and
+-- the dates of trips were never stored, so filled rows are anchored to
+-- 1970-01-01 UTC and their trips are marked by dates_unknown until the dates
+-- are set again. Ends not after their starts are moved to the next day.
+ALTER TABLE trips ADD COLUMN IF NOT EXISTS dates_unknown boolean NOT NULL DEFAULT false;
+
+DO
+$$
+    BEGIN
+        IF EXISTS (SELECT 1
+                   FROM information_schema.columns
+                   WHERE table_schema = current_schema()
+                     AND table_name = 'trips'
+                     AND column_name = 'start_time'
+                     AND data_type = 'time without time zone') THEN
+            ALTER TABLE trips
+                ADD COLUMN start_time_ts timestamptz,
+                ADD COLUMN end_time_ts   timestamptz;
+
+            UPDATE trips
+            SET start_time_ts = (DATE '1970-01-01' + start_time) AT TIME ZONE 'UTC',
+                end_time_ts   = (DATE '1970-01-01' + end_time +
+                                 CASE WHEN end_time <= start_time THEN interval '1 day' ELSE interval '0' END)
+                                    AT TIME ZONE 'UTC',
+                dates_unknown = start_time IS NOT NULL OR end_time IS NOT NULL;
+
+            ALTER TABLE trips
+                DROP COLUMN start_time,
+                DROP COLUMN end_time;
+            ALTER TABLE trips RENAME COLUMN start_time_ts TO start_time;
+            ALTER TABLE trips RENAME COLUMN end_time_ts TO end_time;
+        END IF;
+
+        IF EXISTS (SELECT 1
+                   FROM information_schema.columns
+                   WHERE table_schema = current_schema()
+                     AND table_name = 'events'
+                     AND column_name = 'start_time'
+                     AND data_type = 'time without time zone') THEN
+            ALTER TABLE events
+                ADD COLUMN start_time_ts timestamptz,
+                ADD COLUMN end_time_ts   timestamptz;
+
+            UPDATE events
+            SET start_time_ts = (DATE '1970-01-01' + start_time) AT TIME ZONE 'UTC',
+                end_time_ts   = (DATE '1970-01-01' + end_time +
+                                 CASE WHEN end_time <= start_time THEN interval '1 day' ELSE interval '0' END)
+                                    AT TIME ZONE 'UTC';
+
+            UPDATE trips
+            SET dates_unknown = true
+            WHERE id IN (SELECT trip_id FROM events WHERE start_time IS NOT NULL OR end_time IS NOT NULL);
+
+            ALTER TABLE events
+                DROP COLUMN start_time,
+                DROP COLUMN end_time;
+            ALTER TABLE events RENAME COLUMN start_time_ts TO start_time;
+            ALTER TABLE events RENAME COLUMN end_time_ts TO end_time;
+        END IF;
+    END
+$$;
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS outbox_messages
+(
+    id              bigserial PRIMARY KEY,
+    payload         jsonb       NOT NULL,
+    attempts        bigint      NOT NULL DEFAULT 0,
+    last_error      text        NOT NULL DEFAULT '',
+    next_attempt_at timestamptz NOT NULL DEFAULT now(),
+    sent_at         timestamptz,
+    created_at      timestamptz NOT NULL DEFAULT now()
+);
+
+-- the relay only scans messages that are not sent yet
+CREATE INDEX IF NOT EXISTS idx_outbox_messages_pending
+    ON outbox_messages (next_attempt_at, id)
+    WHERE sent_at IS NULL;
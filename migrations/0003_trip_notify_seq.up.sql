@@ -0,0 +1,2 @@
+-- the sequence number of the last notification of the trip
+ALTER TABLE trips ADD COLUMN IF NOT EXISTS notify_seq bigint NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+ALTER TABLE events DROP COLUMN IF EXISTS version;
+ALTER TABLE trips DROP COLUMN IF EXISTS version;
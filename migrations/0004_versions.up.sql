@@ -0,0 +1,3 @@
+-- versions of trips and events for optimistic concurrency, incremented on every update
+ALTER TABLE trips ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
+ALTER TABLE events ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
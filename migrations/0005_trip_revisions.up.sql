@@ -0,0 +1,15 @@
+-- changes of trip places and events with the state before and after them
+CREATE TABLE IF NOT EXISTS trip_revisions
+(
+    id           bigserial PRIMARY KEY,
+    trip_id      text        NOT NULL,
+    author_id    bigint      NOT NULL DEFAULT 0,
+    action       text        NOT NULL,
+    before_state jsonb       NOT NULL,
+    after_state  jsonb       NOT NULL,
+    created_at   timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT fk_trip_revisions_trip FOREIGN KEY (trip_id) REFERENCES trips (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_trip_revisions_trip
+    ON trip_revisions (trip_id, id DESC);
@@ -0,0 +1,2 @@
+-- pinned events are fixed blocks kept by the scheduler, e.g. booked slots
+ALTER TABLE events ADD COLUMN IF NOT EXISTS pinned boolean NOT NULL DEFAULT false;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS travel_legs;
+ALTER TABLE trips DROP COLUMN IF EXISTS travel_mode;
@@ -0,0 +1,22 @@
+-- travel mode between trip events, legs may choose their own
+ALTER TABLE trips ADD COLUMN IF NOT EXISTS travel_mode text NOT NULL DEFAULT 'driving';
+
+-- computed travel between consecutive events of a trip day
+CREATE TABLE IF NOT EXISTS travel_legs
+(
+    id               text    PRIMARY KEY,
+    trip_id          text    NOT NULL,
+    from_event_id    text    NOT NULL,
+    to_event_id      text    NOT NULL,
+    mode             text    NOT NULL,
+    custom_mode      boolean NOT NULL DEFAULT false,
+    duration_seconds bigint  NOT NULL DEFAULT 0,
+    distance_meters  bigint  NOT NULL DEFAULT 0,
+    CONSTRAINT fk_travel_legs_trip FOREIGN KEY (trip_id) REFERENCES trips (id) ON DELETE CASCADE,
+    CONSTRAINT fk_travel_legs_from_event FOREIGN KEY (from_event_id) REFERENCES events (id) ON DELETE CASCADE,
+    CONSTRAINT fk_travel_legs_to_event FOREIGN KEY (to_event_id) REFERENCES events (id) ON DELETE CASCADE,
+    CONSTRAINT uq_travel_legs_events UNIQUE (from_event_id, to_event_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_travel_legs_trip
+    ON travel_legs (trip_id);
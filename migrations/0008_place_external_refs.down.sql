@@ -0,0 +1,49 @@
+ALTER TABLE places ADD COLUMN IF NOT EXISTS place_id text;
+
+-- places of google get their ids back, places of other providers keep their own
+CREATE TEMPORARY TABLE place_id_changes ON COMMIT DROP AS
+SELECT place_id AS old_id, external_id AS new_id
+FROM place_external_refs
+WHERE provider = 'google';
+
+CREATE FUNCTION pg_temp.change_snapshot_place_ids(state jsonb) RETURNS jsonb AS
+$$
+SELECT state
+           || jsonb_build_object('place_ids', COALESCE(
+        (SELECT jsonb_agg(COALESCE(c.new_id, p.id) ORDER BY p.ord)
+         FROM jsonb_array_elements_text(COALESCE(state -> 'place_ids', '[]')) WITH ORDINALITY AS p(id, ord)
+                  LEFT JOIN place_id_changes c ON c.old_id = p.id), '[]'))
+           || jsonb_build_object('events', COALESCE(
+        (SELECT jsonb_agg(CASE
+                              WHEN c.new_id IS NULL THEN e.event
+                              ELSE jsonb_set(e.event, '{place_id}', to_jsonb(c.new_id)) END ORDER BY e.ord)
+         FROM jsonb_array_elements(COALESCE(state -> 'events', '[]')) WITH ORDINALITY AS e(event, ord)
+                  LEFT JOIN place_id_changes c ON c.old_id = e.event ->> 'place_id'), '[]'))
+$$ LANGUAGE sql;
+
+UPDATE trip_revisions
+SET before_state = pg_temp.change_snapshot_place_ids(before_state),
+    after_state  = pg_temp.change_snapshot_place_ids(after_state);
+
+UPDATE places
+SET id       = c.new_id,
+    place_id = c.new_id
+FROM place_id_changes c
+WHERE places.id = c.old_id;
+
+DROP FUNCTION pg_temp.change_snapshot_place_ids(jsonb);
+
+DROP TABLE IF EXISTS place_external_refs;
+
+ALTER TABLE trip_place
+    DROP CONSTRAINT IF EXISTS fk_trip_place_place,
+    ADD CONSTRAINT fk_trip_place_place FOREIGN KEY (place_id) REFERENCES places (id) ON DELETE CASCADE;
+ALTER TABLE trip_recommended_place
+    DROP CONSTRAINT IF EXISTS fk_trip_recommended_place_place,
+    ADD CONSTRAINT fk_trip_recommended_place_place FOREIGN KEY (place_id) REFERENCES places (id) ON DELETE CASCADE;
+ALTER TABLE events
+    DROP CONSTRAINT IF EXISTS fk_events_place,
+    ADD CONSTRAINT fk_events_place FOREIGN KEY (place_id) REFERENCES places (id);
+ALTER TABLE trips
+    DROP CONSTRAINT IF EXISTS fk_trips_area,
+    ADD CONSTRAINT fk_trips_area FOREIGN KEY (area_id) REFERENCES places (id);
@@ -0,0 +1,71 @@
+-- places have their own ids, ids of the geo providers are references to them
+CREATE TABLE IF NOT EXISTS place_external_refs
+(
+    place_id    text NOT NULL,
+    provider    text NOT NULL,
+    external_id text NOT NULL,
+    PRIMARY KEY (provider, external_id),
+    CONSTRAINT fk_place_external_refs_place FOREIGN KEY (place_id) REFERENCES places (id)
+        ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_place_external_refs_place
+    ON place_external_refs (place_id);
+
+-- references to places follow their new ids
+ALTER TABLE trip_place
+    DROP CONSTRAINT IF EXISTS fk_trip_place_place,
+    ADD CONSTRAINT fk_trip_place_place FOREIGN KEY (place_id) REFERENCES places (id)
+        ON DELETE CASCADE ON UPDATE CASCADE;
+ALTER TABLE trip_recommended_place
+    DROP CONSTRAINT IF EXISTS fk_trip_recommended_place_place,
+    ADD CONSTRAINT fk_trip_recommended_place_place FOREIGN KEY (place_id) REFERENCES places (id)
+        ON DELETE CASCADE ON UPDATE CASCADE;
+ALTER TABLE events
+    DROP CONSTRAINT IF EXISTS fk_events_place,
+    ADD CONSTRAINT fk_events_place FOREIGN KEY (place_id) REFERENCES places (id)
+        ON UPDATE CASCADE;
+ALTER TABLE trips
+    DROP CONSTRAINT IF EXISTS fk_trips_area,
+    ADD CONSTRAINT fk_trips_area FOREIGN KEY (area_id) REFERENCES places (id)
+        ON UPDATE CASCADE;
+
+-- existing places are keyed by google place ids
+INSERT INTO place_external_refs (place_id, provider, external_id)
+SELECT id, 'google', id
+FROM places
+ON CONFLICT DO NOTHING;
+
+CREATE TEMPORARY TABLE place_id_changes ON COMMIT DROP AS
+SELECT id AS old_id, gen_random_uuid()::text AS new_id
+FROM places;
+
+-- revisions keep place ids in their json snapshots
+CREATE FUNCTION pg_temp.change_snapshot_place_ids(state jsonb) RETURNS jsonb AS
+$$
+SELECT state
+           || jsonb_build_object('place_ids', COALESCE(
+        (SELECT jsonb_agg(COALESCE(c.new_id, p.id) ORDER BY p.ord)
+         FROM jsonb_array_elements_text(COALESCE(state -> 'place_ids', '[]')) WITH ORDINALITY AS p(id, ord)
+                  LEFT JOIN place_id_changes c ON c.old_id = p.id), '[]'))
+           || jsonb_build_object('events', COALESCE(
+        (SELECT jsonb_agg(CASE
+                              WHEN c.new_id IS NULL THEN e.event
+                              ELSE jsonb_set(e.event, '{place_id}', to_jsonb(c.new_id)) END ORDER BY e.ord)
+         FROM jsonb_array_elements(COALESCE(state -> 'events', '[]')) WITH ORDINALITY AS e(event, ord)
+                  LEFT JOIN place_id_changes c ON c.old_id = e.event ->> 'place_id'), '[]'))
+$$ LANGUAGE sql;
+
+UPDATE trip_revisions
+SET before_state = pg_temp.change_snapshot_place_ids(before_state),
+    after_state  = pg_temp.change_snapshot_place_ids(after_state);
+
+UPDATE places
+SET id = c.new_id
+FROM place_id_changes c
+WHERE places.id = c.old_id;
+
+DROP FUNCTION pg_temp.change_snapshot_place_ids(jsonb);
+
+-- the google id is kept in the references now
+ALTER TABLE places DROP COLUMN IF EXISTS place_id;
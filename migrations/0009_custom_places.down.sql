@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_places_owner_trip;
+ALTER TABLE places DROP CONSTRAINT IF EXISTS fk_places_owner_trip;
+ALTER TABLE places DROP COLUMN IF EXISTS notes;
+ALTER TABLE places DROP COLUMN IF EXISTS owner_trip_id;
@@ -0,0 +1,11 @@
+-- custom places are created by users of a trip and seen only in it
+ALTER TABLE places ADD COLUMN IF NOT EXISTS owner_trip_id text;
+ALTER TABLE places ADD COLUMN IF NOT EXISTS notes text NOT NULL DEFAULT '';
+
+ALTER TABLE places
+    DROP CONSTRAINT IF EXISTS fk_places_owner_trip,
+    ADD CONSTRAINT fk_places_owner_trip FOREIGN KEY (owner_trip_id) REFERENCES trips (id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_places_owner_trip
+    ON places (owner_trip_id)
+    WHERE owner_trip_id IS NOT NULL;
@@ -0,0 +1,5 @@
+-- pending messages are relayed in order per trip, the relay looks up
+-- earlier unsent messages of the same trip
+CREATE INDEX IF NOT EXISTS idx_outbox_messages_pending_trip
+    ON outbox_messages ((payload -> 'payload' ->> 'trip_id'), id)
+    WHERE sent_at IS NULL;
@@ -0,0 +1,10 @@
+// Package migrations contains versioned SQL migrations of the API database.
+//
+// Every migration is a pair of files NNNN_name.up.sql and NNNN_name.down.sql,
+// migrations are applied in the order of their numbers.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
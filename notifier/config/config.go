@@ -1,14 +1,31 @@
 package config
 
 import (
+	"fmt"
+
 	"github.com/joho/godotenv"
 	"github.com/kelseyhightower/envconfig"
 	"log"
+	"time"
 )
 
 type Config struct {
-	ServerPort  string `envconfig:"NOTIFIER_PORT"`
-	KafkaConfig KafkaConfig
+	ServerPort      string        `envconfig:"NOTIFIER_PORT"`
+	ShutdownTimeout time.Duration `envconfig:"SHUTDOWN_TIMEOUT" default:"15s"`
+	// InstanceID tells notifier replicas apart, every replica has its own
+	// consumer group named by it. It must survive restarts, e.g. the index of
+	// a stateful set pod, or each restart leaves an orphaned group behind.
+	InstanceID string `envconfig:"NOTIFIER_INSTANCE_ID" required:"true"`
+
+	// PresenceTTL is how long a presence stays without heartbeats,
+	// the API drops older ones as left by crashed connections.
+	PresenceTTL time.Duration `envconfig:"PRESENCE_TTL" default:"2m"`
+
+	PostgresConfig  PostgresConfig
+	KafkaConfig     KafkaConfig
+	RedisConfig     RedisConfig
+	BrokerConfig    BrokerConfig
+	WebsocketConfig WebsocketConfig
 }
 
 type KafkaConfig struct {
@@ -18,6 +35,47 @@ type KafkaConfig struct {
 	Group string `envconfig:"KAFKA_GROUP"`
 }
 
+type PostgresConfig struct {
+	Host string `envconfig:"POSTGRES_HOST"`
+	Port string `envconfig:"POSTGRES_PORT"`
+	User string `envconfig:"POSTGRES_USER"`
+	Pass string `envconfig:"POSTGRES_PASSWORD"`
+	DB   string `envconfig:"POSTGRES_DB"`
+	SSL  string `envconfig:"POSTGRES_SSL"`
+}
+
+type RedisConfig struct {
+	Host     string `envconfig:"REDIS_HOST"`
+	Port     string `envconfig:"REDIS_PORT"`
+	Password string `envconfig:"REDIS_PASSWORD"`
+}
+
+type BrokerConfig struct {
+	// Type is one of kafka, redis or memory. The memory broker works only
+	// when the API runs in the same process, e.g. in tests.
+	Type string `envconfig:"BROKER" default:"kafka"`
+	// Stream is the redis stream and the memory queue name, kafka uses KAFKA_TOPIC.
+	Stream string `envconfig:"BROKER_STREAM" default:"notifications"`
+	// Group is the redis consumer group, kafka uses KAFKA_GROUP.
+	Group string `envconfig:"BROKER_GROUP" default:"notifier"`
+	// SingleProcess is set by cmd/standalone, which runs the API too.
+	SingleProcess bool `ignored:"true"`
+}
+
+type WebsocketConfig struct {
+	AllowedOrigins []string `envconfig:"WS_ALLOWED_ORIGINS" default:"http://localhost:3000,https://roamly.ru"`
+	// QueueSize is the number of messages buffered for one connection.
+	QueueSize int `envconfig:"WS_QUEUE_SIZE" default:"64"`
+	// SlowClientPolicy is drop or disconnect, applied when the queue is full.
+	SlowClientPolicy string `envconfig:"WS_SLOW_CLIENT_POLICY" default:"disconnect"`
+	// PongTimeout closes connections that did not answer a ping in time,
+	// pings are sent every 9/10 of it.
+	PongTimeout time.Duration `envconfig:"WS_PONG_TIMEOUT" default:"60s"`
+	// HistorySize is the number of the latest messages of a trip kept for replay.
+	HistorySize int64         `envconfig:"WS_HISTORY_SIZE" default:"100"`
+	HistoryTTL  time.Duration `envconfig:"WS_HISTORY_TTL" default:"24h"`
+}
+
 func LoadConfig() *Config {
 	err := godotenv.Load()
 	if err != nil {
@@ -29,5 +87,22 @@ func LoadConfig() *Config {
 		log.Fatalf("Unable to process environment variables: %s", err)
 	}
 
+	policy := config.WebsocketConfig.SlowClientPolicy
+	if policy != "drop" && policy != "disconnect" {
+		log.Fatalf("Unknown slow client policy %q, expected drop or disconnect", policy)
+	}
+
 	return &config
 }
+
+func (cfg *Config) GetPostgresCfg() string {
+	return fmt.Sprintf(
+		"host=%s port=%s user=%s dbname=%s password=%s sslmode=%s",
+		cfg.PostgresConfig.Host,
+		cfg.PostgresConfig.Port,
+		cfg.PostgresConfig.User,
+		cfg.PostgresConfig.DB,
+		cfg.PostgresConfig.Pass,
+		cfg.PostgresConfig.SSL,
+	)
+}
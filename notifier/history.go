@@ -0,0 +1,80 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	goRedis "github.com/redis/go-redis/v9"
+)
+
+// History keeps the latest messages of every trip in a redis sorted set
+// scored by the message sequence number. Replicas receive the same messages,
+// so adding one twice stores it once.
+type History struct {
+	client *goRedis.Client
+	size   int64
+	ttl    time.Duration
+}
+
+func NewHistory(client *goRedis.Client, size int64, ttl time.Duration) *History {
+	return &History{
+		client: client,
+		size:   size,
+		ttl:    ttl,
+	}
+}
+
+func historyKey(tripID uuid.UUID) string {
+	return fmt.Sprintf("notify:history:%s", tripID)
+}
+
+func (h *History) Add(ctx context.Context, message BrokerMessage) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	key := historyKey(message.Payload.TripID)
+
+	_, err = h.client.TxPipelined(ctx, func(pipe goRedis.Pipeliner) error {
+		pipe.ZAdd(ctx, key, goRedis.Z{Score: float64(message.Payload.Seq), Member: data})
+		pipe.ZRemRangeByRank(ctx, key, 0, -h.size-1)
+		pipe.Expire(ctx, key, h.ttl)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save message to history: %w", err)
+	}
+
+	return nil
+}
+
+// Since returns the messages of the trip with seq greater than lastSeen in order.
+// complete is false when some of them are already evicted from the history.
+func (h *History) Since(ctx context.Context, tripID uuid.UUID, lastSeen int64) (messages []BrokerMessage, complete bool, err error) {
+	values, err := h.client.ZRangeByScore(ctx, historyKey(tripID), &goRedis.ZRangeBy{
+		Min: "(" + strconv.FormatInt(lastSeen, 10),
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get history: %w", err)
+	}
+
+	messages = make([]BrokerMessage, 0, len(values))
+	for _, value := range values {
+		var message BrokerMessage
+		if err := json.Unmarshal([]byte(value), &message); err != nil {
+			return nil, false, fmt.Errorf("failed to decode message: %w", err)
+		}
+		messages = append(messages, message)
+	}
+
+	// a gap right after the cursor means the history was trimmed
+	complete = len(messages) == 0 || messages[0].Payload.Seq == lastSeen+1
+
+	return messages, complete, nil
+}
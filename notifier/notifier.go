@@ -1,162 +1,318 @@
 package notifier
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"os/signal"
+	"sync"
+	"syscall"
 
-	"github.com/confluentinc/confluent-kafka-go/kafka"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
+	goRedis "github.com/redis/go-redis/v9"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 
+	"github.com/ShelbyKS/Roamly-backend/internal/database/storage/postgresql"
+	"github.com/ShelbyKS/Roamly-backend/internal/database/storage/redis"
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/storage"
 	"github.com/ShelbyKS/Roamly-backend/notifier/config"
+	"github.com/ShelbyKS/Roamly-backend/pkg/broker"
 )
 
 type Notifier struct {
-	config  *config.Config
-	clients map[string]*Client
-}
+	config         *config.Config
+	registry       *Registry
+	redisDB        *goRedis.Client
+	pgDB           *gorm.DB
+	sessionStorage storage.ISessionStorage
+	// tripStorage checks trip membership of the clients joining a trip
+	tripStorage     storage.ITripStorage
+	presenceStorage storage.IPresenceStorage
+	history         *History
+	upgrader        websocket.Upgrader
 
-type Client struct {
-	SessionToken string
-	MessageChan  chan []byte
+	// closing is closed on shutdown to make stream handlers close their connections
+	closing     chan struct{}
+	connections sync.WaitGroup
+	// draining is set under mu before shutdown waits for connections,
+	// so no handler is counted after the wait has started
+	mu       sync.Mutex
+	draining bool
 }
 
 func New(cfg *config.Config) *Notifier {
-	return &Notifier{
-		config: cfg,
+	app := &Notifier{
+		config:   cfg,
+		registry: NewRegistry(),
+		closing:  make(chan struct{}),
+	}
+	app.upgrader = websocket.Upgrader{
+		CheckOrigin: func(r *http.Request) bool {
+			return app.allowedOrigin(r.Header.Get("Origin"))
+		},
 	}
+
+	return app
 }
 
 func (app *Notifier) Run() {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	app.redisDB = goRedis.NewClient(&goRedis.Options{
+		Addr:     app.config.RedisConfig.Host + ":" + app.config.RedisConfig.Port,
+		Password: app.config.RedisConfig.Password,
+	})
+	if err := app.redisDB.Ping(ctx).Err(); err != nil {
+		log.Fatalf("Failed to connect to redis: %v", err)
+	}
+	app.sessionStorage = redis.NewSessionStorage(app.redisDB)
+	app.presenceStorage = redis.NewPresenceStorage(app.redisDB, app.config.PresenceTTL)
+
+	var err error
+	app.pgDB, err = gorm.Open(postgres.Open(app.config.GetPostgresCfg()), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		log.Fatalf("Failed to connect to postgres: %v", err)
+	}
+	app.tripStorage = postgresql.NewTripStorage(app.pgDB)
+	app.history = NewHistory(app.redisDB, app.config.WebsocketConfig.HistorySize, app.config.WebsocketConfig.HistoryTTL)
+
 	broadcast := make(chan []byte)
-	app.clients = make(map[string]*Client)
 
-	go app.startKafkaConsumer(broadcast)
-	go app.broadcastMessages(broadcast)
+	subscriber, err := app.newSubscriber()
+	if err != nil {
+		log.Fatalf("Failed to init message broker: %v", err)
+	}
+
+	consumerCtx, stopConsumer := context.WithCancel(context.Background())
+	consumerDone := make(chan struct{})
+	go func() {
+		defer close(consumerDone)
+		app.consume(consumerCtx, subscriber, broadcast)
+	}()
+	go app.broadcastMessages(consumerCtx, broadcast)
+	go app.subscribePresence(consumerCtx)
 
 	r := app.newRouter()
 
-	if err := r.Run(":" + app.config.ServerPort); err != nil {
-		log.Fatalf("Failed to start notifier: %v", err)
+	server := &http.Server{
+		Addr:    ":" + app.config.ServerPort,
+		Handler: r,
 	}
-}
 
-func (app *Notifier) startKafkaConsumer(broadcast chan []byte) {
-	consumer, err := kafka.NewConsumer(&kafka.ConfigMap{
-		"bootstrap.servers": fmt.Sprintf("%s:%s", app.config.KafkaConfig.Host, app.config.KafkaConfig.Port),
-		"group.id":          app.config.KafkaConfig.Group,
-		"auto.offset.reset": "earliest",
-	})
-	if err != nil {
-		log.Fatalf("Failed to init kafka consumer: %s\n", err)
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("Failed to start notifier: %v", err)
+		}
+	case <-ctx.Done():
+		log.Println("Shutting down notifier")
 	}
-	defer consumer.Close()
 
-	err = consumer.Subscribe(app.config.KafkaConfig.Topic, nil)
-	if err != nil {
-		log.Fatalf("Failed to subscribe kafka topic: %s\n", err)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), app.config.ShutdownTimeout)
+	defer cancel()
+
+	// stop reading first so that no message is committed without being sent
+	stopConsumer()
+	select {
+	case <-consumerDone:
+	case <-shutdownCtx.Done():
+		log.Println("Broker consumer did not stop in time")
 	}
 
-	for {
-		msg, err := consumer.ReadMessage(-1)
-		if err == nil {
-			broadcast <- msg.Value
-		} else {
-			log.Printf("Failed to read message: %v\n", err)
+	// streams are closed first: server.Shutdown waits for SSE requests,
+	// while hijacked websocket connections are not tracked by it at all
+	app.stopAccepting()
+	close(app.closing)
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Failed to shutdown notifier server: %v", err)
+	}
+
+	if !waitTimeout(shutdownCtx, &app.connections) {
+		log.Println("Client connections were not closed in time")
+	}
+
+	if err := app.redisDB.Close(); err != nil {
+		log.Printf("Failed to close redis client: %v", err)
+	}
+
+	if sqlDB, err := app.pgDB.DB(); err == nil {
+		if err := sqlDB.Close(); err != nil {
+			log.Printf("Failed to close postgres pool: %v", err)
 		}
 	}
+
+	log.Println("Notifier stopped")
 }
 
-func (app *Notifier) newRouter() *gin.Engine {
-	router := gin.New()
-	router.Use(gin.Logger())
-	router.Use(gin.Recovery())
+// acquireConnection counts a stream handler until it returns, so postgres and
+// redis are closed only after it. Returns false once the notifier shuts down.
+func (app *Notifier) acquireConnection() bool {
+	app.mu.Lock()
+	defer app.mu.Unlock()
 
-	router.GET("/notifications", app.websocketHandler)
+	if app.draining {
+		return false
+	}
+	app.connections.Add(1)
+	return true
+}
 
-	return router
+func (app *Notifier) stopAccepting() {
+	app.mu.Lock()
+	defer app.mu.Unlock()
+
+	app.draining = true
 }
 
-var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool {
+func waitTimeout(ctx context.Context, wg *sync.WaitGroup) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
 		return true
-	},
+	case <-ctx.Done():
+		return false
+	}
 }
 
-func (app *Notifier) websocketHandler(c *gin.Context) {
-	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
-	if err != nil {
-		log.Printf("Failed to upgrade connection for websocket: %v", err)
-		return
-	}
-	defer conn.Close()
+func (app *Notifier) newSubscriber() (broker.Subscriber, error) {
+	brokerCfg := app.config.BrokerConfig
 
-	sessionToken, err := c.Cookie("session_token")
-	if err != nil {
-		log.Printf("No session token cookie: %v", err)
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "No session token"})
-		c.Abort()
-		return
+	// every replica reads all messages in its own group: a client
+	// may be connected to any of them
+	cfg := broker.Config{
+		Type:       brokerCfg.Type,
+		Topic:      brokerCfg.Stream,
+		Group:      brokerCfg.Group + "-" + app.config.InstanceID,
+		FromLatest: true,
+
+		SingleProcess: brokerCfg.SingleProcess,
 	}
 
-	client := &Client{
-		SessionToken: sessionToken,
-		MessageChan:  make(chan []byte),
+	switch brokerCfg.Type {
+	case broker.TypeKafka:
+		cfg.Topic = app.config.KafkaConfig.Topic
+		cfg.Group = app.config.KafkaConfig.Group + "-" + app.config.InstanceID
+		cfg.KafkaHost = app.config.KafkaConfig.Host
+		cfg.KafkaPort = app.config.KafkaConfig.Port
+	case broker.TypeRedis:
+		cfg.Redis = app.redisDB
+		cfg.RedisConsumer = app.config.InstanceID
 	}
-	app.clients[sessionToken] = client
 
+	return broker.NewSubscriber(cfg)
+}
+
+func (app *Notifier) consume(ctx context.Context, subscriber broker.Subscriber, broadcast chan []byte) {
 	defer func() {
-		delete(app.clients, sessionToken)
-		close(client.MessageChan)
+		if err := subscriber.Close(); err != nil {
+			log.Printf("Failed to close broker subscriber: %v\n", err)
+		}
 	}()
 
-	for msg := range client.MessageChan {
-		err := conn.WriteMessage(websocket.TextMessage, msg)
-		if err != nil {
-			log.Printf("Failed to send message: %v", err)
-			break
+	err := subscriber.Subscribe(ctx, func(ctx context.Context, value []byte) error {
+		select {
+		case broadcast <- value:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
 		}
-
-		log.Printf("Sent message: %v", string(msg))
+	})
+	if err != nil {
+		log.Fatalf("Failed to consume messages: %v\n", err)
 	}
 }
 
+func (app *Notifier) newRouter() *gin.Engine {
+	router := gin.New()
+	router.Use(gin.Logger())
+	router.Use(gin.Recovery())
+
+	router.GET("/notifications", app.websocketHandler)
+	router.GET("/notifications/sse", app.sseHandler)
+
+	return router
+}
+
 type EventPayload struct {
-	Action  string `json:"action"`
-	Author  string `json:"author"`
-	TripID  string `json:"trip_id"`
-	Message string `json:"message"`
+	Action  string    `json:"action"`
+	Author  string    `json:"author"`
+	TripID  uuid.UUID `json:"trip_id"`
+	Message string    `json:"message"`
+	Seq     int64     `json:"seq"`
+	// EventID and Version are the new version of a changed trip or event.
+	EventID *uuid.UUID `json:"event_id,omitempty"`
+	Version int64      `json:"version,omitempty"`
 }
 
-type KafkaMessage struct {
+type BrokerMessage struct {
 	Payload EventPayload `json:"payload"`
-	Clients []string     `json:"clients"`
+	Members []int        `json:"members"`
 }
 
-func (app *Notifier) broadcastMessages(broadcast chan []byte) {
+func (message BrokerMessage) Delivery() (Delivery, error) {
+	data, err := json.Marshal(message.Payload)
+	if err != nil {
+		return Delivery{}, fmt.Errorf("failed to encode payload: %w", err)
+	}
+
+	return Delivery{
+		TripID: message.Payload.TripID,
+		Seq:    message.Payload.Seq,
+		Data:   data,
+	}, nil
+}
+
+func (app *Notifier) broadcastMessages(ctx context.Context, broadcast chan []byte) {
 	for {
-		message := <-broadcast
+		var message []byte
+		select {
+		case message = <-broadcast:
+		case <-ctx.Done():
+			return
+		}
 
-		var kafkaMessage KafkaMessage
-		if err := json.Unmarshal(message, &kafkaMessage); err != nil {
+		var brokerMessage BrokerMessage
+		if err := json.Unmarshal(message, &brokerMessage); err != nil {
 			log.Printf("Failed to decode message: %v", err)
 			continue
 		}
 
-		payloadBytes, err := json.Marshal(kafkaMessage.Payload)
+		delivery, err := brokerMessage.Delivery()
 		if err != nil {
-			log.Printf("Failed to encode payload: %v", err)
+			log.Printf("Failed to encode message: %v", err)
 			continue
 		}
 
-		for _, actionParticipant := range kafkaMessage.Clients {
-			client, exists := app.clients[actionParticipant]
-			if !exists {
-				continue
-			}
-			client.MessageChan <- payloadBytes
+		// saved before sending: a client registered after the send
+		// finds the message in the history
+		err = app.history.Add(ctx, brokerMessage)
+		if err != nil {
+			log.Printf("Failed to save message to history: %v", err)
+		}
+
+		for _, member := range brokerMessage.Members {
+			app.registry.Send(member, delivery)
 		}
 	}
 }
@@ -0,0 +1,217 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/model"
+)
+
+// presenceChannel is the redis pub/sub channel presence changes are
+// published to, every notifier replica delivers them to its own clients.
+const presenceChannel = "notify:presence"
+
+const (
+	actionPresenceJoin  = "presence_join"
+	actionPresenceLeave = "presence_leave"
+	actionPresenceFocus = "presence_focus"
+	// actionPresenceState lists the members present in the trip,
+	// it is sent to the client that joins it.
+	actionPresenceState = "presence_state"
+)
+
+var (
+	errNotTripMember = errors.New("user is not a member of the trip")
+	errNotJoined     = errors.New("client has not joined the trip")
+	errInvalidFocus  = errors.New("invalid focus")
+)
+
+// PresenceEvent is a presence change sent to the clients joined to the trip.
+// Presence events are not numbered and not replayed: clients that reconnect
+// join the trip again and get its current state.
+type PresenceEvent struct {
+	Action   string          `json:"action"`
+	TripID   uuid.UUID       `json:"trip_id"`
+	Presence *model.Presence `json:"presence,omitempty"`
+	// ConnectionID and Presences are set in presence_state only,
+	// ConnectionID is the own connection of the client among Presences.
+	ConnectionID string           `json:"connection_id,omitempty"`
+	Presences    []model.Presence `json:"presences,omitempty"`
+}
+
+func (event PresenceEvent) Delivery() (Delivery, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return Delivery{}, fmt.Errorf("failed to encode presence event: %w", err)
+	}
+
+	return Delivery{
+		TripID: event.TripID,
+		Data:   data,
+	}, nil
+}
+
+// joinTrip marks the client present in the trip, tells the other members
+// about it and sends the client the members already present.
+func (app *Notifier) joinTrip(ctx context.Context, client *Client, tripID uuid.UUID) error {
+	_, err := app.tripStorage.GetUserRole(ctx, client.UserID, tripID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errNotTripMember, err)
+	}
+
+	client.Join(tripID)
+	presence, _ := client.Presence(tripID)
+
+	err = app.presenceStorage.Set(ctx, presence)
+	if err != nil {
+		return err
+	}
+
+	err = app.publishPresence(ctx, PresenceEvent{Action: actionPresenceJoin, TripID: tripID, Presence: &presence})
+	if err != nil {
+		return err
+	}
+
+	presences, err := app.presenceStorage.GetByTrip(ctx, tripID)
+	if err != nil {
+		return err
+	}
+
+	delivery, err := PresenceEvent{
+		Action:       actionPresenceState,
+		TripID:       tripID,
+		ConnectionID: client.ConnectionID,
+		Presences:    presences,
+	}.Delivery()
+	if err != nil {
+		return err
+	}
+
+	client.enqueue(delivery)
+	return nil
+}
+
+// leaveTrip removes the presence of the client in the trip, leaving a trip
+// the client has not joined does nothing.
+func (app *Notifier) leaveTrip(ctx context.Context, client *Client, tripID uuid.UUID) error {
+	if !client.Leave(tripID) {
+		return nil
+	}
+
+	err := app.presenceStorage.Remove(ctx, tripID, client.ConnectionID)
+	if err != nil {
+		return err
+	}
+
+	presence := model.Presence{
+		ConnectionID: client.ConnectionID,
+		TripID:       tripID,
+		UserID:       client.UserID,
+	}
+	return app.publishPresence(ctx, PresenceEvent{Action: actionPresenceLeave, TripID: tripID, Presence: &presence})
+}
+
+// focusTrip changes the event or place the client has focused in a joined trip.
+func (app *Notifier) focusTrip(ctx context.Context, client *Client, tripID uuid.UUID, focus *model.PresenceFocus) error {
+	if focus != nil && (focus.ID == "" || (focus.Type != model.FocusEvent && focus.Type != model.FocusPlace)) {
+		return errInvalidFocus
+	}
+
+	if !client.Focus(tripID, focus) {
+		return errNotJoined
+	}
+	presence, _ := client.Presence(tripID)
+
+	err := app.presenceStorage.Set(ctx, presence)
+	if err != nil {
+		return err
+	}
+
+	return app.publishPresence(ctx, PresenceEvent{Action: actionPresenceFocus, TripID: tripID, Presence: &presence})
+}
+
+// leaveAllTrips is called when the connection is closed.
+func (app *Notifier) leaveAllTrips(ctx context.Context, client *Client) {
+	for _, presence := range client.Presences() {
+		err := app.leaveTrip(ctx, client, presence.TripID)
+		if err != nil {
+			log.Printf("Failed to leave trip %s: %v", presence.TripID, err)
+		}
+	}
+}
+
+// refreshPresences is the heartbeat of the client presences: those not
+// refreshed within the presence TTL are considered gone. Members removed
+// from the trip meanwhile leave it.
+func (app *Notifier) refreshPresences(ctx context.Context, client *Client) {
+	for _, presence := range client.Presences() {
+		_, err := app.tripStorage.GetUserRole(ctx, client.UserID, presence.TripID)
+		if err != nil {
+			err = app.leaveTrip(ctx, client, presence.TripID)
+			if err != nil {
+				log.Printf("Failed to leave trip %s: %v", presence.TripID, err)
+			}
+			continue
+		}
+
+		err = app.presenceStorage.Set(ctx, presence)
+		if err != nil {
+			log.Printf("Failed to refresh presence: %v", err)
+		}
+	}
+}
+
+func (app *Notifier) publishPresence(ctx context.Context, event PresenceEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode presence event: %w", err)
+	}
+
+	err = app.redisDB.Publish(ctx, presenceChannel, data).Err()
+	if err != nil {
+		return fmt.Errorf("failed to publish presence event: %w", err)
+	}
+
+	return nil
+}
+
+// subscribePresence delivers presence changes published by all replicas
+// to the clients of this one, the client that made the change is skipped.
+func (app *Notifier) subscribePresence(ctx context.Context) {
+	pubsub := app.redisDB.Subscribe(ctx, presenceChannel)
+	defer pubsub.Close()
+
+	messages := pubsub.Channel()
+	for {
+		select {
+		case message, ok := <-messages:
+			if !ok {
+				return
+			}
+
+			var event PresenceEvent
+			if err := json.Unmarshal([]byte(message.Payload), &event); err != nil {
+				log.Printf("Failed to decode presence event: %v", err)
+				continue
+			}
+			if event.Presence == nil {
+				continue
+			}
+
+			delivery, err := event.Delivery()
+			if err != nil {
+				log.Printf("Failed to encode presence event: %v", err)
+				continue
+			}
+
+			app.registry.SendPresence(delivery, event.Presence.ConnectionID)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
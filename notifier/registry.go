@@ -0,0 +1,290 @@
+package notifier
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/model"
+)
+
+const (
+	// PolicyDrop drops messages for a client whose queue is full.
+	PolicyDrop = "drop"
+	// PolicyDisconnect closes the connection of a client whose queue is full,
+	// the client reconnects and refetches the trip.
+	PolicyDisconnect = "disconnect"
+)
+
+// replayQueueSize limits replays requested while the previous ones are served.
+const replayQueueSize = 16
+
+// Delivery is a message of the trip encoded for the client.
+type Delivery struct {
+	TripID uuid.UUID
+	Seq    int64
+	Data   []byte
+}
+
+// Cursor is the last message of the trip the client has seen.
+type Cursor struct {
+	TripID   uuid.UUID
+	LastSeen int64
+}
+
+// Client is one websocket connection. A user may have several of them,
+// e.g. a trip opened in two browser tabs.
+type Client struct {
+	// ConnectionID tells apart presences of the same user in several tabs.
+	ConnectionID string
+	SessionToken string
+	UserID       int
+
+	// all is set until the client subscribes to a trip: it gets messages of
+	// all trips of the user except the unsubscribed ones, of trips otherwise
+	mu           sync.RWMutex
+	all          bool
+	trips        map[uuid.UUID]struct{}
+	unsubscribed map[uuid.UUID]struct{}
+	// joined are the trips the client is present in with its current focus
+	joined map[uuid.UUID]*model.PresenceFocus
+
+	queue  chan Delivery
+	policy string
+
+	// replays are requested by the reader and served by the writer of the connection
+	replays chan Cursor
+	dropped atomic.Int64
+
+	// kicked is closed when the client is disconnected for being slow
+	kicked   chan struct{}
+	kickOnce sync.Once
+}
+
+func NewClient(sessionToken string, userID int, queueSize int, policy string) *Client {
+	return &Client{
+		ConnectionID: uuid.New().String(),
+		SessionToken: sessionToken,
+		UserID:       userID,
+		all:          true,
+		trips:        make(map[uuid.UUID]struct{}),
+		unsubscribed: make(map[uuid.UUID]struct{}),
+		joined:       make(map[uuid.UUID]*model.PresenceFocus),
+		queue:        make(chan Delivery, queueSize),
+		replays:      make(chan Cursor, replayQueueSize),
+		policy:       policy,
+		kicked:       make(chan struct{}),
+	}
+}
+
+// Subscribe limits the messages of the client to the subscribed trips.
+func (c *Client) Subscribe(tripID uuid.UUID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.subscribe(tripID)
+}
+
+func (c *Client) subscribe(tripID uuid.UUID) {
+	c.all = false
+	c.trips[tripID] = struct{}{}
+}
+
+func (c *Client) Unsubscribe(tripID uuid.UUID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.trips, tripID)
+	if c.all {
+		c.unsubscribed[tripID] = struct{}{}
+	}
+}
+
+// Subscribed reports whether the client wants messages of the trip. Clients
+// that have not subscribed to any trip get messages of all trips of the user,
+// those that unsubscribed from every trip get none.
+func (c *Client) Subscribed(tripID uuid.UUID) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.all {
+		_, ok := c.unsubscribed[tripID]
+		return !ok
+	}
+	_, ok := c.trips[tripID]
+	return ok
+}
+
+// Join marks the client present in the trip and subscribes it to the trip.
+func (c *Client) Join(tripID uuid.UUID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.subscribe(tripID)
+	if _, ok := c.joined[tripID]; !ok {
+		c.joined[tripID] = nil
+	}
+}
+
+// Leave removes the presence of the client in the trip,
+// it returns false if the client has not joined it.
+func (c *Client) Leave(tripID uuid.UUID) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, ok := c.joined[tripID]
+	delete(c.joined, tripID)
+	return ok
+}
+
+// Focus changes the focus of the client in a joined trip, nil clears it.
+func (c *Client) Focus(tripID uuid.UUID, focus *model.PresenceFocus) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.joined[tripID]; !ok {
+		return false
+	}
+	c.joined[tripID] = focus
+	return true
+}
+
+func (c *Client) Joined(tripID uuid.UUID) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	_, ok := c.joined[tripID]
+	return ok
+}
+
+// Presence returns the current presence of the client in the trip.
+func (c *Client) Presence(tripID uuid.UUID) (model.Presence, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	focus, ok := c.joined[tripID]
+	if !ok {
+		return model.Presence{}, false
+	}
+	return c.presence(tripID, focus), true
+}
+
+// Presences returns the current presence of the client in every joined trip.
+func (c *Client) Presences() []model.Presence {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	presences := make([]model.Presence, 0, len(c.joined))
+	for tripID, focus := range c.joined {
+		presences = append(presences, c.presence(tripID, focus))
+	}
+	return presences
+}
+
+func (c *Client) presence(tripID uuid.UUID, focus *model.PresenceFocus) model.Presence {
+	return model.Presence{
+		ConnectionID: c.ConnectionID,
+		TripID:       tripID,
+		UserID:       c.UserID,
+		Focus:        focus,
+		SeenAt:       time.Now(),
+	}
+}
+
+// RequestReplay subscribes the client to the trip and asks to resend the
+// messages after the cursor. It returns false if too many replays are pending.
+func (c *Client) RequestReplay(cursor Cursor) bool {
+	c.Subscribe(cursor.TripID)
+
+	select {
+	case c.replays <- cursor:
+		return true
+	default:
+		return false
+	}
+}
+
+// enqueue never blocks: a full queue is handled by the slow client policy.
+func (c *Client) enqueue(delivery Delivery) bool {
+	select {
+	case c.queue <- delivery:
+		return true
+	default:
+	}
+
+	c.dropped.Add(1)
+	if c.policy == PolicyDisconnect {
+		c.kickOnce.Do(func() {
+			close(c.kicked)
+		})
+	}
+	return false
+}
+
+// Registry holds the clients connected to this notifier instance.
+// Every instance receives all messages and delivers those of its own clients.
+type Registry struct {
+	mu    sync.RWMutex
+	users map[int]map[*Client]struct{}
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		users: make(map[int]map[*Client]struct{}),
+	}
+}
+
+func (r *Registry) Add(client *Client) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	clients, ok := r.users[client.UserID]
+	if !ok {
+		clients = make(map[*Client]struct{})
+		r.users[client.UserID] = clients
+	}
+	clients[client] = struct{}{}
+}
+
+func (r *Registry) Remove(client *Client) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	clients := r.users[client.UserID]
+	delete(clients, client)
+	if len(clients) == 0 {
+		delete(r.users, client.UserID)
+	}
+}
+
+// Send queues the delivery to the connections of the user subscribed to its
+// trip and returns the number of connections it was queued to.
+func (r *Registry) Send(userID int, delivery Delivery) int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	sent := 0
+	for client := range r.users[userID] {
+		if client.Subscribed(delivery.TripID) && client.enqueue(delivery) {
+			sent++
+		}
+	}
+	return sent
+}
+
+// SendPresence queues the delivery to the connections joined to its trip,
+// except the connection the presence change came from.
+func (r *Registry) SendPresence(delivery Delivery, exceptConnectionID string) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, clients := range r.users {
+		for client := range clients {
+			if client.ConnectionID != exceptConnectionID && client.Joined(delivery.TripID) {
+				client.enqueue(delivery)
+			}
+		}
+	}
+}
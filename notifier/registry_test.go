@@ -0,0 +1,66 @@
+package notifier
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestClientSubscribed(t *testing.T) {
+	first, second := uuid.New(), uuid.New()
+
+	tests := []struct {
+		name   string
+		change func(c *Client)
+		first  bool
+		second bool
+	}{
+		{
+			name:   "all trips until subscribed",
+			change: func(c *Client) {},
+			first:  true,
+			second: true,
+		},
+		{
+			name:   "subscribed trips only",
+			change: func(c *Client) { c.Subscribe(first) },
+			first:  true,
+			second: false,
+		},
+		{
+			name: "no trips after unsubscribing from the last one",
+			change: func(c *Client) {
+				c.Subscribe(first)
+				c.Unsubscribe(first)
+			},
+			first:  false,
+			second: false,
+		},
+		{
+			name:   "all trips but the unsubscribed one",
+			change: func(c *Client) { c.Unsubscribe(first) },
+			first:  false,
+			second: true,
+		},
+		{
+			name:   "joined trips are subscribed",
+			change: func(c *Client) { c.Join(second) },
+			first:  false,
+			second: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient("token", 1, 1, PolicyDrop)
+			tt.change(client)
+
+			if got := client.Subscribed(first); got != tt.first {
+				t.Errorf("subscribed to the first trip: %v, want %v", got, tt.first)
+			}
+			if got := client.Subscribed(second); got != tt.second {
+				t.Errorf("subscribed to the second trip: %v, want %v", got, tt.second)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package notifier
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// sseHandler streams the same messages as websocketHandler as Server-Sent
+// Events for clients behind proxies that break websockets. Subscriptions are
+// fixed for the stream: trips are passed as trip_id, cursors of the missed
+// messages as last_seen=<trip_id>:<seq> or the Last-Event-ID header.
+func (app *Notifier) sseHandler(c *gin.Context) {
+	if !app.acquire(c) {
+		return
+	}
+	defer app.connections.Done()
+
+	origin := c.GetHeader("Origin")
+	if !app.allowedOrigin(origin) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Origin is not allowed"})
+		return
+	}
+	if origin != "" {
+		c.Header("Access-Control-Allow-Origin", origin)
+		c.Header("Access-Control-Allow-Credentials", "true")
+	}
+
+	sessionToken, userID, ok := app.authorize(c)
+	if !ok {
+		return
+	}
+
+	var tripIDs []uuid.UUID
+	for _, value := range c.QueryArray("trip_id") {
+		tripID, err := uuid.Parse(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid trip_id"})
+			return
+		}
+		tripIDs = append(tripIDs, tripID)
+	}
+
+	// the browser resends the id of the last event on reconnect,
+	// it carries the cursors of all trips of the stream
+	cursors, err := parseCursors(append(c.QueryArray("last_seen"), c.GetHeader("Last-Event-ID")))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	wsCfg := app.config.WebsocketConfig
+	client := NewClient(sessionToken, userID, wsCfg.QueueSize, wsCfg.SlowClientPolicy)
+	for _, tripID := range tripIDs {
+		client.Subscribe(tripID)
+	}
+
+	t := &sseTransport{
+		writer:     c.Writer,
+		controller: http.NewResponseController(c.Writer),
+		cursors:    make(map[uuid.UUID]int64),
+	}
+	for _, cursor := range cursors {
+		client.RequestReplay(cursor)
+		t.cursors[cursor.TripID] = max(t.cursors[cursor.TripID], cursor.LastSeen)
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	// disables response buffering of nginx
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	if err := t.flush(); err != nil {
+		return
+	}
+
+	// registered before reading the history, so no message falls in between
+	app.registry.Add(client)
+	defer app.registry.Remove(client)
+
+	app.stream(client, t, c.Request.Context().Done())
+}
+
+type sseTransport struct {
+	writer     gin.ResponseWriter
+	controller *http.ResponseController
+	// cursors is sent as the event id, so a reconnect resumes every trip
+	cursors map[uuid.UUID]int64
+}
+
+func (t *sseTransport) Write(delivery Delivery) error {
+	if delivery.Seq > 0 {
+		t.cursors[delivery.TripID] = max(t.cursors[delivery.TripID], delivery.Seq)
+	}
+
+	err := t.controller.SetWriteDeadline(time.Now().Add(writeWait))
+	if err != nil && err != http.ErrNotSupported {
+		return err
+	}
+
+	_, err = fmt.Fprintf(t.writer, "id: %s\nevent: message\ndata: %s\n\n", formatCursors(t.cursors), delivery.Data)
+	if err != nil {
+		return err
+	}
+
+	return t.flush()
+}
+
+func (t *sseTransport) Ping() error {
+	_, err := fmt.Fprint(t.writer, ": ping\n\n")
+	if err != nil {
+		return err
+	}
+
+	return t.flush()
+}
+
+func (t *sseTransport) Close(reason string) {
+	_, err := fmt.Fprintf(t.writer, "event: close\ndata: %s\n\n", reason)
+	if err == nil {
+		t.flush()
+	}
+}
+
+func (t *sseTransport) flush() error {
+	err := t.controller.Flush()
+	if err == http.ErrNotSupported {
+		t.writer.Flush()
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,254 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/ShelbyKS/Roamly-backend/internal/domain"
+)
+
+// writeWait limits writing of a single message to a connection.
+const writeWait = 10 * time.Second
+
+// actionResync tells the client to reload the trip: messages it missed
+// are no longer kept.
+const actionResync = "trip_resync"
+
+const (
+	closeShutdown       = "server shutdown"
+	closeSlowClient     = "client is too slow"
+	closeSessionExpired = "session expired"
+)
+
+// transport writes messages of a stream to the client: websocket or SSE.
+type transport interface {
+	Write(delivery Delivery) error
+	Ping() error
+	// Close tells the client why the stream ends.
+	Close(reason string)
+}
+
+// allowedOrigin allows streams only from the configured frontends:
+// the session cookie is sent by browsers to any page opening the stream.
+// Non-browser clients send no origin.
+func (app *Notifier) allowedOrigin(origin string) bool {
+	if origin == "" {
+		return true
+	}
+
+	for _, allowed := range app.config.WebsocketConfig.AllowedOrigins {
+		if origin == allowed {
+			return true
+		}
+	}
+
+	log.Printf("Rejected stream from origin %q", origin)
+	return false
+}
+
+// acquire counts the handler until it returns, on shutdown the response
+// is already written.
+func (app *Notifier) acquire(c *gin.Context) bool {
+	if !app.acquireConnection() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Notifier is shutting down"})
+		return false
+	}
+	return true
+}
+
+// authorize returns the user of the session cookie,
+// on failure the response is already written.
+func (app *Notifier) authorize(c *gin.Context) (string, int, bool) {
+	sessionToken, err := c.Cookie("session_token")
+	if err != nil {
+		log.Printf("No session token cookie: %v", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "No session token"})
+		return "", 0, false
+	}
+
+	session, err := app.sessionStorage.SessionExists(c.Request.Context(), sessionToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid session token"})
+		return "", 0, false
+	}
+
+	return sessionToken, session.UserID, true
+}
+
+// stream delivers messages of the client through t until the client goes away
+// (done is closed), is kicked for being slow, or the notifier shuts down.
+// The client must be registered before: messages published meanwhile
+// are queued and written after the requested replays.
+func (app *Notifier) stream(client *Client, t transport, done <-chan struct{}) {
+	// presences are removed before the connection counts as closed,
+	// redis is still available then on shutdown
+	defer app.leaveAllTrips(context.Background(), client)
+
+	ping := time.NewTicker(app.config.WebsocketConfig.PongTimeout * 9 / 10)
+	defer ping.Stop()
+
+	// sent is the latest seq written per trip: messages both replayed
+	// and queued live are written once
+	sent := make(map[uuid.UUID]int64)
+	write := func(delivery Delivery) error {
+		if delivery.Seq > 0 && delivery.Seq <= sent[delivery.TripID] {
+			return nil
+		}
+
+		err := t.Write(delivery)
+		if err != nil {
+			return err
+		}
+
+		sent[delivery.TripID] = max(sent[delivery.TripID], delivery.Seq)
+		return nil
+	}
+
+	for {
+		// replays go first, so live streaming resumes after the missed messages
+		select {
+		case cursor := <-client.replays:
+			err := app.replay(client, cursor, sent, write)
+			if err != nil {
+				log.Printf("Failed to replay messages: %v", err)
+				return
+			}
+			continue
+		default:
+		}
+
+		select {
+		case cursor := <-client.replays:
+			err := app.replay(client, cursor, sent, write)
+			if err != nil {
+				log.Printf("Failed to replay messages: %v", err)
+				return
+			}
+		case delivery := <-client.queue:
+			err := write(delivery)
+			if err != nil {
+				log.Printf("Failed to send message: %v", err)
+				return
+			}
+		case <-ping.C:
+			// sessions end on logout or expiry while the stream stays open
+			_, err := app.sessionStorage.SessionExists(context.Background(), client.SessionToken)
+			if errors.Is(err, domain.ErrSessionNotFound) {
+				t.Close(closeSessionExpired)
+				return
+			}
+
+			app.refreshPresences(context.Background(), client)
+
+			err = t.Ping()
+			if err != nil {
+				log.Printf("Failed to send ping: %v", err)
+				return
+			}
+		case <-done:
+			return
+		case <-client.kicked:
+			log.Printf("Disconnecting slow client, %d message(s) dropped", client.dropped.Load())
+			t.Close(closeSlowClient)
+			return
+		case <-app.closing:
+			t.Close(closeShutdown)
+			return
+		}
+	}
+}
+
+// replay writes the messages of the trip the client missed after the cursor.
+// If some of them are no longer in the history, the client is asked to
+// reload the trip instead.
+func (app *Notifier) replay(client *Client, cursor Cursor, sent map[uuid.UUID]int64, write func(Delivery) error) error {
+	messages, complete, err := app.history.Since(context.Background(), cursor.TripID, cursor.LastSeen)
+	if err != nil {
+		return err
+	}
+
+	if !complete {
+		data, err := json.Marshal(EventPayload{Action: actionResync, TripID: cursor.TripID})
+		if err != nil {
+			return err
+		}
+
+		err = write(Delivery{TripID: cursor.TripID, Data: data})
+		if err != nil {
+			return err
+		}
+	}
+
+	sent[cursor.TripID] = max(sent[cursor.TripID], cursor.LastSeen)
+	for _, message := range messages {
+		if !slices.Contains(message.Members, client.UserID) {
+			continue
+		}
+
+		delivery, err := message.Delivery()
+		if err != nil {
+			return err
+		}
+
+		err = write(delivery)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parseCursors parses comma separated cursors formatted as <trip_id>:<seq>.
+func parseCursors(values []string) ([]Cursor, error) {
+	var cursors []Cursor
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item == "" {
+				continue
+			}
+
+			tripID, seq, ok := strings.Cut(item, ":")
+			if !ok {
+				return nil, fmt.Errorf("invalid cursor %q, expected <trip_id>:<seq>", item)
+			}
+
+			cursor := Cursor{}
+			var err error
+			cursor.TripID, err = uuid.Parse(tripID)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trip id in cursor %q: %w", item, err)
+			}
+			cursor.LastSeen, err = strconv.ParseInt(seq, 10, 64)
+			if err != nil || cursor.LastSeen < 0 {
+				return nil, fmt.Errorf("invalid seq in cursor %q", item)
+			}
+
+			cursors = append(cursors, cursor)
+		}
+	}
+
+	return cursors, nil
+}
+
+// formatCursors is the inverse of parseCursors.
+func formatCursors(cursors map[uuid.UUID]int64) string {
+	items := make([]string, 0, len(cursors))
+	for tripID, seq := range cursors {
+		items = append(items, fmt.Sprintf("%s:%d", tripID, seq))
+	}
+	slices.Sort(items)
+
+	return strings.Join(items, ",")
+}
@@ -0,0 +1,166 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/model"
+)
+
+const (
+	commandSubscribe   = "subscribe"
+	commandUnsubscribe = "unsubscribe"
+	commandJoin        = "join"
+	commandLeave       = "leave"
+	commandFocus       = "focus"
+)
+
+// Command is sent by websocket clients to choose the trips they get messages of
+// and to share their presence in a trip. LastSeen in subscribe replays the
+// messages after it, Focus in focus is the event or place the member edits,
+// none clears it.
+type Command struct {
+	Action   string               `json:"action"`
+	TripID   uuid.UUID            `json:"trip_id"`
+	LastSeen *int64               `json:"last_seen,omitempty"`
+	Focus    *model.PresenceFocus `json:"focus,omitempty"`
+}
+
+// websocketHandler streams messages over a websocket. Cursors of the missed
+// messages are passed as last_seen=<trip_id>:<seq> or in subscribe commands.
+func (app *Notifier) websocketHandler(c *gin.Context) {
+	if !app.acquire(c) {
+		return
+	}
+	defer app.connections.Done()
+
+	sessionToken, userID, ok := app.authorize(c)
+	if !ok {
+		return
+	}
+
+	cursors, err := parseCursors(c.QueryArray("last_seen"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	conn, err := app.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("Failed to upgrade connection for websocket: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	wsCfg := app.config.WebsocketConfig
+	client := NewClient(sessionToken, userID, wsCfg.QueueSize, wsCfg.SlowClientPolicy)
+
+	// registered before reading the history, so no message falls in between
+	app.registry.Add(client)
+	defer app.registry.Remove(client)
+
+	for _, cursor := range cursors {
+		client.RequestReplay(cursor)
+	}
+
+	readDone := make(chan struct{})
+	go func() {
+		defer close(readDone)
+		app.readPump(conn, client, wsCfg.PongTimeout)
+	}()
+
+	app.stream(client, &websocketTransport{conn: conn}, readDone)
+
+	// the read pump touches the storages, it must be done before the handler counts as closed
+	conn.Close()
+	<-readDone
+}
+
+// readPump handles subscription and presence commands, pongs and close frames.
+// It returns when the connection is closed or stays silent for pongTimeout.
+func (app *Notifier) readPump(conn *websocket.Conn, client *Client, pongTimeout time.Duration) {
+	conn.SetReadLimit(512)
+	conn.SetReadDeadline(time.Now().Add(pongTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongTimeout))
+	})
+
+	for {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var command Command
+		if err := json.Unmarshal(msg, &command); err != nil {
+			log.Printf("Failed to decode command: %v", err)
+			continue
+		}
+
+		switch command.Action {
+		case commandSubscribe:
+			if command.LastSeen == nil {
+				client.Subscribe(command.TripID)
+				continue
+			}
+
+			ok := client.RequestReplay(Cursor{TripID: command.TripID, LastSeen: *command.LastSeen})
+			if !ok {
+				log.Printf("Too many replays requested by user %d", client.UserID)
+			}
+		case commandUnsubscribe:
+			client.Unsubscribe(command.TripID)
+		case commandJoin:
+			err := app.joinTrip(context.Background(), client, command.TripID)
+			if err != nil {
+				log.Printf("Failed to join trip %s by user %d: %v", command.TripID, client.UserID, err)
+			}
+		case commandLeave:
+			err := app.leaveTrip(context.Background(), client, command.TripID)
+			if err != nil {
+				log.Printf("Failed to leave trip %s by user %d: %v", command.TripID, client.UserID, err)
+			}
+		case commandFocus:
+			err := app.focusTrip(context.Background(), client, command.TripID, command.Focus)
+			if err != nil {
+				log.Printf("Failed to change focus in trip %s by user %d: %v", command.TripID, client.UserID, err)
+			}
+		default:
+			log.Printf("Unknown command %q", command.Action)
+		}
+	}
+}
+
+type websocketTransport struct {
+	conn *websocket.Conn
+}
+
+func (t *websocketTransport) Write(delivery Delivery) error {
+	t.conn.SetWriteDeadline(time.Now().Add(writeWait))
+	return t.conn.WriteMessage(websocket.TextMessage, delivery.Data)
+}
+
+func (t *websocketTransport) Ping() error {
+	return t.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait))
+}
+
+var closeCodes = map[string]int{
+	closeShutdown:       websocket.CloseGoingAway,
+	closeSlowClient:     websocket.ClosePolicyViolation,
+	closeSessionExpired: websocket.ClosePolicyViolation,
+}
+
+func (t *websocketTransport) Close(reason string) {
+	closeMessage := websocket.FormatCloseMessage(closeCodes[reason], reason)
+	err := t.conn.WriteControl(websocket.CloseMessage, closeMessage, time.Now().Add(time.Second))
+	if err != nil {
+		log.Printf("Failed to send close frame: %v", err)
+	}
+}
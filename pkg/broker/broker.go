@@ -0,0 +1,110 @@
+// Package broker connects the API and the notifier through a message broker
+// chosen by the config: Kafka, Redis Streams or an in-process queue.
+package broker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	goRedis "github.com/redis/go-redis/v9"
+
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/model"
+	"github.com/ShelbyKS/Roamly-backend/pkg/broker/kafka"
+	"github.com/ShelbyKS/Roamly-backend/pkg/broker/memory"
+	"github.com/ShelbyKS/Roamly-backend/pkg/broker/redis"
+)
+
+const (
+	TypeKafka  = "kafka"
+	TypeRedis  = "redis"
+	TypeMemory = "memory"
+)
+
+type Publisher interface {
+	// Publish returns after the broker has accepted value.
+	Publish(ctx context.Context, value []byte) error
+	Close(timeout time.Duration) error
+}
+
+type Subscriber interface {
+	// Subscribe calls handle for every message until ctx is done.
+	// A message is acknowledged only after handle returns nil.
+	Subscribe(ctx context.Context, handle func(ctx context.Context, value []byte) error) error
+	Close() error
+}
+
+type Config struct {
+	Type  string
+	Topic string
+	Group string
+	// FromLatest makes a new group skip messages published before it was created.
+	FromLatest bool
+
+	KafkaHost string
+	KafkaPort string
+
+	Redis         *goRedis.Client
+	RedisMaxLen   int64
+	RedisConsumer string
+
+	// SingleProcess is set when the API and the notifier run in one process,
+	// the memory broker is rejected otherwise: nobody would read its queue.
+	SingleProcess bool
+}
+
+var ErrMemoryBroker = errors.New("memory broker works only when the API and the notifier run in one process")
+
+func NewPublisher(cfg Config) (Publisher, error) {
+	switch cfg.Type {
+	case TypeKafka:
+		return kafka.NewPublisher(cfg.KafkaHost, cfg.KafkaPort, cfg.Topic)
+	case TypeRedis:
+		return redis.NewPublisher(cfg.Redis, cfg.Topic, cfg.RedisMaxLen), nil
+	case TypeMemory:
+		if !cfg.SingleProcess {
+			return nil, ErrMemoryBroker
+		}
+		return memory.NewPublisher(cfg.Topic), nil
+	default:
+		return nil, fmt.Errorf("unknown broker %q", cfg.Type)
+	}
+}
+
+func NewSubscriber(cfg Config) (Subscriber, error) {
+	switch cfg.Type {
+	case TypeKafka:
+		return kafka.NewSubscriber(cfg.KafkaHost, cfg.KafkaPort, cfg.Topic, cfg.Group, cfg.FromLatest)
+	case TypeRedis:
+		return redis.NewSubscriber(cfg.Redis, cfg.Topic, cfg.Group, cfg.RedisConsumer, cfg.FromLatest), nil
+	case TypeMemory:
+		if !cfg.SingleProcess {
+			return nil, ErrMemoryBroker
+		}
+		return memory.NewSubscriber(cfg.Topic), nil
+	default:
+		return nil, fmt.Errorf("unknown broker %q", cfg.Type)
+	}
+}
+
+// MessageProducer publishes trip notifications with any broker.
+type MessageProducer struct {
+	publisher Publisher
+}
+
+func NewMessageProducer(publisher Publisher) *MessageProducer {
+	return &MessageProducer{
+		publisher: publisher,
+	}
+}
+
+func (p *MessageProducer) SendMessage(ctx context.Context, msg model.NotifyMessage) error {
+	jsonMessage, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to serialize message: %w", err)
+	}
+
+	return p.publisher.Publish(ctx, jsonMessage)
+}
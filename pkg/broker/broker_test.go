@@ -0,0 +1,111 @@
+package broker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/model"
+)
+
+func TestMemoryBroker(t *testing.T) {
+	cfg := Config{
+		Type:          TypeMemory,
+		Topic:         "test-" + uuid.NewString(),
+		SingleProcess: true,
+	}
+
+	publisher, err := NewPublisher(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	subscriber, err := NewSubscriber(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var sent [3]model.NotifyMessage
+	for i := range sent {
+		sent[i].Payload.TripID = uuid.New()
+		sent[i].Payload.Seq = int64(i + 1)
+		err := NewMessageProducer(publisher).SendMessage(ctx, sent[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var received []model.NotifyMessage
+	err = subscriber.Subscribe(ctx, func(ctx context.Context, value []byte) error {
+		var msg model.NotifyMessage
+		if err := json.Unmarshal(value, &msg); err != nil {
+			t.Errorf("failed to decode message: %v", err)
+		}
+		received = append(received, msg)
+		if len(received) == len(sent) {
+			cancel()
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(received) != len(sent) {
+		t.Fatalf("received %d messages, want %d", len(received), len(sent))
+	}
+	for i, msg := range received {
+		if msg.Payload.TripID != sent[i].Payload.TripID || msg.Payload.Seq != sent[i].Payload.Seq {
+			t.Errorf("message %d is %+v, want %+v", i, msg.Payload, sent[i].Payload)
+		}
+	}
+}
+
+func TestMemoryBrokerPublishStopsWithContext(t *testing.T) {
+	publisher, err := NewPublisher(Config{Type: TypeMemory, Topic: "test-" + uuid.NewString(), SingleProcess: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// nobody reads the queue, publishing must not block once it is full
+	for {
+		err := publisher.Publish(ctx, []byte("{}"))
+		if errors.Is(err, context.Canceled) {
+			return
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestMemoryBrokerRejectedAcrossProcesses(t *testing.T) {
+	cfg := Config{Type: TypeMemory, Topic: "test-" + uuid.NewString()}
+
+	if _, err := NewPublisher(cfg); !errors.Is(err, ErrMemoryBroker) {
+		t.Errorf("publisher error %v, want %v", err, ErrMemoryBroker)
+	}
+	if _, err := NewSubscriber(cfg); !errors.Is(err, ErrMemoryBroker) {
+		t.Errorf("subscriber error %v, want %v", err, ErrMemoryBroker)
+	}
+}
+
+func TestUnknownBroker(t *testing.T) {
+	cfg := Config{Type: "rabbitmq"}
+
+	if _, err := NewPublisher(cfg); err == nil {
+		t.Error("no error for an unknown publisher")
+	}
+	if _, err := NewSubscriber(cfg); err == nil {
+		t.Error("no error for an unknown subscriber")
+	}
+}
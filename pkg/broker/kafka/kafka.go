@@ -0,0 +1,157 @@
+//go:build cgo
+
+// Package kafka is the Kafka message broker. librdkafka requires cgo,
+// builds without it get a stub that fails on start.
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+const (
+	deliveryTimeout = 10 * time.Second
+	pollTimeout     = 100 * time.Millisecond
+)
+
+type Publisher struct {
+	producer *kafka.Producer
+	topic    string
+}
+
+func NewPublisher(host string, port string, topic string) (*Publisher, error) {
+	producer, err := kafka.NewProducer(&kafka.ConfigMap{
+		"bootstrap.servers": fmt.Sprintf("%s:%s", host, port),
+		// Publish blocks until the delivery report, so it must not wait forever
+		"message.timeout.ms": int(deliveryTimeout.Milliseconds()),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to init kafka producer: %w", err)
+	}
+
+	return &Publisher{
+		producer: producer,
+		topic:    topic,
+	}, nil
+}
+
+// Publish produces value and waits until the broker acknowledges it.
+func (p *Publisher) Publish(ctx context.Context, value []byte) error {
+	deliveryChan := make(chan kafka.Event, 1)
+	err := p.producer.Produce(&kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &p.topic, Partition: kafka.PartitionAny},
+		Value:          value,
+	}, deliveryChan)
+	if err != nil {
+		return fmt.Errorf("failed to produce message: %w", err)
+	}
+
+	var event kafka.Event
+	select {
+	case event = <-deliveryChan:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	report, ok := event.(*kafka.Message)
+	if !ok {
+		return fmt.Errorf("unexpected delivery report: %v", event)
+	}
+	if report.TopicPartition.Error != nil {
+		return fmt.Errorf("failed to deliver message: %w", report.TopicPartition.Error)
+	}
+
+	return nil
+}
+
+// Close waits up to timeout for queued messages to be delivered and closes the producer.
+func (p *Publisher) Close(timeout time.Duration) error {
+	left := p.producer.Flush(int(timeout.Milliseconds()))
+	p.producer.Close()
+
+	if left > 0 {
+		return fmt.Errorf("%d message(s) were not delivered", left)
+	}
+	return nil
+}
+
+type Subscriber struct {
+	consumer *kafka.Consumer
+	topic    string
+}
+
+func NewSubscriber(host string, port string, topic string, group string, fromLatest bool) (*Subscriber, error) {
+	offsetReset := "earliest"
+	if fromLatest {
+		offsetReset = "latest"
+	}
+
+	consumer, err := kafka.NewConsumer(&kafka.ConfigMap{
+		"bootstrap.servers": fmt.Sprintf("%s:%s", host, port),
+		"group.id":          group,
+		"auto.offset.reset": offsetReset,
+		// offsets are stored only after the message is handled
+		"enable.auto.offset.store": false,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to init kafka consumer: %w", err)
+	}
+
+	return &Subscriber{
+		consumer: consumer,
+		topic:    topic,
+	}, nil
+}
+
+// Subscribe calls handle for every message until ctx is done. The offset of
+// a message is committed only after handle returns nil.
+func (s *Subscriber) Subscribe(ctx context.Context, handle func(ctx context.Context, value []byte) error) error {
+	err := s.consumer.Subscribe(s.topic, nil)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe kafka topic: %w", err)
+	}
+
+	for ctx.Err() == nil {
+		msg, err := s.consumer.ReadMessage(pollTimeout)
+		if err != nil {
+			if kafkaErr, ok := err.(kafka.Error); !ok || kafkaErr.Code() != kafka.ErrTimedOut {
+				log.Printf("Failed to read message: %v\n", err)
+			}
+			continue
+		}
+
+		err = handle(ctx, msg.Value)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			log.Printf("Failed to handle message: %v\n", err)
+			continue
+		}
+
+		if _, err := s.consumer.StoreMessage(msg); err != nil {
+			log.Printf("Failed to store offset: %v\n", err)
+		}
+	}
+
+	return nil
+}
+
+// Close commits the handled messages and leaves the consumer group.
+func (s *Subscriber) Close() error {
+	if _, err := s.consumer.Commit(); err != nil && !isNoOffset(err) {
+		log.Printf("Failed to commit offsets: %v\n", err)
+	}
+
+	return s.consumer.Close()
+}
+
+func isNoOffset(err error) bool {
+	var kafkaErr kafka.Error
+	return errors.As(err, &kafkaErr) && kafkaErr.Code() == kafka.ErrNoOffset
+}
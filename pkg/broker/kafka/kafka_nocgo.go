@@ -0,0 +1,39 @@
+//go:build !cgo
+
+package kafka
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrUnavailable = errors.New("kafka broker requires cgo, rebuild with CGO_ENABLED=1 or use another broker")
+
+type Publisher struct{}
+
+func NewPublisher(host string, port string, topic string) (*Publisher, error) {
+	return nil, ErrUnavailable
+}
+
+func (p *Publisher) Publish(ctx context.Context, value []byte) error {
+	return ErrUnavailable
+}
+
+func (p *Publisher) Close(timeout time.Duration) error {
+	return nil
+}
+
+type Subscriber struct{}
+
+func NewSubscriber(host string, port string, topic string, group string, fromLatest bool) (*Subscriber, error) {
+	return nil, ErrUnavailable
+}
+
+func (s *Subscriber) Subscribe(ctx context.Context, handle func(ctx context.Context, value []byte) error) error {
+	return ErrUnavailable
+}
+
+func (s *Subscriber) Close() error {
+	return nil
+}
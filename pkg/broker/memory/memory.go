@@ -0,0 +1,80 @@
+// Package memory is an in-process message broker for local development and
+// tests: publishers and subscribers of a topic must live in the same process.
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const queueSize = 1024
+
+var (
+	mu     sync.Mutex
+	topics = make(map[string]chan []byte)
+)
+
+// queue returns the queue shared by all publishers and subscribers of topic.
+func queue(topic string) chan []byte {
+	mu.Lock()
+	defer mu.Unlock()
+
+	q, ok := topics[topic]
+	if !ok {
+		q = make(chan []byte, queueSize)
+		topics[topic] = q
+	}
+	return q
+}
+
+type Publisher struct {
+	queue chan []byte
+}
+
+func NewPublisher(topic string) *Publisher {
+	return &Publisher{
+		queue: queue(topic),
+	}
+}
+
+// Publish blocks while the queue is full.
+func (p *Publisher) Publish(ctx context.Context, value []byte) error {
+	select {
+	case p.queue <- value:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *Publisher) Close(timeout time.Duration) error {
+	return nil
+}
+
+// Subscriber competes for messages with other subscribers of the topic,
+// like the members of one consumer group.
+type Subscriber struct {
+	queue chan []byte
+}
+
+func NewSubscriber(topic string) *Subscriber {
+	return &Subscriber{
+		queue: queue(topic),
+	}
+}
+
+func (s *Subscriber) Subscribe(ctx context.Context, handle func(ctx context.Context, value []byte) error) error {
+	for {
+		select {
+		case value := <-s.queue:
+			_ = handle(ctx, value)
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (s *Subscriber) Close() error {
+	return nil
+}
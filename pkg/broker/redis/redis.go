@@ -0,0 +1,150 @@
+// Package redis is the message broker on top of Redis Streams.
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	goRedis "github.com/redis/go-redis/v9"
+)
+
+const (
+	valueField = "value"
+	readCount  = 100
+	readBlock  = time.Second
+)
+
+type Publisher struct {
+	client *goRedis.Client
+	stream string
+	maxLen int64
+}
+
+// NewPublisher returns a publisher to stream, which is trimmed to about maxLen entries.
+func NewPublisher(client *goRedis.Client, stream string, maxLen int64) *Publisher {
+	return &Publisher{
+		client: client,
+		stream: stream,
+		maxLen: maxLen,
+	}
+}
+
+func (p *Publisher) Publish(ctx context.Context, value []byte) error {
+	err := p.client.XAdd(ctx, &goRedis.XAddArgs{
+		Stream: p.stream,
+		MaxLen: p.maxLen,
+		Approx: true,
+		Values: map[string]interface{}{valueField: value},
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("failed to add message to stream: %w", err)
+	}
+
+	return nil
+}
+
+// Close does nothing: XADD is acknowledged synchronously and the client is owned by the caller.
+func (p *Publisher) Close(timeout time.Duration) error {
+	return nil
+}
+
+type Subscriber struct {
+	client   *goRedis.Client
+	stream   string
+	group    string
+	consumer string
+	start    string
+}
+
+// NewSubscriber reads stream as consumer of group. Consumer names must be
+// unique within the group and stable across restarts of the same instance.
+func NewSubscriber(client *goRedis.Client, stream string, group string, consumer string, fromLatest bool) *Subscriber {
+	start := "0"
+	if fromLatest {
+		start = "$"
+	}
+
+	return &Subscriber{
+		client:   client,
+		stream:   stream,
+		group:    group,
+		consumer: consumer,
+		start:    start,
+	}
+}
+
+// Subscribe calls handle for every message until ctx is done. A message is
+// acknowledged only after handle returns nil, messages left pending by the
+// previous run of the consumer are handled first.
+func (s *Subscriber) Subscribe(ctx context.Context, handle func(ctx context.Context, value []byte) error) error {
+	err := s.client.XGroupCreateMkStream(ctx, s.stream, s.group, s.start).Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("failed to create consumer group: %w", err)
+	}
+
+	// pending messages are read by their ids starting from "0", new ones by ">"
+	pending := true
+	lastID := "0"
+	for ctx.Err() == nil {
+		streams, err := s.client.XReadGroup(ctx, &goRedis.XReadGroupArgs{
+			Group:    s.group,
+			Consumer: s.consumer,
+			Streams:  []string{s.stream, lastID},
+			Count:    readCount,
+			Block:    readBlock,
+		}).Result()
+		if errors.Is(err, goRedis.Nil) {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			log.Printf("Failed to read stream: %v\n", err)
+			time.Sleep(readBlock)
+			continue
+		}
+
+		var messages []goRedis.XMessage
+		for _, stream := range streams {
+			messages = append(messages, stream.Messages...)
+		}
+		if pending {
+			if len(messages) == 0 {
+				pending = false
+				lastID = ">"
+				continue
+			}
+			lastID = messages[len(messages)-1].ID
+		}
+
+		for _, message := range messages {
+			value, _ := message.Values[valueField].(string)
+
+			err = handle(ctx, []byte(value))
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				log.Printf("Failed to handle message %s: %v\n", message.ID, err)
+				continue
+			}
+
+			err = s.client.XAck(ctx, s.stream, s.group, message.ID).Err()
+			if err != nil {
+				log.Printf("Failed to ack message %s: %v\n", message.ID, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Close does nothing: the client is owned by the caller.
+func (s *Subscriber) Close() error {
+	return nil
+}
@@ -0,0 +1,335 @@
+package googleapi
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	goRedis "github.com/redis/go-redis/v9"
+
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/clients"
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/model"
+)
+
+const cachePrefix = "googleapi:"
+
+// cacheStats counts hits and misses by method, served at /debug/vars.
+var cacheStats = expvar.NewMap("google_api_cache")
+
+// CacheTTL are lifetimes of cached responses, zero disables caching of the method.
+type CacheTTL struct {
+	Place    time.Duration // GetPlaceByID
+	Search   time.Duration // FindPlace and GetPlacesNearby
+	Matrix   time.Duration // GetTimeDistanceMatrix and GetTimeDistanceMatrixBetween, every place pair separately
+	TimeZone time.Duration // GetTimeZone
+}
+
+// CachedClient keeps responses of the client in redis. Cache failures are
+// not fatal: the client is called directly then.
+type CachedClient struct {
+	client clients.IGoogleApiClient
+	redis  *goRedis.Client
+	ttl    CacheTTL
+}
+
+func NewCachedClient(client clients.IGoogleApiClient, redis *goRedis.Client, ttl CacheTTL) *CachedClient {
+	return &CachedClient{
+		client: client,
+		redis:  redis,
+		ttl:    ttl,
+	}
+}
+
+func (c *CachedClient) Provider() string {
+	return c.client.Provider()
+}
+
+func (c *CachedClient) FindPlace(ctx context.Context, input string, fields []string) ([]model.GooglePlace, error) {
+	// place and matrix keys are made of place ids that differ by provider, queries are not
+	key := cachePrefix + c.client.Provider() + ":find:" + language + ":" + fieldsKey(fields) + ":" + digest(input)
+
+	return cached(ctx, c, "find_place", key, c.ttl.Search, func() ([]model.GooglePlace, error) {
+		return c.client.FindPlace(ctx, input, fields)
+	})
+}
+
+func (c *CachedClient) GetPlaceByID(ctx context.Context, id string, fields []string) (model.GooglePlace, error) {
+	key := placeKeyPrefix(id) + language + ":" + fieldsKey(fields)
+
+	return cached(ctx, c, "place", key, c.ttl.Place, func() (model.GooglePlace, error) {
+		return c.client.GetPlaceByID(ctx, id, fields)
+	})
+}
+
+func (c *CachedClient) GetTimeZone(ctx context.Context, lat float64, lng float64) (string, error) {
+	// ~11 m precision, zones don't change within it
+	key := cachePrefix + c.client.Provider() + fmt.Sprintf(":tz:%.4f:%.4f", lat, lng)
+
+	return cached(ctx, c, "time_zone", key, c.ttl.TimeZone, func() (string, error) {
+		return c.client.GetTimeZone(ctx, lat, lng)
+	})
+}
+
+func (c *CachedClient) GetPlacesNearby(ctx context.Context,
+	includedTypes []string,
+	maxPlaces int,
+	rankPrefernce string,
+	lat float64,
+	lng float64,
+	radius float64,
+	languageCode string) ([]model.GooglePlace, error) {
+	key := cachePrefix + c.client.Provider() + ":nearby:" + languageCode + ":" + digest(
+		fieldsKey(includedTypes),
+		fmt.Sprint(maxPlaces),
+		rankPrefernce,
+		fmt.Sprintf("%.5f:%.5f:%.0f", lat, lng, radius),
+	)
+
+	return cached(ctx, c, "places_nearby", key, c.ttl.Search, func() ([]model.GooglePlace, error) {
+		return c.client.GetPlacesNearby(ctx, includedTypes, maxPlaces, rankPrefernce, lat, lng, radius, languageCode)
+	})
+}
+
+func (c *CachedClient) GetTimeDistanceMatrix(ctx context.Context, placeIDs []string, mode string) (model.DistanceMatrix, error) {
+	return c.GetTimeDistanceMatrixBetween(ctx, placeIDs, placeIDs, mode)
+}
+
+// GetTimeDistanceMatrixBetween caches every pair, so a reschedule with one new
+// place asks the client only for the row and the column of that place.
+// Pairs without a route are cached too.
+func (c *CachedClient) GetTimeDistanceMatrixBetween(
+	ctx context.Context,
+	origins []string,
+	destinations []string,
+	mode string,
+) (model.DistanceMatrix, error) {
+	if c.ttl.Matrix <= 0 || len(origins) == 0 || len(destinations) == 0 {
+		return c.client.GetTimeDistanceMatrixBetween(ctx, origins, destinations, mode)
+	}
+
+	keys := make([]string, 0, len(origins)*len(destinations))
+	for _, origin := range origins {
+		for _, destination := range destinations {
+			keys = append(keys, matrixKey(mode, origin, destination))
+		}
+	}
+
+	values, err := c.redis.MGet(ctx, keys...).Result()
+	if err != nil {
+		log.Printf("google api cache: failed to get matrix: %v", err)
+		values = make([]any, len(keys))
+	}
+
+	matrix := make(model.DistanceMatrix, len(origins))
+	var missing []placePair
+	for i, value := range values {
+		pair := placePair{origin: origins[i/len(destinations)], destination: destinations[i%len(destinations)]}
+
+		data, ok := value.(string)
+		var metrics map[string]float64
+		if !ok || json.Unmarshal([]byte(data), &metrics) != nil {
+			missing = append(missing, pair)
+			continue
+		}
+
+		setMetrics(matrix, pair.origin, pair.destination, metrics)
+	}
+	cacheStats.Add("matrix_hit", int64(len(keys)-len(missing)))
+	cacheStats.Add("matrix_miss", int64(len(missing)))
+
+	if len(missing) == 0 {
+		return matrix, nil
+	}
+
+	// every missing pair starts or ends at a new place: new -> all, then the rest -> new
+	isNew := make(map[string]bool)
+	for _, placeID := range newPlaces(missing) {
+		isNew[placeID] = true
+	}
+	newOrigins := slices.DeleteFunc(slices.Clone(origins), func(placeID string) bool {
+		return !isNew[placeID]
+	})
+	oldOrigins := slices.DeleteFunc(slices.Clone(origins), func(placeID string) bool {
+		return isNew[placeID]
+	})
+	newDestinations := slices.DeleteFunc(slices.Clone(destinations), func(placeID string) bool {
+		return !isNew[placeID]
+	})
+
+	pipe := c.redis.Pipeline()
+	for _, request := range []struct{ origins, destinations []string }{
+		{origins: newOrigins, destinations: destinations},
+		{origins: oldOrigins, destinations: newDestinations},
+	} {
+		if len(request.origins) == 0 || len(request.destinations) == 0 {
+			continue
+		}
+
+		fetched, err := c.client.GetTimeDistanceMatrixBetween(ctx, request.origins, request.destinations, mode)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, origin := range request.origins {
+			for _, destination := range request.destinations {
+				metrics := fetched[origin][destination]
+				setMetrics(matrix, origin, destination, metrics)
+
+				if metrics == nil {
+					metrics = map[string]float64{}
+				}
+				data, err := json.Marshal(metrics)
+				if err != nil {
+					continue
+				}
+				pipe.Set(ctx, matrixKey(mode, origin, destination), data, c.ttl.Matrix)
+			}
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("google api cache: failed to save matrix: %v", err)
+	}
+
+	return matrix, nil
+}
+
+type placePair struct {
+	origin      string
+	destination string
+}
+
+// newPlaces picks few places that every missing pair starts or ends at.
+// The place with most missing pairs is taken first, so a place added
+// to a cached trip is the only one picked.
+func newPlaces(missing []placePair) []string {
+	var result []string
+	for len(missing) > 0 {
+		counts := make(map[string]int)
+		best := ""
+		for _, pair := range missing {
+			placeIDs := []string{pair.origin, pair.destination}
+			if pair.origin == pair.destination {
+				placeIDs = placeIDs[:1]
+			}
+			for _, placeID := range placeIDs {
+				counts[placeID]++
+				if counts[placeID] > counts[best] {
+					best = placeID
+				}
+			}
+		}
+
+		result = append(result, best)
+		missing = slices.DeleteFunc(missing, func(pair placePair) bool {
+			return pair.origin == best || pair.destination == best
+		})
+	}
+	return result
+}
+
+// InvalidatePlace drops the cached details of the place and its distances.
+// Search results with the place expire by their TTL.
+func (c *CachedClient) InvalidatePlace(ctx context.Context, placeID string) error {
+	patterns := []string{
+		placeKeyPrefix(placeID) + "*",
+		cachePrefix + "matrix:*:" + placeID + ":*",
+		cachePrefix + "matrix:*:" + placeID,
+	}
+
+	for _, pattern := range patterns {
+		iter := c.redis.Scan(ctx, 0, pattern, 100).Iterator()
+		var keys []string
+		for iter.Next(ctx) {
+			keys = append(keys, iter.Val())
+		}
+		if err := iter.Err(); err != nil {
+			return fmt.Errorf("failed to find cached keys of place %s: %w", placeID, err)
+		}
+		if len(keys) == 0 {
+			continue
+		}
+
+		if err := c.redis.Del(ctx, keys...).Err(); err != nil {
+			return fmt.Errorf("failed to delete cached keys of place %s: %w", placeID, err)
+		}
+	}
+
+	return nil
+}
+
+func cached[T any](
+	ctx context.Context,
+	c *CachedClient,
+	method string,
+	key string,
+	ttl time.Duration,
+	load func() (T, error),
+) (T, error) {
+	if ttl <= 0 {
+		return load()
+	}
+
+	var value T
+	data, err := c.redis.Get(ctx, key).Bytes()
+	if err == nil && json.Unmarshal(data, &value) == nil {
+		cacheStats.Add(method+"_hit", 1)
+		return value, nil
+	}
+	if err != nil && !errors.Is(err, goRedis.Nil) {
+		log.Printf("google api cache: failed to get %s: %v", key, err)
+	}
+	cacheStats.Add(method+"_miss", 1)
+
+	value, err = load()
+	if err != nil {
+		return value, err
+	}
+
+	data, err = json.Marshal(value)
+	if err == nil {
+		err = c.redis.Set(ctx, key, data, ttl).Err()
+	}
+	if err != nil {
+		log.Printf("google api cache: failed to save %s: %v", key, err)
+	}
+
+	return value, nil
+}
+
+func setMetrics(matrix model.DistanceMatrix, origin, destination string, metrics map[string]float64) {
+	if len(metrics) == 0 {
+		return
+	}
+	if matrix[origin] == nil {
+		matrix[origin] = make(map[string]map[string]float64)
+	}
+	matrix[origin][destination] = metrics
+}
+
+func placeKeyPrefix(placeID string) string {
+	return cachePrefix + "place:" + placeID + ":"
+}
+
+func matrixKey(mode, origin, destination string) string {
+	return cachePrefix + "matrix:" + mode + ":" + language + ":" + origin + ":" + destination
+}
+
+// fieldsKey doesn't depend on the order of fields.
+func fieldsKey(fields []string) string {
+	sorted := slices.Clone(fields)
+	slices.Sort(sorted)
+	return strings.Join(sorted, ",")
+}
+
+func digest(parts ...string) string {
+	sum := sha1.Sum([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
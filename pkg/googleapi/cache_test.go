@@ -0,0 +1,66 @@
+package googleapi
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNewPlaces(t *testing.T) {
+	// pairs of the place with the cached ones, the place to itself included
+	pairsOf := func(placeID string, cached ...string) []placePair {
+		pairs := []placePair{{origin: placeID, destination: placeID}}
+		for _, other := range cached {
+			pairs = append(pairs,
+				placePair{origin: placeID, destination: other},
+				placePair{origin: other, destination: placeID})
+		}
+		return pairs
+	}
+
+	tests := []struct {
+		name    string
+		missing []placePair
+		places  []string
+	}{
+		{
+			name:    "nothing is missing",
+			missing: nil,
+			places:  nil,
+		},
+		{
+			name:    "one new place",
+			missing: pairsOf("new", "p00", "p01", "p02"),
+			places:  []string{"new"},
+		},
+		{
+			name: "two new places",
+			missing: slices.Concat(
+				pairsOf("a", "p00", "p01", "p02", "b"),
+				pairsOf("b", "p00", "p01", "p02"),
+			),
+			places: []string{"a", "b"},
+		},
+		{
+			name:    "single expired pair",
+			missing: []placePair{{origin: "p00", destination: "p01"}},
+			places:  []string{"p00"},
+		},
+		{
+			name:    "expired pair to itself",
+			missing: []placePair{{origin: "p00", destination: "p00"}},
+			places:  []string{"p00"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			missing := slices.Clone(tt.missing)
+
+			places := newPlaces(missing)
+
+			if !slices.Equal(places, tt.places) {
+				t.Errorf("new places %v, want %v", places, tt.places)
+			}
+		})
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/go-resty/resty/v2"
 
@@ -22,9 +24,16 @@ const (
 	methodGetPlacePhoto   = "https://maps.googleapis.com/maps/api/place/photo"
 	methodGetTimeMatrix   = "https://maps.googleapis.com/maps/api/distancematrix/json"
 	methodGetPlacesNearby = "https://places.googleapis.com/v1/places:searchNearby"
+	methodGetTimeZone     = "https://maps.googleapis.com/maps/api/timezone/json"
 	// methodGetPlacesNearby = "https://maps.googleapis.com/maps/api/place/nearbysearch/json"
 
 	fieldMask = "places.id,places.formattedAddress,places.displayName,places.rating,places.location,places.photos,places.editorialSummary"
+
+	// language of place names and addresses in responses
+	language = "ru"
+
+	// requestTimeout limits every request to the api, photos included.
+	requestTimeout = 30 * time.Second
 )
 
 type Location struct {
@@ -69,11 +78,15 @@ func NewClient(apiKey string) *GoogleApiClient {
 	// init settings from cfg
 
 	return &GoogleApiClient{
-		client: resty.New(),
+		client: resty.New().SetTimeout(requestTimeout),
 		apiKey: apiKey,
 	}
 }
 
+func (c *GoogleApiClient) Provider() string {
+	return model.PlaceProviderGoogle
+}
+
 type FindPlaceResponse struct {
 	Candidates []model.GooglePlace
 	Status     string `json:"status"`
@@ -88,7 +101,7 @@ func (c *GoogleApiClient) FindPlace(ctx context.Context, input string, fields []
 		"key":       c.apiKey,
 	}
 
-	params["language"] = "ru"
+	params["language"] = language
 
 	var result FindPlaceResponse
 
@@ -128,7 +141,7 @@ func (c *GoogleApiClient) GetPlaceByID(ctx context.Context, placeID string, fiel
 		"key":      c.apiKey,
 	}
 
-	params["language"] = "ru"
+	params["language"] = language
 
 	var result GetPlaceDataResponse
 
@@ -248,9 +261,9 @@ func isWithinRadius(lat1, lng1, lat2, lng2, radius float64) bool {
 	return distance <= radius
 }
 
-func (c *GoogleApiClient) GetPlacePhoto(ctx context.Context, reference string) ([]byte, error) {
+func (c *GoogleApiClient) GetPlacePhoto(ctx context.Context, reference string, maxWidth int) ([]byte, error) {
 	params := map[string]string{
-		"maxwidth":        "200",
+		"maxwidth":        strconv.Itoa(maxWidth),
 		"photo_reference": reference,
 		"key":             c.apiKey,
 	}
@@ -294,25 +307,125 @@ type DistanceMatrixResponse struct {
 	Status               string   `json:"status"`
 }
 
-func (c *GoogleApiClient) GetTimeDistanceMatrix(ctx context.Context, placeIDs []string) (model.DistanceMatrix, error) {
-	// Добавляем префикс "place_id:" к каждому идентификатору
-	var placeIDsQuery []string
-	for _, placeID := range placeIDs {
-		placeIDsQuery = append(placeIDsQuery, "place_id:"+placeID)
+// Limits of a single distance matrix request.
+const (
+	maxMatrixPlaces   = 25  // origins or destinations
+	maxMatrixElements = 100 // origins * destinations
+	// maxMatrixRequests limits concurrent requests of a single matrix.
+	maxMatrixRequests = 4
+)
+
+func (c *GoogleApiClient) GetTimeDistanceMatrix(ctx context.Context, placeIDs []string, mode string) (model.DistanceMatrix, error) {
+	return c.GetTimeDistanceMatrixBetween(ctx, placeIDs, placeIDs, mode)
+}
+
+// GetTimeDistanceMatrixBetween splits the places into requests within the api
+// limits, sends them concurrently and merges the results. Pairs without a route
+// are omitted. The first failed request fails the matrix, requests not sent by
+// then are dropped.
+func (c *GoogleApiClient) GetTimeDistanceMatrixBetween(
+	ctx context.Context,
+	origins []string,
+	destinations []string,
+	mode string,
+) (model.DistanceMatrix, error) {
+	result := make(model.DistanceMatrix, len(origins))
+	if len(origins) == 0 || len(destinations) == 0 {
+		return result, nil
 	}
 
-	fmt.Println("LEN: ", len(placeIDsQuery))
+	destinationsChunk := min(len(destinations), maxMatrixPlaces)
+	originsChunk := min(maxMatrixElements/destinationsChunk, maxMatrixPlaces)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	sem := make(chan struct{}, maxMatrixRequests)
+
+	fail := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+	}
 
-	placesParams := strings.Join(placeIDsQuery, "|")
+	for _, origins := range chunks(origins, originsChunk) {
+		for _, destinations := range chunks(destinations, destinationsChunk) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				select {
+				case sem <- struct{}{}:
+				case <-ctx.Done():
+					fail(ctx.Err())
+					return
+				}
+				defer func() { <-sem }()
+
+				if err := ctx.Err(); err != nil {
+					fail(err)
+					return
+				}
+
+				part, err := c.getTimeDistanceMatrix(ctx, origins, destinations, mode)
+				if err != nil {
+					fail(err)
+					return
+				}
+
+				mu.Lock()
+				defer mu.Unlock()
+				for origin, row := range part {
+					if result[origin] == nil {
+						result[origin] = make(map[string]map[string]float64, len(destinations))
+					}
+					for destination, metrics := range row {
+						result[origin][destination] = metrics
+					}
+				}
+			}()
+		}
+	}
+	wg.Wait()
 
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	return result, nil
+}
+
+func chunks(values []string, size int) [][]string {
+	var parts [][]string
+	for len(values) > size {
+		parts = append(parts, values[:size])
+		values = values[size:]
+	}
+	return append(parts, values)
+}
+
+func (c *GoogleApiClient) getTimeDistanceMatrix(
+	ctx context.Context,
+	origins []string,
+	destinations []string,
+	mode string,
+) (model.DistanceMatrix, error) {
 	params := map[string]string{
-		"origins":      placesParams,
-		"destinations": placesParams,
+		"origins":      placeIDsParam(origins),
+		"destinations": placeIDsParam(destinations),
+		"mode":         mode,
 		"key":          c.apiKey,
+		"language":     language,
 	}
 
-	params["language"] = "ru"
-
 	var result DistanceMatrixResponse
 
 	resp, err := c.client.R().
@@ -329,23 +442,52 @@ func (c *GoogleApiClient) GetTimeDistanceMatrix(ctx context.Context, placeIDs []
 		return nil, fmt.Errorf("error get time distance matrix: received status '%s'", resp.Status())
 	}
 
-	fmt.Println("GOOGLE result: ", result.Status)
+	if result.Status != "OK" {
+		return nil, fmt.Errorf("error get time distance matrix: received status '%s'", result.Status)
+	}
 
-	parsedMatrix := c.getParsedTimeDistance(placeIDs, result)
+	if len(result.Rows) != len(origins) {
+		return nil, fmt.Errorf("error get time distance matrix: got %d rows for %d origins", len(result.Rows), len(origins))
+	}
 
-	return parsedMatrix, nil
+	return c.getParsedTimeDistance(origins, destinations, result), nil
 }
 
-func (c *GoogleApiClient) getParsedTimeDistance(placeIDs []string, response DistanceMatrixResponse) model.DistanceMatrix {
-	result := make(map[string]map[string]map[string]float64)
+// placeIDsParam adds the "place_id:" prefix to every id.
+func placeIDsParam(placeIDs []string) string {
+	query := make([]string, len(placeIDs))
+	for i, placeID := range placeIDs {
+		query[i] = "place_id:" + placeID
+	}
+	return strings.Join(query, "|")
+}
 
-	for i, origin := range placeIDs {
-		result[origin] = make(map[string]map[string]float64)
+// getParsedTimeDistance keeps the pairs with a route, others
+// (ZERO_RESULTS, NOT_FOUND, ...) are left for the caller to estimate.
+func (c *GoogleApiClient) getParsedTimeDistance(
+	origins []string,
+	destinations []string,
+	response DistanceMatrixResponse,
+) model.DistanceMatrix {
+	result := make(model.DistanceMatrix, len(origins))
+
+	for i, origin := range origins {
+		result[origin] = make(map[string]map[string]float64, len(destinations))
+
+		elements := response.Rows[i].Elements
+		for j, destination := range destinations {
+			if j >= len(elements) {
+				break
+			}
+
+			if elements[j].Status != "OK" {
+				log.Printf("no route %s -> %s: %s", origin, destination, elements[j].Status)
+				continue
+			}
 
-		for j, destination := range placeIDs {
 			// Извлекаем значение расстояния в километрах и времени в минутах
-			distanceKm := float64(response.Rows[i].Elements[j].Distance.Value) / 1000.0
-			durationMin := float64(response.Rows[i].Elements[j].Duration.Value) / 60.0
+			distanceKm := float64(elements[j].Distance.Value) / 1000.0
+			durationMin := float64(elements[j].Duration.Value) / 60.0
 
 			result[origin][destination] = map[string]float64{
 				"distance": distanceKm,
@@ -357,25 +499,42 @@ func (c *GoogleApiClient) getParsedTimeDistance(placeIDs []string, response Dist
 	//Example:
 	//result[ChIJP-7oyAP00S0RtHCGoa9FgNM][ChIJP-7oyAP00S0RtHCGoa9FgNM] = { distance: 0.00 км, duration: 0.00 мин }
 	//result[ChIJP-7oyAP00S0RtHCGoa9FgNM][ChIJq95xT4I30i0RaU3j93Diq8o] = { distance: 73.32 км, duration: 135.45 мин }
-	//result[ChIJP-7oyAP00S0RtHCGoa9FgNM][ChIJ0Zo39DZH0i0R0acI0bADr2U] = { distance: 57.52 км, duration: 108.72 мин }
-	//result[ChIJq95xT4I30i0RaU3j93Diq8o][ChIJq95xT4I30i0RaU3j93Diq8o] = { distance: 0.00 км, duration: 0.00 мин }
-	//result[ChIJq95xT4I30i0RaU3j93Diq8o][ChIJ0Zo39DZH0i0R0acI0bADr2U] = { distance: 86.98 км, duration: 148.28 мин }
 	//result[ChIJq95xT4I30i0RaU3j93Diq8o][ChIJP-7oyAP00S0RtHCGoa9FgNM] = { distance: 72.75 км, duration: 134.07 мин }
-	//result[ChIJ0Zo39DZH0i0R0acI0bADr2U][ChIJP-7oyAP00S0RtHCGoa9FgNM] = { distance: 57.22 км, duration: 113.58 мин }
-	//result[ChIJ0Zo39DZH0i0R0acI0bADr2U][ChIJq95xT4I30i0RaU3j93Diq8o] = { distance: 84.93 км, duration: 158.73 мин }
-	//result[ChIJ0Zo39DZH0i0R0acI0bADr2U][ChIJ0Zo39DZH0i0R0acI0bADr2U] = { distance: 0.00 км, duration: 0.00 мин }
 
-	for origin, destinations := range result {
-		for destination, metrics := range destinations {
-			if origin == destination {
-				continue
-			}
-			fmt.Printf("result[%s][%s] = { distance: %.2f км, duration: %.2f мин }\n",
-				origin, destination, metrics["distance"], metrics["duration"])
-		}
+	return result
+}
+
+type TimeZoneResponse struct {
+	TimeZoneID   string `json:"timeZoneId"`
+	Status       string `json:"status"`
+	ErrorMessage string `json:"errorMessage"`
+}
+
+// GetTimeZone returns the IANA time zone (e.g. "Europe/Moscow") of the location.
+func (c *GoogleApiClient) GetTimeZone(ctx context.Context, lat float64, lng float64) (string, error) {
+	params := map[string]string{
+		"location":  fmt.Sprintf("%f,%f", lat, lng),
+		"timestamp": strconv.FormatInt(time.Now().Unix(), 10),
+		"key":       c.apiKey,
 	}
 
-	return result
+	var result TimeZoneResponse
+
+	_, err := c.client.R().
+		SetContext(ctx).
+		SetQueryParams(params).
+		SetResult(&result).
+		Get(methodGetTimeZone)
+
+	if err != nil {
+		return "", err
+	}
+
+	if result.Status != "OK" {
+		return "", fmt.Errorf("error: received status '%s'", result.Status)
+	}
+
+	return result.TimeZoneID, nil
 }
 
 func (c *GoogleApiClient) GetPlacesNearby(ctx context.Context,
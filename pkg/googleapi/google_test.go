@@ -0,0 +1,232 @@
+package googleapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// redirectTransport sends every request of the client to the test server.
+type redirectTransport struct {
+	target *url.URL
+}
+
+func (t redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *GoogleApiClient {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	target, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &GoogleApiClient{
+		client: resty.New().SetTransport(redirectTransport{target: target}),
+		apiKey: "key",
+	}
+}
+
+func testPlaceIDs(n int) []string {
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("p%02d", i)
+	}
+	return ids
+}
+
+func parsePlaceIDs(param string) []string {
+	ids := strings.Split(param, "|")
+	for i, id := range ids {
+		ids[i] = strings.TrimPrefix(id, "place_id:")
+	}
+	return ids
+}
+
+func placeIndex(id string) int {
+	i, _ := strconv.Atoi(strings.TrimPrefix(id, "p"))
+	return i
+}
+
+// matrixHandler answers like the distance matrix api with a minute and
+// a kilometre per index between places, p01 -> p02 has no route.
+func matrixHandler(t *testing.T, requests *atomic.Int32) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+
+		origins := parsePlaceIDs(r.URL.Query().Get("origins"))
+		destinations := parsePlaceIDs(r.URL.Query().Get("destinations"))
+		if len(origins) > maxMatrixPlaces || len(destinations) > maxMatrixPlaces {
+			t.Errorf("request of %d origins and %d destinations", len(origins), len(destinations))
+		}
+		if len(origins)*len(destinations) > maxMatrixElements {
+			t.Errorf("request of %d elements", len(origins)*len(destinations))
+		}
+
+		response := DistanceMatrixResponse{Status: "OK"}
+		for _, origin := range origins {
+			var row Row
+			for _, destination := range destinations {
+				var element Element
+				element.Status = "OK"
+				if origin == "p01" && destination == "p02" {
+					element.Status = "ZERO_RESULTS"
+				}
+				diff := placeIndex(destination) - placeIndex(origin)
+				if diff < 0 {
+					diff = -diff
+				}
+				element.Duration.Value = 60 * diff
+				element.Distance.Value = 1000 * diff
+				row.Elements = append(row.Elements, element)
+			}
+			response.Rows = append(response.Rows, row)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	}
+}
+
+func TestChunks(t *testing.T) {
+	tests := []struct {
+		name  string
+		size  int
+		n     int
+		sizes []int
+	}{
+		{name: "shorter than chunk", size: 25, n: 3, sizes: []int{3}},
+		{name: "exact chunks", size: 4, n: 8, sizes: []int{4, 4}},
+		{name: "last chunk is shorter", size: 4, n: 10, sizes: []int{4, 4, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids := testPlaceIDs(tt.n)
+
+			parts := chunks(ids, tt.size)
+
+			sizes := make([]int, len(parts))
+			for i, part := range parts {
+				sizes[i] = len(part)
+			}
+			if !slices.Equal(sizes, tt.sizes) {
+				t.Errorf("chunk sizes %v, want %v", sizes, tt.sizes)
+			}
+			if joined := slices.Concat(parts...); !slices.Equal(joined, ids) {
+				t.Errorf("chunks %v, want %v", joined, ids)
+			}
+		})
+	}
+}
+
+func TestGetTimeDistanceMatrix(t *testing.T) {
+	tests := []struct {
+		name     string
+		places   int
+		requests int32
+	}{
+		{name: "single request", places: 10, requests: 1},
+		{name: "split by elements", places: 20, requests: 4},
+		{name: "split by places and elements", places: 30, requests: 16},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			client := newTestClient(t, matrixHandler(t, &requests))
+			ids := testPlaceIDs(tt.places)
+
+			matrix, err := client.GetTimeDistanceMatrix(context.Background(), ids, "driving")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := requests.Load(); got != tt.requests {
+				t.Errorf("sent %d requests, want %d", got, tt.requests)
+			}
+			for _, origin := range ids {
+				for _, destination := range ids {
+					metrics, ok := matrix[origin][destination]
+					if origin == "p01" && destination == "p02" {
+						if ok {
+							t.Errorf("pair without a route is in the matrix: %v", metrics)
+						}
+						continue
+					}
+					if !ok {
+						t.Fatalf("pair %s -> %s is missing", origin, destination)
+					}
+
+					diff := float64(placeIndex(destination) - placeIndex(origin))
+					if diff < 0 {
+						diff = -diff
+					}
+					if metrics["duration"] != diff || metrics["distance"] != diff {
+						t.Errorf("pair %s -> %s is %v, want %v minutes and km", origin, destination, metrics, diff)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestGetTimeDistanceMatrixBetween(t *testing.T) {
+	var requests atomic.Int32
+	client := newTestClient(t, matrixHandler(t, &requests))
+	ids := testPlaceIDs(30)
+	origins, destinations := ids[:2], ids
+
+	matrix, err := client.GetTimeDistanceMatrixBetween(context.Background(), origins, destinations, "driving")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 2 rows of 30 places fit in 2 requests of 25 destinations
+	if got := requests.Load(); got != 2 {
+		t.Errorf("sent %d requests, want 2", got)
+	}
+	if len(matrix) != len(origins) {
+		t.Errorf("matrix has %d origins, want %d", len(matrix), len(origins))
+	}
+	if _, ok := matrix["p00"]["p29"]; !ok {
+		t.Error("pair p00 -> p29 is missing")
+	}
+	if _, ok := matrix["p29"]; ok {
+		t.Error("destination is routed as an origin")
+	}
+}
+
+func TestGetTimeDistanceMatrixStopsAfterError(t *testing.T) {
+	var requests atomic.Int32
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	// 16 requests, only those already sent may fail
+	_, err := client.GetTimeDistanceMatrix(context.Background(), testPlaceIDs(30), "driving")
+	if err == nil {
+		t.Fatal("no error for a failed request")
+	}
+
+	if got := requests.Load(); got > maxMatrixRequests {
+		t.Errorf("sent %d requests after the first error, want at most %d", got, maxMatrixRequests)
+	}
+}
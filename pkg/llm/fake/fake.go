@@ -0,0 +1,39 @@
+package fake
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/model"
+)
+
+// DefaultReplies are valid answers to the prompts of every use case, named
+// as the clients.LLMUse* values: an empty schedule, a chat reply without
+// places, a duration and attractions of the osm stand-in.
+var DefaultReplies = map[string]string{
+	"schedule": `[]`,
+	"chat":     `{"places": [], "message": "Это тестовый ответ планировщика"}`,
+	"duration": `60`,
+	"places":   `Красная площадь, ГУМ, Парк Горького`,
+}
+
+// Provider answers with fixed replies by model name, for local runs and tests
+// without a model.
+type Provider struct {
+	replies map[string]string
+}
+
+func NewProvider(replies map[string]string) *Provider {
+	return &Provider{
+		replies: replies,
+	}
+}
+
+func (p *Provider) Complete(ctx context.Context, modelName string, messages []model.ChatMessage) (string, error) {
+	reply, ok := p.replies[modelName]
+	if !ok {
+		return "", fmt.Errorf("no fake reply for model %q", modelName)
+	}
+
+	return reply, nil
+}
@@ -0,0 +1,98 @@
+// Package llm routes prompts of every use case to its model at the provider
+// chosen by the config: OpenAI, Ollama or a deterministic fake.
+package llm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/clients"
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/model"
+	"github.com/ShelbyKS/Roamly-backend/pkg/llm/fake"
+	"github.com/ShelbyKS/Roamly-backend/pkg/llm/ollama"
+	"github.com/ShelbyKS/Roamly-backend/pkg/llm/openai"
+)
+
+const (
+	TypeOpenAI = "openai"
+	TypeOllama = "ollama"
+	TypeFake   = "fake"
+)
+
+var useCases = []string{clients.LLMUseSchedule, clients.LLMUseChat, clients.LLMUseDuration, clients.LLMUsePlaces}
+
+type Provider interface {
+	// Complete returns the reply of the model to the conversation.
+	Complete(ctx context.Context, model string, messages []model.ChatMessage) (string, error)
+}
+
+type Config struct {
+	Type string
+
+	OpenAIURL string
+	OpenAIKey string
+	OllamaURL string
+
+	// Models maps the clients.LLMUse* use cases to model names at the
+	// provider, use cases without a model fall back to the chat model.
+	// The fake ignores them and answers by the use case.
+	Models map[string]string
+}
+
+type Client struct {
+	provider Provider
+	models   map[string]string
+}
+
+func NewClient(cfg Config) (clients.IChatClient, error) {
+	var provider Provider
+	switch cfg.Type {
+	case TypeOpenAI:
+		provider = openai.NewProvider(cfg.OpenAIURL, cfg.OpenAIKey)
+	case TypeOllama:
+		provider = ollama.NewProvider(cfg.OllamaURL)
+	case TypeFake:
+		return &Client{
+			provider: fake.NewProvider(fake.DefaultReplies),
+			models:   useCaseModels(),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown llm provider %q", cfg.Type)
+	}
+
+	chatModel := cfg.Models[clients.LLMUseChat]
+	if chatModel == "" {
+		return nil, fmt.Errorf("no llm model for %s", clients.LLMUseChat)
+	}
+
+	models := make(map[string]string, len(useCases))
+	for _, useCase := range useCases {
+		models[useCase] = cfg.Models[useCase]
+		if models[useCase] == "" {
+			models[useCase] = chatModel
+		}
+	}
+
+	return &Client{
+		provider: provider,
+		models:   models,
+	}, nil
+}
+
+func (c *Client) PostPrompt(ctx context.Context, messages []model.ChatMessage, useCase string) (string, error) {
+	modelName, ok := c.models[useCase]
+	if !ok {
+		return "", fmt.Errorf("unknown llm use case %q", useCase)
+	}
+
+	return c.provider.Complete(ctx, modelName, messages)
+}
+
+// useCaseModels names the models by the use cases, so the fake can tell them apart.
+func useCaseModels() map[string]string {
+	models := make(map[string]string, len(useCases))
+	for _, useCase := range useCases {
+		models[useCase] = useCase
+	}
+	return models
+}
@@ -0,0 +1,172 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/clients"
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/model"
+	"github.com/ShelbyKS/Roamly-backend/pkg/llm/fake"
+	"github.com/ShelbyKS/Roamly-backend/pkg/llm/ollama"
+)
+
+var prompt = []model.ChatMessage{{Role: model.RoleUser, Content: "Привет"}}
+
+// newOllamaServer answers every prompt with the name of the model it was sent to.
+func newOllamaServer(t *testing.T) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ollama.Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(ollama.Response{
+			Model:   req.Model,
+			Message: ollama.Message{Role: "assistant", Content: req.Model},
+			Done:    true,
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	return server.URL
+}
+
+func TestUseCaseRouting(t *testing.T) {
+	tests := []struct {
+		name   string
+		models map[string]string
+		want   map[string]string
+	}{
+		{
+			name: "model of every use case",
+			models: map[string]string{
+				clients.LLMUseSchedule: "llama3.1:70b",
+				clients.LLMUseChat:     "llama3.1",
+				clients.LLMUseDuration: "qwen2.5:3b",
+				clients.LLMUsePlaces:   "qwen2.5:7b",
+			},
+			want: map[string]string{
+				clients.LLMUseSchedule: "llama3.1:70b",
+				clients.LLMUseChat:     "llama3.1",
+				clients.LLMUseDuration: "qwen2.5:3b",
+				clients.LLMUsePlaces:   "qwen2.5:7b",
+			},
+		},
+		{
+			name: "unconfigured use cases fall back to the chat model",
+			models: map[string]string{
+				clients.LLMUseChat:     "llama3.1",
+				clients.LLMUseDuration: "",
+				clients.LLMUsePlaces:   "qwen2.5:7b",
+			},
+			want: map[string]string{
+				clients.LLMUseSchedule: "llama3.1",
+				clients.LLMUseChat:     "llama3.1",
+				clients.LLMUseDuration: "llama3.1",
+				clients.LLMUsePlaces:   "qwen2.5:7b",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewClient(Config{Type: TypeOllama, OllamaURL: newOllamaServer(t), Models: tt.models})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for useCase, want := range tt.want {
+				got, err := client.PostPrompt(context.Background(), prompt, useCase)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != want {
+					t.Errorf("%s is served by %q, want %q", useCase, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestUnknownUseCase(t *testing.T) {
+	client, err := NewClient(Config{
+		Type:      TypeOllama,
+		OllamaURL: newOllamaServer(t),
+		Models:    map[string]string{clients.LLMUseChat: "llama3.1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.PostPrompt(context.Background(), prompt, "translate"); err == nil {
+		t.Error("no error for an unknown use case")
+	}
+}
+
+func TestNewClientErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{
+			name: "unknown provider",
+			cfg:  Config{Type: "anthropic", Models: map[string]string{clients.LLMUseChat: "model"}},
+		},
+		{
+			name: "no chat model to fall back to",
+			cfg:  Config{Type: TypeOpenAI, Models: map[string]string{clients.LLMUseSchedule: "gpt-4o"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewClient(tt.cfg); err == nil {
+				t.Error("no error")
+			}
+		})
+	}
+}
+
+func TestFakeClient(t *testing.T) {
+	// models are not needed by the fake
+	client, err := NewClient(Config{Type: TypeFake})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, useCase := range useCases {
+		got, err := client.PostPrompt(context.Background(), prompt, useCase)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := fake.DefaultReplies[useCase]; got != want {
+			t.Errorf("%s reply %q, want %q", useCase, got, want)
+		}
+
+		// deterministic for the same prompt
+		again, _ := client.PostPrompt(context.Background(), prompt, useCase)
+		if again != got {
+			t.Errorf("%s replies %q and then %q", useCase, got, again)
+		}
+	}
+}
+
+func TestFakeProvider(t *testing.T) {
+	provider := fake.NewProvider(map[string]string{"duration": "90"})
+
+	got, err := provider.Complete(context.Background(), "duration", prompt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "90" {
+		t.Errorf("reply %q, want %q", got, "90")
+	}
+
+	if _, err := provider.Complete(context.Background(), "chat", prompt); err == nil {
+		t.Error("no error for a model without a reply")
+	}
+}
@@ -0,0 +1,74 @@
+package ollama
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/model"
+)
+
+const methodChat = "/api/chat"
+
+// Provider talks to a local ollama server, the models must be pulled there.
+type Provider struct {
+	client *resty.Client
+	url    string
+}
+
+func NewProvider(url string) *Provider {
+	return &Provider{
+		client: resty.New(),
+		url:    strings.TrimRight(url, "/"),
+	}
+}
+
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type Request struct {
+	Model    string    `json:"model"`
+	Messages []Message `json:"messages"`
+	Stream   bool      `json:"stream"`
+}
+
+type Response struct {
+	Model   string  `json:"model"`
+	Message Message `json:"message"`
+	Done    bool    `json:"done"`
+	Error   string  `json:"error"`
+}
+
+func (p *Provider) Complete(ctx context.Context, modelName string, messages []model.ChatMessage) (string, error) {
+	req := Request{
+		Model:    modelName,
+		Messages: make([]Message, len(messages)),
+		// the whole reply in a single response
+		Stream: false,
+	}
+	for i, message := range messages {
+		req.Messages[i] = Message{Role: message.Role, Content: message.Content}
+	}
+
+	var resp Response
+	res, err := p.client.R().
+		SetContext(ctx).
+		SetBody(req).
+		SetResult(&resp).
+		ForceContentType("application/json").
+		SetError(&resp).
+		Post(p.url + methodChat)
+	if err != nil {
+		return "", fmt.Errorf("failed to post ollama prompt: %w", err)
+	}
+
+	if res.IsError() || !resp.Done {
+		return "", fmt.Errorf("invalid response from ollama: status '%s', error '%s'", res.Status(), resp.Error)
+	}
+
+	return resp.Message.Content, nil
+}
@@ -0,0 +1,79 @@
+package openai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/model"
+)
+
+const (
+	methodChatCompletions = "/chat/completions"
+
+	temperature = 0.7
+)
+
+// Provider talks to the openai chat completions api or a compatible one.
+type Provider struct {
+	client *resty.Client
+	url    string
+	apiKey string
+}
+
+func NewProvider(url, apiKey string) *Provider {
+	return &Provider{
+		client: resty.New(),
+		url:    strings.TrimRight(url, "/"),
+		apiKey: apiKey,
+	}
+}
+
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type Request struct {
+	Model       string    `json:"model"`
+	Messages    []Message `json:"messages"`
+	Temperature float64   `json:"temperature"`
+}
+
+type Response struct {
+	Choices []struct {
+		Message Message `json:"message"`
+	} `json:"choices"`
+}
+
+func (p *Provider) Complete(ctx context.Context, modelName string, messages []model.ChatMessage) (string, error) {
+	req := Request{
+		Model:       modelName,
+		Messages:    make([]Message, len(messages)),
+		Temperature: temperature,
+	}
+	for i, message := range messages {
+		req.Messages[i] = Message{Role: message.Role, Content: message.Content}
+	}
+
+	var resp Response
+	res, err := p.client.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetAuthToken(p.apiKey).
+		SetBody(req).
+		SetResult(&resp).
+		ForceContentType("application/json").
+		Post(p.url + methodChatCompletions)
+	if err != nil {
+		return "", fmt.Errorf("failed to post openai prompt: %w", err)
+	}
+
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("invalid response from openai: %s", res.Body())
+	}
+
+	return resp.Choices[0].Message.Content, nil
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/ShelbyKS/Roamly-backend/pkg/osm/osmtest"
+)
+
+// main serves the osmtest places, use it as NOMINATIM_URL and OSRM_URL.
+func main() {
+	serverAddress := ":8088"
+
+	// Start the server
+	err := http.ListenAndServe(serverAddress, osmtest.Handler())
+	if err != nil {
+		panic(err)
+	}
+}
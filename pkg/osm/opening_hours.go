@@ -0,0 +1,175 @@
+package osm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/model"
+)
+
+var weekdays = map[string]time.Weekday{
+	"Su": time.Sunday,
+	"Mo": time.Monday,
+	"Tu": time.Tuesday,
+	"We": time.Wednesday,
+	"Th": time.Thursday,
+	"Fr": time.Friday,
+	"Sa": time.Saturday,
+}
+
+// ParseOpeningHours converts the common subset of the osm opening_hours
+// syntax ("24/7", "Mo-Fr 09:00-18:00; Sa 10:00-14:00,15:00-17:00; Su off")
+// to google periods. Rules it doesn't understand (holidays, months,
+// sunrise, ...) are skipped, later rules override earlier ones for their
+// days as in osm. Returns nil if nothing was understood, so the hours
+// stay unknown.
+func ParseOpeningHours(value string) *model.GoogleOpeningHours {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+
+	if value == "24/7" {
+		// a single period without close, as google marks always open places
+		return &model.GoogleOpeningHours{
+			Periods: []model.OpeningPeriod{{Open: model.OpeningPoint{Day: int(time.Sunday), Time: "0000"}}},
+		}
+	}
+
+	var (
+		days   [7][][2]string
+		parsed bool
+	)
+	for _, rule := range strings.Split(value, ";") {
+		ruleDays, spans, ok := parseRule(strings.TrimSpace(rule))
+		if !ok {
+			continue
+		}
+		parsed = true
+		for _, day := range ruleDays {
+			days[day] = spans
+		}
+	}
+
+	if !parsed {
+		return nil
+	}
+
+	hours := &model.GoogleOpeningHours{Periods: []model.OpeningPeriod{}}
+	for day, spans := range days {
+		for _, span := range spans {
+			closeDay, closeTime := closing(day, span[0], span[1])
+
+			hours.Periods = append(hours.Periods, model.OpeningPeriod{
+				Open:  model.OpeningPoint{Day: day, Time: span[0]},
+				Close: &model.OpeningPoint{Day: closeDay, Time: closeTime},
+			})
+		}
+	}
+
+	return hours
+}
+
+// closing returns the close day and time of a span opened on the day.
+// Closes past midnight are on the next day, either written as "22:00-02:00"
+// or, as osm allows, as "22:00-26:00".
+func closing(day int, openTime, closeTime string) (int, string) {
+	hours, _ := strconv.Atoi(closeTime[:2])
+	closeDay := (day + hours/24) % 7
+	closeTime = fmt.Sprintf("%02d%s", hours%24, closeTime[2:])
+
+	if hours < 24 && closeTime <= openTime {
+		closeDay = (day + 1) % 7
+	}
+
+	return closeDay, closeTime
+}
+
+// parseRule parses "<days> <times>", the days default to the whole week.
+// Times are "hh:mm-hh:mm" spans separated by commas or "off".
+func parseRule(rule string) ([]time.Weekday, [][2]string, bool) {
+	if rule == "" {
+		return nil, nil, false
+	}
+
+	days := allDays()
+	timesPart := rule
+	if daysPart, rest, found := strings.Cut(rule, " "); found {
+		var ok bool
+		days, ok = parseDays(daysPart)
+		if !ok {
+			return nil, nil, false
+		}
+		timesPart = strings.TrimSpace(rest)
+	}
+
+	if timesPart == "off" || timesPart == "closed" {
+		return days, nil, true
+	}
+
+	var spans [][2]string
+	for _, span := range strings.Split(timesPart, ",") {
+		open, closing, ok := strings.Cut(strings.TrimSpace(span), "-")
+		if !ok || !isClock(open) || !isClock(closing) {
+			return nil, nil, false
+		}
+		spans = append(spans, [2]string{strings.Replace(open, ":", "", 1), strings.Replace(closing, ":", "", 1)})
+	}
+
+	return days, spans, true
+}
+
+// parseDays parses weekdays and their ranges separated by commas, e.g. "Mo-Fr,Su".
+func parseDays(value string) ([]time.Weekday, bool) {
+	var days []time.Weekday
+	for _, part := range strings.Split(value, ",") {
+		from, to, isRange := strings.Cut(part, "-")
+
+		first, ok := weekdays[from]
+		if !ok {
+			return nil, false
+		}
+		if !isRange {
+			days = append(days, first)
+			continue
+		}
+
+		last, ok := weekdays[to]
+		if !ok {
+			return nil, false
+		}
+		// ranges can wrap the week, e.g. "Fr-Mo"
+		for day := first; ; day = (day + 1) % 7 {
+			days = append(days, day)
+			if day == last {
+				break
+			}
+		}
+	}
+
+	return days, true
+}
+
+func allDays() []time.Weekday {
+	days := make([]time.Weekday, 0, 7)
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		days = append(days, day)
+	}
+	return days
+}
+
+// isClock checks the "hh:mm" format, hours up to 48 are allowed by osm for
+// places closing after midnight.
+func isClock(value string) bool {
+	if len(value) != 5 || value[2] != ':' {
+		return false
+	}
+	for i, r := range value {
+		if i != 2 && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return value <= "48:00" && value[3] <= '5'
+}
@@ -0,0 +1,122 @@
+package osm
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/model"
+)
+
+func period(openDay int, open string, closeDay int, close string) model.OpeningPeriod {
+	return model.OpeningPeriod{
+		Open:  model.OpeningPoint{Day: openDay, Time: open},
+		Close: &model.OpeningPoint{Day: closeDay, Time: close},
+	}
+}
+
+func TestParseOpeningHours(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		periods []model.OpeningPeriod
+	}{
+		{
+			name:    "always open",
+			value:   "24/7",
+			periods: []model.OpeningPeriod{{Open: model.OpeningPoint{Day: 0, Time: "0000"}}},
+		},
+		{
+			name:  "day range",
+			value: "Mo-We 09:00-18:00",
+			periods: []model.OpeningPeriod{
+				period(1, "0900", 1, "1800"),
+				period(2, "0900", 2, "1800"),
+				period(3, "0900", 3, "1800"),
+			},
+		},
+		{
+			name:  "days without a range",
+			value: "10:00-12:00",
+			periods: []model.OpeningPeriod{
+				period(0, "1000", 0, "1200"),
+				period(1, "1000", 1, "1200"),
+				period(2, "1000", 2, "1200"),
+				period(3, "1000", 3, "1200"),
+				period(4, "1000", 4, "1200"),
+				period(5, "1000", 5, "1200"),
+				period(6, "1000", 6, "1200"),
+			},
+		},
+		{
+			name:  "several spans and days off",
+			value: "Sa 10:00-14:00,15:00-17:00; Su off",
+			periods: []model.OpeningPeriod{
+				period(6, "1000", 6, "1400"),
+				period(6, "1500", 6, "1700"),
+			},
+		},
+		{
+			name:  "later rules override earlier ones",
+			value: "Mo-Tu 09:00-18:00; Tu 12:00-13:00",
+			periods: []model.OpeningPeriod{
+				period(1, "0900", 1, "1800"),
+				period(2, "1200", 2, "1300"),
+			},
+		},
+		{
+			name:  "range wraps the week",
+			value: "Sa-Mo 12:00-13:00",
+			periods: []model.OpeningPeriod{
+				period(0, "1200", 0, "1300"),
+				period(1, "1200", 1, "1300"),
+				period(6, "1200", 6, "1300"),
+			},
+		},
+		{
+			name:    "closes after midnight",
+			value:   "Fr 22:00-02:00",
+			periods: []model.OpeningPeriod{period(5, "2200", 6, "0200")},
+		},
+		{
+			name:    "closes at midnight",
+			value:   "Sa 18:00-24:00",
+			periods: []model.OpeningPeriod{period(6, "1800", 0, "0000")},
+		},
+		{
+			name:    "closes past 24:00",
+			value:   "Sa 18:00-26:30",
+			periods: []model.OpeningPeriod{period(6, "1800", 0, "0230")},
+		},
+		{
+			name:    "unknown rules are skipped",
+			value:   "PH off; Mo 09:00-10:00; Tu sunrise-sunset",
+			periods: []model.OpeningPeriod{period(1, "0900", 1, "1000")},
+		},
+		{
+			name:    "only closed days",
+			value:   "Mo-Su off",
+			periods: []model.OpeningPeriod{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hours := ParseOpeningHours(tt.value)
+			if hours == nil {
+				t.Fatal("hours are unknown")
+			}
+
+			if !reflect.DeepEqual(hours.Periods, tt.periods) {
+				t.Errorf("periods %+v, want %+v", hours.Periods, tt.periods)
+			}
+		})
+	}
+}
+
+func TestParseOpeningHoursUnknown(t *testing.T) {
+	for _, value := range []string{"", "  ", "sunrise-sunset", "Mo 9-18", "Jan-Mar 10:00-12:00", "Mo 10:00-49:00"} {
+		if hours := ParseOpeningHours(value); hours != nil {
+			t.Errorf("hours of %q are %+v, want unknown", value, hours)
+		}
+	}
+}
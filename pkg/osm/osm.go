@@ -0,0 +1,446 @@
+package osm
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/clients"
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/model"
+)
+
+const (
+	methodSearch = "/search"
+	methodLookup = "/lookup"
+	methodTable  = "/table/v1/"
+
+	// language of place names and addresses in responses
+	language = "ru"
+
+	// maxLookupIDs is the limit of places in a single nominatim lookup.
+	maxLookupIDs = 50
+	// maxTableChunk keeps origins plus destinations of a single osrm
+	// table request within the default limit of 100 coordinates.
+	maxTableChunk = 50
+	// maxFindResults limits candidates of a text search.
+	maxFindResults = 10
+)
+
+// travelProfiles maps travel modes to osrm profiles. Transit has no
+// profile, such pairs are left for the caller to estimate.
+var travelProfiles = map[string]string{
+	model.TravelModeDriving:   "driving",
+	model.TravelModeWalking:   "foot",
+	model.TravelModeBicycling: "bike",
+}
+
+// searchPhrases maps google place types to nominatim special phrases
+// where they differ, other types are searched with "_" replaced by spaces.
+var searchPhrases = map[string]string{
+	"tourist_attraction": "attraction",
+	"amusement_park":     "theme park",
+	"art_gallery":        "gallery",
+	"shopping_mall":      "mall",
+	"night_club":         "nightclub",
+	"place_of_worship":   "place of worship",
+}
+
+// Place is a nominatim search or lookup result in the jsonv2 format.
+type Place struct {
+	OsmType     string            `json:"osm_type"`
+	OsmID       int64             `json:"osm_id"`
+	Lat         string            `json:"lat"`
+	Lon         string            `json:"lon"`
+	Category    string            `json:"category"`
+	Type        string            `json:"type"`
+	Name        string            `json:"name"`
+	DisplayName string            `json:"display_name"`
+	ExtraTags   map[string]string `json:"extratags"`
+}
+
+// TableResponse is an osrm table result, unreachable pairs are null.
+type TableResponse struct {
+	Code      string       `json:"code"`
+	Message   string       `json:"message"`
+	Durations [][]*float64 `json:"durations"`
+	Distances [][]*float64 `json:"distances"`
+}
+
+// Client implements clients.IGoogleApiClient by nominatim compatible search
+// and osrm compatible routing, so places can be found without a google key.
+// Place ids are osm types and ids, e.g. "N123" for the node 123.
+type Client struct {
+	client       *resty.Client
+	nominatimURL string
+	osrmURL      string
+}
+
+// NewClient creates a client of the services at the base urls. The user
+// agent identifies the application as required by the nominatim usage policy.
+func NewClient(nominatimURL string, osrmURL string, userAgent string) *Client {
+	return &Client{
+		client:       resty.New().SetHeader("User-Agent", userAgent),
+		nominatimURL: strings.TrimRight(nominatimURL, "/"),
+		osrmURL:      strings.TrimRight(osrmURL, "/"),
+	}
+}
+
+func (c *Client) Provider() string {
+	return model.PlaceProviderOSM
+}
+
+// FindPlace searches places by text. Fields are ignored: nominatim
+// returns all known data at once.
+func (c *Client) FindPlace(ctx context.Context, input string, fields []string) ([]model.GooglePlace, error) {
+	places, err := c.search(ctx, map[string]string{
+		"q":     input,
+		"limit": strconv.Itoa(maxFindResults),
+	}, language)
+	if err != nil {
+		return nil, err
+	}
+
+	return toGooglePlaces(places), nil
+}
+
+// GetPlaceByID returns the place by its osm id, fields are ignored as in FindPlace.
+func (c *Client) GetPlaceByID(ctx context.Context, id string, fields []string) (model.GooglePlace, error) {
+	places, err := c.lookup(ctx, []string{id})
+	if err != nil {
+		return model.GooglePlace{}, err
+	}
+
+	if len(places) == 0 {
+		return model.GooglePlace{}, fmt.Errorf("error: place '%s' not found", id)
+	}
+
+	return places[0].toGooglePlace(), nil
+}
+
+func (c *Client) GetTimeDistanceMatrix(ctx context.Context, placeIDs []string, mode string) (model.DistanceMatrix, error) {
+	return c.GetTimeDistanceMatrixBetween(ctx, placeIDs, placeIDs, mode)
+}
+
+// GetTimeDistanceMatrixBetween looks up coordinates of the places and routes
+// them by the osrm table service. Pairs without a route, places that were not
+// found and the transit mode are omitted.
+func (c *Client) GetTimeDistanceMatrixBetween(
+	ctx context.Context,
+	origins []string,
+	destinations []string,
+	mode string,
+) (model.DistanceMatrix, error) {
+	result := make(model.DistanceMatrix, len(origins))
+
+	profile, ok := travelProfiles[mode]
+	if !ok || len(origins) == 0 || len(destinations) == 0 {
+		return result, nil
+	}
+
+	placeIDs := slices.Clone(origins)
+	for _, id := range destinations {
+		if !slices.Contains(origins, id) {
+			placeIDs = append(placeIDs, id)
+		}
+	}
+
+	locations := make(map[string]model.GooglePlace, len(placeIDs))
+	for _, ids := range chunks(placeIDs, maxLookupIDs) {
+		places, err := c.lookup(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		for _, place := range toGooglePlaces(places) {
+			locations[place.PlaceID] = place
+		}
+	}
+
+	originPlaces, destinationPlaces := placesByIDs(locations, origins), placesByIDs(locations, destinations)
+	if len(originPlaces) == 0 || len(destinationPlaces) == 0 {
+		return result, nil
+	}
+
+	for _, originsChunk := range chunks(originPlaces, maxTableChunk) {
+		for _, destinationsChunk := range chunks(destinationPlaces, maxTableChunk) {
+			err := c.table(ctx, profile, originsChunk, destinationsChunk, result)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return result, nil
+}
+
+func placesByIDs(places map[string]model.GooglePlace, ids []string) []model.GooglePlace {
+	var result []model.GooglePlace
+	for _, id := range ids {
+		if place, ok := places[id]; ok {
+			result = append(result, place)
+		}
+	}
+	return result
+}
+
+// GetTimeZone always returns clients.ErrNoTimeZone: osm services have no
+// time zone lookup and a zone guessed by the longitude is wrong across
+// borders and in summer. Opening hours of osm places are in the local time
+// of the place, so the zone of osm trips must be given on trip creation.
+// Trips without it are planned in UTC and the hours are off by the offset.
+func (c *Client) GetTimeZone(ctx context.Context, lat float64, lng float64) (string, error) {
+	return "", clients.ErrNoTimeZone
+}
+
+// GetPlacesNearby searches every type within the circle and merges the results.
+// "DISTANCE" rank preference sorts them by distance, otherwise nominatim
+// importance order is kept.
+func (c *Client) GetPlacesNearby(ctx context.Context,
+	includedTypes []string,
+	maxPlaces int,
+	rankPrefernce string,
+	lat float64,
+	lng float64,
+	radius float64,
+	languageCode string) ([]model.GooglePlace, error) {
+	if languageCode == "" {
+		languageCode = language
+	}
+
+	dLat := radius / metersPerDegree
+	dLng := radius / (metersPerDegree * math.Cos(lat*math.Pi/180))
+	viewbox := fmt.Sprintf("%f,%f,%f,%f", lng-dLng, lat+dLat, lng+dLng, lat-dLat)
+
+	var result []model.GooglePlace
+	seen := make(map[string]bool)
+	for _, placeType := range includedTypes {
+		phrase, ok := searchPhrases[placeType]
+		if !ok {
+			phrase = strings.ReplaceAll(placeType, "_", " ")
+		}
+
+		places, err := c.search(ctx, map[string]string{
+			"q":       phrase,
+			"viewbox": viewbox,
+			"bounded": "1",
+			"limit":   strconv.Itoa(maxPlaces),
+		}, languageCode)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, place := range toGooglePlaces(places) {
+			location := place.Geometry.Location
+			if seen[place.PlaceID] || distanceMeters(lat, lng, location.Lat, location.Lng) > radius {
+				continue
+			}
+			seen[place.PlaceID] = true
+			if !slices.Contains(place.Types, placeType) {
+				place.Types = append([]string{placeType}, place.Types...)
+			}
+			result = append(result, place)
+		}
+	}
+
+	if rankPrefernce == "DISTANCE" {
+		sort.SliceStable(result, func(i, j int) bool {
+			a, b := result[i].Geometry.Location, result[j].Geometry.Location
+			return distanceMeters(lat, lng, a.Lat, a.Lng) < distanceMeters(lat, lng, b.Lat, b.Lng)
+		})
+	}
+
+	if len(result) > maxPlaces {
+		result = result[:maxPlaces]
+	}
+
+	return result, nil
+}
+
+func (c *Client) search(ctx context.Context, params map[string]string, languageCode string) ([]Place, error) {
+	params["format"] = "jsonv2"
+	params["extratags"] = "1"
+	params["accept-language"] = languageCode
+
+	var result []Place
+
+	resp, err := c.client.R().
+		SetContext(ctx).
+		SetQueryParams(params).
+		SetResult(&result).
+		Get(c.nominatimURL + methodSearch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call nominatim search: %w", err)
+	}
+
+	if resp.StatusCode() != http.StatusOK {
+		return nil, fmt.Errorf("error search places: received status '%s'", resp.Status())
+	}
+
+	return result, nil
+}
+
+func (c *Client) lookup(ctx context.Context, ids []string) ([]Place, error) {
+	params := map[string]string{
+		"osm_ids":         strings.Join(ids, ","),
+		"format":          "jsonv2",
+		"extratags":       "1",
+		"accept-language": language,
+	}
+
+	var result []Place
+
+	resp, err := c.client.R().
+		SetContext(ctx).
+		SetQueryParams(params).
+		SetResult(&result).
+		Get(c.nominatimURL + methodLookup)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call nominatim lookup: %w", err)
+	}
+
+	if resp.StatusCode() != http.StatusOK {
+		return nil, fmt.Errorf("error lookup places: received status '%s'", resp.Status())
+	}
+
+	return result, nil
+}
+
+// table routes the origins to the destinations and adds the pairs with a route to the result.
+func (c *Client) table(
+	ctx context.Context,
+	profile string,
+	origins []model.GooglePlace,
+	destinations []model.GooglePlace,
+	result model.DistanceMatrix,
+) error {
+	coordinates := make([]string, 0, len(origins)+len(destinations))
+	sources := make([]string, 0, len(origins))
+	targets := make([]string, 0, len(destinations))
+	for _, place := range origins {
+		sources = append(sources, strconv.Itoa(len(coordinates)))
+		coordinates = append(coordinates, coordinate(place))
+	}
+	for _, place := range destinations {
+		targets = append(targets, strconv.Itoa(len(coordinates)))
+		coordinates = append(coordinates, coordinate(place))
+	}
+
+	params := map[string]string{
+		"sources":      strings.Join(sources, ";"),
+		"destinations": strings.Join(targets, ";"),
+		"annotations":  "duration,distance",
+	}
+
+	var table TableResponse
+
+	resp, err := c.client.R().
+		SetContext(ctx).
+		SetQueryParams(params).
+		SetResult(&table).
+		Get(c.osrmURL + methodTable + profile + "/" + strings.Join(coordinates, ";"))
+	if err != nil {
+		return fmt.Errorf("failed to call osrm table: %w", err)
+	}
+
+	if resp.StatusCode() != http.StatusOK || table.Code != "Ok" {
+		return fmt.Errorf("error get time distance matrix: received status '%s' %s", resp.Status(), table.Message)
+	}
+
+	if len(table.Durations) != len(origins) || len(table.Distances) != len(origins) {
+		return fmt.Errorf("error get time distance matrix: got %d rows for %d origins", len(table.Durations), len(origins))
+	}
+
+	for i, origin := range origins {
+		if result[origin.PlaceID] == nil {
+			result[origin.PlaceID] = make(map[string]map[string]float64)
+		}
+
+		for j, destination := range destinations {
+			if j >= len(table.Durations[i]) || j >= len(table.Distances[i]) {
+				break
+			}
+
+			duration, distance := table.Durations[i][j], table.Distances[i][j]
+			if duration == nil || distance == nil {
+				continue
+			}
+
+			result[origin.PlaceID][destination.PlaceID] = map[string]float64{
+				"distance": *distance / 1000.0,
+				"duration": *duration / 60.0,
+			}
+		}
+	}
+
+	return nil
+}
+
+func (p Place) toGooglePlace() model.GooglePlace {
+	lat, _ := strconv.ParseFloat(p.Lat, 64)
+	lng, _ := strconv.ParseFloat(p.Lon, 64)
+
+	name := p.Name
+	if name == "" {
+		name, _, _ = strings.Cut(p.DisplayName, ",")
+	}
+
+	return model.GooglePlace{
+		PlaceID:          p.ID(),
+		Name:             name,
+		FormattedAddress: p.DisplayName,
+		Vicinity:         p.DisplayName,
+		Geometry:         model.Geometry{Location: model.Location{Lat: lat, Lng: lng}},
+		Types:            []string{p.Type, p.Category},
+		EditorialSummary: p.ExtraTags["description"],
+		OpeningHours:     ParseOpeningHours(p.ExtraTags["opening_hours"]),
+	}
+}
+
+// ID is the place id accepted by the nominatim lookup, e.g. "N123".
+func (p Place) ID() string {
+	if p.OsmType == "" {
+		return ""
+	}
+	return strings.ToUpper(p.OsmType[:1]) + strconv.FormatInt(p.OsmID, 10)
+}
+
+func toGooglePlaces(places []Place) []model.GooglePlace {
+	result := make([]model.GooglePlace, 0, len(places))
+	for _, place := range places {
+		result = append(result, place.toGooglePlace())
+	}
+	return result
+}
+
+func coordinate(place model.GooglePlace) string {
+	return fmt.Sprintf("%f,%f", place.Geometry.Location.Lng, place.Geometry.Location.Lat)
+}
+
+func chunks[T any](values []T, size int) [][]T {
+	var parts [][]T
+	for len(values) > size {
+		parts = append(parts, values[:size])
+		values = values[size:]
+	}
+	return append(parts, values)
+}
+
+const (
+	metersPerDegree = 111320.0
+	earthRadius     = 6371000.0
+)
+
+func distanceMeters(lat1, lng1, lat2, lng2 float64) float64 {
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLng := (lng2 - lng1) * rad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(a))
+}
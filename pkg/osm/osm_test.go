@@ -0,0 +1,295 @@
+package osm
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/model"
+	"github.com/ShelbyKS/Roamly-backend/pkg/osm/osmtest"
+)
+
+const (
+	redSquare = "W4401458"
+	gum       = "W26609466"
+	museum    = "N2364917318"
+	park      = "R2903813"
+	monastery = "W125537034"
+)
+
+// newTestClient routes the client to the osmtest places and counts requests by path.
+func newTestClient(t *testing.T) (*Client, func(path string) int) {
+	var (
+		mu       sync.Mutex
+		requests = make(map[string]int)
+	)
+	handler := osmtest.Handler()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("User-Agent") != "roamly-test" {
+			t.Errorf("request without the user agent")
+		}
+
+		path := r.URL.Path
+		if strings.HasPrefix(path, methodTable) {
+			path = methodTable
+		}
+		mu.Lock()
+		requests[path]++
+		mu.Unlock()
+
+		handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	count := func(path string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return requests[path]
+	}
+
+	return NewClient(server.URL+"/", server.URL, "roamly-test"), count
+}
+
+func placeIDs(places []model.GooglePlace) []string {
+	ids := make([]string, len(places))
+	for i, place := range places {
+		ids[i] = place.PlaceID
+	}
+	return ids
+}
+
+func TestFindPlace(t *testing.T) {
+	client, requests := newTestClient(t)
+
+	places, err := client.FindPlace(context.Background(), "гум", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if requests(methodSearch) != 1 {
+		t.Errorf("sent %d search requests, want 1", requests(methodSearch))
+	}
+	if ids := placeIDs(places); !slices.Equal(ids, []string{gum}) {
+		t.Fatalf("found %v, want %v", ids, []string{gum})
+	}
+
+	place := places[0]
+	if place.Name != "ГУМ" {
+		t.Errorf("name %q, want %q", place.Name, "ГУМ")
+	}
+	if location := place.Geometry.Location; location.Lat != 55.7547 || location.Lng != 37.6215 {
+		t.Errorf("location %+v, want 55.7547, 37.6215", location)
+	}
+	if !slices.Contains(place.Types, "mall") {
+		t.Errorf("types %v have no mall", place.Types)
+	}
+	if place.OpeningHours == nil || len(place.OpeningHours.Periods) != 7 {
+		t.Errorf("opening hours %+v, want a period a day", place.OpeningHours)
+	}
+}
+
+func TestGetPlaceByID(t *testing.T) {
+	client, _ := newTestClient(t)
+
+	place, err := client.GetPlaceByID(context.Background(), museum, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if place.PlaceID != museum {
+		t.Errorf("place %s, want %s", place.PlaceID, museum)
+	}
+
+	_, err = client.GetPlaceByID(context.Background(), "N1", nil)
+	if err == nil {
+		t.Error("no error for an unknown place")
+	}
+}
+
+func TestGetPlacesNearby(t *testing.T) {
+	// the museum is 1.2 km from the red square, the gum is next to it
+	tests := []struct {
+		name      string
+		types     []string
+		maxPlaces int
+		rank      string
+		radius    float64
+		places    []string
+	}{
+		{
+			name:      "places outside the radius are skipped",
+			types:     []string{"museum", "shopping_mall"},
+			maxPlaces: 10,
+			radius:    1000,
+			places:    []string{gum},
+		},
+		{
+			name:      "search order by default",
+			types:     []string{"museum", "shopping_mall"},
+			maxPlaces: 10,
+			radius:    3000,
+			places:    []string{museum, gum},
+		},
+		{
+			name:      "closest first",
+			types:     []string{"museum", "shopping_mall"},
+			maxPlaces: 10,
+			rank:      "DISTANCE",
+			radius:    3000,
+			places:    []string{gum, museum},
+		},
+		{
+			name:      "limited number of places",
+			types:     []string{"museum", "shopping_mall"},
+			maxPlaces: 1,
+			rank:      "DISTANCE",
+			radius:    3000,
+			places:    []string{gum},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, requests := newTestClient(t)
+
+			places, err := client.GetPlacesNearby(context.Background(),
+				tt.types, tt.maxPlaces, tt.rank, 55.7539, 37.6208, tt.radius, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if requests(methodSearch) != len(tt.types) {
+				t.Errorf("sent %d search requests, want one a type", requests(methodSearch))
+			}
+			if ids := placeIDs(places); !slices.Equal(ids, tt.places) {
+				t.Errorf("found %v, want %v", ids, tt.places)
+			}
+			for _, place := range places {
+				if place.PlaceID == gum && place.Types[0] != "shopping_mall" {
+					t.Errorf("searched type is not the first of %v", place.Types)
+				}
+			}
+		})
+	}
+}
+
+func TestGetTimeDistanceMatrix(t *testing.T) {
+	client, requests := newTestClient(t)
+	ids := []string{redSquare, gum, park, monastery, "N1"}
+
+	matrix, err := client.GetTimeDistanceMatrix(context.Background(), ids, model.TravelModeDriving)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if requests(methodLookup) != 1 || requests(methodTable) != 1 {
+		t.Errorf("sent %d lookup and %d table requests, want 1 and 1", requests(methodLookup), requests(methodTable))
+	}
+
+	for _, origin := range ids {
+		for _, destination := range ids {
+			metrics, ok := matrix[origin][destination]
+
+			unknown := origin == "N1" || destination == "N1"
+			unreachable := (origin == monastery) != (destination == monastery)
+			if unknown || unreachable {
+				if ok {
+					t.Errorf("pair %s -> %s has no route, got %v", origin, destination, metrics)
+				}
+				continue
+			}
+			if !ok {
+				t.Errorf("pair %s -> %s is missing", origin, destination)
+				continue
+			}
+
+			// 30 km/h is two minutes a kilometre
+			if math.Abs(metrics["duration"]-2*metrics["distance"]) > 1e-6 {
+				t.Errorf("pair %s -> %s is %v, want two minutes a kilometre", origin, destination, metrics)
+			}
+			if (origin == destination) != (metrics["distance"] == 0) {
+				t.Errorf("pair %s -> %s is %.3f km away", origin, destination, metrics["distance"])
+			}
+		}
+	}
+}
+
+func TestGetTimeDistanceMatrixBetween(t *testing.T) {
+	client, _ := newTestClient(t)
+
+	matrix, err := client.GetTimeDistanceMatrixBetween(context.Background(),
+		[]string{redSquare}, []string{gum, park}, model.TravelModeWalking)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(matrix) != 1 || len(matrix[redSquare]) != 2 {
+		t.Errorf("matrix %v, want the red square to the gum and the park", matrix)
+	}
+}
+
+func TestGetTimeDistanceMatrixChunks(t *testing.T) {
+	client, requests := newTestClient(t)
+
+	ids := []string{redSquare, gum, park}
+	for i := len(ids); i < maxLookupIDs+maxTableChunk; i++ {
+		ids = append(ids, fmt.Sprintf("N%d", i))
+	}
+
+	matrix, err := client.GetTimeDistanceMatrix(context.Background(), ids, model.TravelModeDriving)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if requests(methodLookup) != 2 {
+		t.Errorf("sent %d lookup requests, want 2", requests(methodLookup))
+	}
+	// unknown places are not routed, the found ones fit in a single table
+	if requests(methodTable) != 1 {
+		t.Errorf("sent %d table requests, want 1", requests(methodTable))
+	}
+	if len(matrix) != 3 {
+		t.Errorf("matrix of %d origins, want 3", len(matrix))
+	}
+}
+
+func TestGetTimeDistanceMatrixTransit(t *testing.T) {
+	client, requests := newTestClient(t)
+
+	matrix, err := client.GetTimeDistanceMatrix(context.Background(), []string{redSquare, gum}, model.TravelModeTransit)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(matrix) != 0 || requests(methodLookup) != 0 {
+		t.Errorf("transit is routed: %v", matrix)
+	}
+}
+
+func TestRequestErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(server.Close)
+	client := NewClient(server.URL, server.URL, "roamly-test")
+	ctx := context.Background()
+
+	if _, err := client.FindPlace(ctx, "гум", nil); err == nil {
+		t.Error("no error for a failed search")
+	}
+	if _, err := client.GetPlaceByID(ctx, gum, nil); err == nil {
+		t.Error("no error for a failed lookup")
+	}
+	if _, err := client.GetPlacesNearby(ctx, []string{"museum"}, 10, "", 55.7539, 37.6208, 1000, ""); err == nil {
+		t.Error("no error for a failed nearby search")
+	}
+	if _, err := client.GetTimeDistanceMatrix(ctx, []string{redSquare, gum}, model.TravelModeDriving); err == nil {
+		t.Error("no error for a failed matrix")
+	}
+}
@@ -0,0 +1,177 @@
+// Package osmtest serves a few Moscow places by the nominatim and osrm
+// apis, for tests of the osm client and for running the app offline.
+package osmtest
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Place is a nominatim jsonv2 result.
+type Place struct {
+	OsmType     string            `json:"osm_type"`
+	OsmID       int64             `json:"osm_id"`
+	Lat         string            `json:"lat"`
+	Lon         string            `json:"lon"`
+	Category    string            `json:"category"`
+	Type        string            `json:"type"`
+	Name        string            `json:"name"`
+	DisplayName string            `json:"display_name"`
+	ExtraTags   map[string]string `json:"extratags"`
+}
+
+// TableResponse is an osrm table result, unreachable pairs are null.
+type TableResponse struct {
+	Code      string       `json:"code"`
+	Durations [][]*float64 `json:"durations"`
+	Distances [][]*float64 `json:"distances"`
+}
+
+// maxRoute is the longest route, farther places are unreachable
+// as if they were across the sea.
+const maxRoute = 100000.0
+
+var places = []Place{
+	{
+		OsmType: "way", OsmID: 4401458, Lat: "55.7539", Lon: "37.6208",
+		Category: "place", Type: "square", Name: "Красная площадь",
+		DisplayName: "Красная площадь, Москва, Россия",
+		ExtraTags:   map[string]string{"opening_hours": "24/7"},
+	},
+	{
+		OsmType: "way", OsmID: 26609466, Lat: "55.7547", Lon: "37.6215",
+		Category: "shop", Type: "mall", Name: "ГУМ",
+		DisplayName: "ГУМ, Красная площадь, 3, Москва, Россия",
+		ExtraTags:   map[string]string{"opening_hours": "Mo-Su 10:00-22:00"},
+	},
+	{
+		OsmType: "node", OsmID: 2364917318, Lat: "55.7473", Lon: "37.6051",
+		Category: "tourism", Type: "museum", Name: "Государственный музей изобразительных искусств имени А. С. Пушкина",
+		DisplayName: "ГМИИ им. Пушкина, Волхонка, 12, Москва, Россия",
+		ExtraTags:   map[string]string{"opening_hours": "Tu-Su 11:00-20:00; Th 11:00-21:00; Mo off"},
+	},
+	{
+		OsmType: "relation", OsmID: 2903813, Lat: "55.7312", Lon: "37.6035",
+		Category: "leisure", Type: "park", Name: "Парк Горького",
+		DisplayName: "Парк Горького, Крымский Вал, Москва, Россия",
+	},
+	{
+		OsmType: "way", OsmID: 125537034, Lat: "65.0246", Lon: "35.7103",
+		Category: "amenity", Type: "monastery", Name: "Соловецкий монастырь",
+		DisplayName: "Соловецкий монастырь, Соловецкий, Архангельская область, Россия",
+	},
+}
+
+// searchHandler returns the places whose name, address or type contains the query.
+func searchHandler(w http.ResponseWriter, r *http.Request) {
+	query := strings.ToLower(r.URL.Query().Get("q"))
+
+	result := []Place{}
+	for _, place := range places {
+		text := strings.ToLower(place.Name + " " + place.DisplayName + " " + place.Type)
+		if strings.Contains(text, query) {
+			result = append(result, place)
+		}
+	}
+
+	writeJSON(w, result)
+}
+
+// lookupHandler returns the places by ids like "N123", unknown ids are skipped.
+func lookupHandler(w http.ResponseWriter, r *http.Request) {
+	result := []Place{}
+	for _, id := range strings.Split(r.URL.Query().Get("osm_ids"), ",") {
+		for _, place := range places {
+			if strings.ToUpper(place.OsmType[:1])+strconv.FormatInt(place.OsmID, 10) == id {
+				result = append(result, place)
+			}
+		}
+	}
+
+	writeJSON(w, result)
+}
+
+// tableHandler routes by the straight line at 30 km/h, so a route takes
+// two minutes per kilometre.
+func tableHandler(w http.ResponseWriter, r *http.Request) {
+	path := strings.Split(strings.TrimPrefix(r.URL.Path, "/table/v1/"), "/")
+	if len(path) != 2 {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	var coordinates [][2]float64
+	for _, pair := range strings.Split(path[1], ";") {
+		lon, lat, _ := strings.Cut(pair, ",")
+		lonValue, _ := strconv.ParseFloat(lon, 64)
+		latValue, _ := strconv.ParseFloat(lat, 64)
+		coordinates = append(coordinates, [2]float64{latValue, lonValue})
+	}
+
+	sources := indexes(r.URL.Query().Get("sources"), len(coordinates))
+	destinations := indexes(r.URL.Query().Get("destinations"), len(coordinates))
+
+	response := TableResponse{Code: "Ok"}
+	for _, i := range sources {
+		var durations, distances []*float64
+		for _, j := range destinations {
+			distance := distanceMeters(coordinates[i], coordinates[j])
+			if distance > maxRoute {
+				durations = append(durations, nil)
+				distances = append(distances, nil)
+				continue
+			}
+			duration := distance / (30000.0 / 3600)
+			distances = append(distances, &distance)
+			durations = append(durations, &duration)
+		}
+		response.Durations = append(response.Durations, durations)
+		response.Distances = append(response.Distances, distances)
+	}
+
+	writeJSON(w, response)
+}
+
+func indexes(value string, count int) []int {
+	var result []int
+	if value == "" {
+		for i := 0; i < count; i++ {
+			result = append(result, i)
+		}
+		return result
+	}
+
+	for _, index := range strings.Split(value, ";") {
+		i, err := strconv.Atoi(index)
+		if err == nil && i < count {
+			result = append(result, i)
+		}
+	}
+	return result
+}
+
+func distanceMeters(a, b [2]float64) float64 {
+	rad := math.Pi / 180
+	dLat := (b[0] - a[0]) * rad
+	dLng := (b[1] - a[1]) * rad
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(a[0]*rad)*math.Cos(b[0]*rad)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * 6371000 * math.Asin(math.Sqrt(h))
+}
+
+func writeJSON(w http.ResponseWriter, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(value)
+}
+
+// Handler serves both nominatim and osrm, its url is NOMINATIM_URL and OSRM_URL.
+func Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/search", searchHandler)
+	mux.HandleFunc("/lookup", lookupHandler)
+	mux.HandleFunc("/table/v1/", tableHandler)
+	return mux
+}
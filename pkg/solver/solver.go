@@ -0,0 +1,378 @@
+package solver
+
+import (
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/model"
+)
+
+// Config describes the constraints of a single trip day.
+type Config struct {
+	DayStart        time.Duration // offset from midnight
+	DayEnd          time.Duration // offset from midnight
+	MaxPlacesPerDay int
+	Slot            time.Duration // event start is rounded up to the slot
+	DefaultDuration time.Duration // used when a place has no recommended duration
+	DefaultTravel   time.Duration // used when the matrix has no value for a pair
+}
+
+type Input struct {
+	TripID uuid.UUID
+	Start  time.Time
+	End    time.Time
+	Places []*model.Place
+	// Fixed are the pinned events: places are scheduled around them
+	// together with the travel to and from them.
+	Fixed  []model.Event
+	Matrix model.DistanceMatrix
+}
+
+type Result struct {
+	Events   []model.Event
+	Warnings []model.ScheduleWarning
+}
+
+const maxTripDays = 366
+
+// Solve builds an itinerary without any external calls: places are ordered
+// into the shortest route found by nearest neighbour + 2-opt over the travel
+// time matrix, and the route is then split evenly between trip days.
+// Places are visited only inside their opening hours; places that can't be
+// fitted are returned as warnings. Fixed events are not moved and not returned.
+// The same input always gives the same result.
+func Solve(in Input, cfg Config) Result {
+	places := uniquePlaces(in.Places)
+	if len(places) == 0 {
+		return Result{}
+	}
+
+	route := bestRoute(places, in.Matrix, cfg)
+	days := tripDays(in.Start, in.End)
+
+	return fillDays(in, route, days, cfg)
+}
+
+func uniquePlaces(places []*model.Place) []*model.Place {
+	seen := make(map[string]struct{}, len(places))
+	unique := make([]*model.Place, 0, len(places))
+	for _, place := range places {
+		if place == nil {
+			continue
+		}
+		if _, ok := seen[place.ID]; ok {
+			continue
+		}
+		seen[place.ID] = struct{}{}
+		unique = append(unique, place)
+	}
+
+	sort.Slice(unique, func(i, j int) bool {
+		return unique[i].ID < unique[j].ID
+	})
+
+	return unique
+}
+
+func travel(matrix model.DistanceMatrix, from, to string, cfg Config) time.Duration {
+	if from == to {
+		return 0
+	}
+
+	metrics, ok := matrix[from][to]
+	if !ok {
+		return cfg.DefaultTravel
+	}
+
+	duration, ok := metrics["duration"]
+	if !ok {
+		return cfg.DefaultTravel
+	}
+
+	return time.Duration(duration * float64(time.Minute))
+}
+
+func routeCost(route []*model.Place, matrix model.DistanceMatrix, cfg Config) time.Duration {
+	var cost time.Duration
+	for i := 1; i < len(route); i++ {
+		cost += travel(matrix, route[i-1].ID, route[i].ID, cfg)
+	}
+	return cost
+}
+
+func bestRoute(places []*model.Place, matrix model.DistanceMatrix, cfg Config) []*model.Place {
+	var best []*model.Place
+	var bestCost time.Duration
+
+	for start := range places {
+		route := nearestNeighbour(places, start, matrix, cfg)
+		route = twoOpt(route, matrix, cfg)
+
+		cost := routeCost(route, matrix, cfg)
+		if best == nil || cost < bestCost {
+			best = route
+			bestCost = cost
+		}
+	}
+
+	return best
+}
+
+func nearestNeighbour(places []*model.Place, start int, matrix model.DistanceMatrix, cfg Config) []*model.Place {
+	visited := make([]bool, len(places))
+	route := make([]*model.Place, 0, len(places))
+
+	current := start
+	for {
+		visited[current] = true
+		route = append(route, places[current])
+
+		next := -1
+		var nextCost time.Duration
+		for i, place := range places {
+			if visited[i] {
+				continue
+			}
+			cost := travel(matrix, places[current].ID, place.ID, cfg)
+			if next == -1 || cost < nextCost {
+				next = i
+				nextCost = cost
+			}
+		}
+
+		if next == -1 {
+			return route
+		}
+		current = next
+	}
+}
+
+// twoOpt reverses route segments while it shortens the route.
+func twoOpt(route []*model.Place, matrix model.DistanceMatrix, cfg Config) []*model.Place {
+	bestCost := routeCost(route, matrix, cfg)
+
+	for improved := true; improved; {
+		improved = false
+		for i := 0; i < len(route)-1; i++ {
+			for j := i + 1; j < len(route); j++ {
+				candidate := reversed(route, i, j)
+				cost := routeCost(candidate, matrix, cfg)
+				if cost < bestCost {
+					route = candidate
+					bestCost = cost
+					improved = true
+				}
+			}
+		}
+	}
+
+	return route
+}
+
+func reversed(route []*model.Place, i, j int) []*model.Place {
+	candidate := make([]*model.Place, len(route))
+	copy(candidate, route)
+	for left, right := i, j; left < right; left, right = left+1, right-1 {
+		candidate[left], candidate[right] = candidate[right], candidate[left]
+	}
+	return candidate
+}
+
+func tripDays(start, end time.Time) []time.Time {
+	first := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location())
+	last := time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, start.Location())
+
+	days := []time.Time{first}
+	for day := first.AddDate(0, 0, 1); !day.After(last) && len(days) < maxTripDays; day = day.AddDate(0, 0, 1) {
+		days = append(days, day)
+	}
+
+	return days
+}
+
+// dayWindow returns the planning window of the day, the first and the last
+// trip days are cut by the trip start and end.
+func dayWindow(day time.Time, in Input, cfg Config) (time.Time, time.Time) {
+	start := day.Add(cfg.DayStart)
+	if tripStart := in.Start.In(day.Location()); start.Before(tripStart) {
+		start = tripStart
+	}
+
+	end := day.Add(cfg.DayEnd)
+	if tripEnd := in.End.In(day.Location()); end.After(tripEnd) {
+		end = tripEnd
+	}
+
+	return start, end
+}
+
+func fillDays(in Input, route []*model.Place, days []time.Time, cfg Config) Result {
+	var result Result
+	matrix := in.Matrix
+
+	remaining := route
+	for dayIdx, day := range days {
+		if len(remaining) == 0 {
+			break
+		}
+
+		// distribute remaining places evenly between remaining days
+		remainingDays := len(days) - dayIdx
+		quota := (len(remaining) + remainingDays - 1) / remainingDays
+		dayFixed := fixedOn(in.Fixed, day)
+		if cfg.MaxPlacesPerDay > 0 && quota > cfg.MaxPlacesPerDay-len(dayFixed) {
+			quota = max(cfg.MaxPlacesPerDay-len(dayFixed), 0)
+		}
+
+		cursor, dayEnd := dayWindow(day, in, cfg)
+		var prev *model.Place
+		var postponed []*model.Place
+
+		count := 0
+		for _, place := range remaining {
+			if count >= quota {
+				postponed = append(postponed, place)
+				continue
+			}
+
+			arrival := cursor
+			if prev != nil {
+				arrival = cursor.Add(travel(matrix, prev.ID, place.ID, cfg))
+			}
+
+			start, ok := earliestFreeStart(place, day, arrival, dayEnd, dayFixed, matrix, cfg)
+			if !ok {
+				postponed = append(postponed, place)
+				continue
+			}
+			end := start.Add(visitingDuration(place, cfg))
+
+			result.Events = append(result.Events, model.Event{
+				Name:      place.GooglePlace.Name,
+				PlaceID:   place.ID,
+				TripID:    in.TripID,
+				StartTime: start,
+				EndTime:   end,
+			})
+
+			cursor = end
+			prev = place
+			count++
+		}
+
+		remaining = postponed
+	}
+
+	for _, place := range remaining {
+		result.Warnings = append(result.Warnings, model.ScheduleWarning{
+			PlaceID:   place.ID,
+			PlaceName: place.GooglePlace.Name,
+			Reason:    unscheduledReason(place, days, in, cfg),
+		})
+	}
+
+	return result
+}
+
+// earliestStart finds the first slot not earlier than arrival when the place
+// is open for the whole visit and the visit ends before the day end.
+func earliestStart(place *model.Place, day, arrival, dayEnd time.Time, cfg Config) (time.Time, bool) {
+	duration := visitingDuration(place, cfg)
+
+	windows := place.OpenWindows(day)
+	sort.Slice(windows, func(i, j int) bool {
+		return windows[i][0].Before(windows[j][0])
+	})
+
+	for _, window := range windows {
+		start := arrival
+		if start.Before(window[0]) {
+			start = window[0]
+		}
+		start = roundUp(day, start, cfg.Slot)
+		end := start.Add(duration)
+
+		if !end.After(window[1]) && !end.After(dayEnd) {
+			return start, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// earliestFreeStart is earliestStart that also keeps the visit clear of the
+// fixed events of the day, leaving time to travel to and from them.
+func earliestFreeStart(
+	place *model.Place,
+	day, arrival, dayEnd time.Time,
+	fixed []model.Event,
+	matrix model.DistanceMatrix,
+	cfg Config,
+) (time.Time, bool) {
+	for {
+		start, ok := earliestStart(place, day, arrival, dayEnd, cfg)
+		if !ok {
+			return time.Time{}, false
+		}
+		end := start.Add(visitingDuration(place, cfg))
+
+		blocked := false
+		for _, event := range fixed {
+			free := event.EndTime.Add(travel(matrix, event.PlaceID, place.ID, cfg))
+			if start.Before(free) && end.Add(travel(matrix, place.ID, event.PlaceID, cfg)).After(event.StartTime) {
+				// every retry starts after one more fixed event, so the loop ends
+				arrival = free
+				blocked = true
+				break
+			}
+		}
+		if !blocked {
+			return start, true
+		}
+	}
+}
+
+// fixedOn returns the fixed events starting on the day.
+func fixedOn(fixed []model.Event, day time.Time) []model.Event {
+	var events []model.Event
+	for _, event := range fixed {
+		start := event.StartTime.In(day.Location())
+		if start.Year() == day.Year() && start.YearDay() == day.YearDay() {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+func unscheduledReason(place *model.Place, days []time.Time, in Input, cfg Config) string {
+	for _, day := range days {
+		dayStart, dayEnd := dayWindow(day, in, cfg)
+		_, ok := earliestStart(place, day, dayStart, dayEnd, cfg)
+		if ok {
+			return model.WarningNoFreeTime
+		}
+	}
+	return model.WarningOutsideOpeningHours
+}
+
+func visitingDuration(place *model.Place, cfg Config) time.Duration {
+	if place.RecommendedVisitingDuration <= 0 {
+		return cfg.DefaultDuration
+	}
+	return time.Duration(place.RecommendedVisitingDuration) * time.Minute
+}
+
+func roundUp(day, t time.Time, slot time.Duration) time.Time {
+	if slot <= 0 {
+		return t
+	}
+
+	offset := t.Sub(day)
+	if rem := offset % slot; rem != 0 {
+		offset += slot - rem
+	}
+
+	return day.Add(offset)
+}
@@ -0,0 +1,236 @@
+package solver
+
+import (
+	"maps"
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ShelbyKS/Roamly-backend/internal/domain/model"
+)
+
+var testConfig = Config{
+	DayStart:        9 * time.Hour,
+	DayEnd:          21 * time.Hour,
+	Slot:            30 * time.Minute,
+	DefaultDuration: time.Hour,
+	DefaultTravel:   30 * time.Minute,
+}
+
+// 2024-06-04 is a tuesday
+func at(day, hour, minute int) time.Time {
+	return time.Date(2024, time.June, day, hour, minute, 0, 0, time.UTC)
+}
+
+func testPlaces(ids ...string) []*model.Place {
+	places := make([]*model.Place, len(ids))
+	for i, id := range ids {
+		places[i] = &model.Place{ID: id, GooglePlace: model.GooglePlace{Name: id}}
+	}
+	return places
+}
+
+// lineMatrix puts the places on a line, travel takes a minute per unit.
+func lineMatrix(positions map[string]float64) model.DistanceMatrix {
+	matrix := model.DistanceMatrix{}
+	for from, fromPos := range positions {
+		matrix[from] = map[string]map[string]float64{}
+		for to, toPos := range positions {
+			matrix[from][to] = map[string]float64{"duration": math.Abs(fromPos - toPos)}
+		}
+	}
+	return matrix
+}
+
+func placeIDs(events []model.Event) []string {
+	ids := make([]string, len(events))
+	for i, event := range events {
+		ids[i] = event.PlaceID
+	}
+	return ids
+}
+
+func routeIDs(route []*model.Place) []string {
+	ids := make([]string, len(route))
+	for i, place := range route {
+		ids[i] = place.ID
+	}
+	return ids
+}
+
+func warningReasons(warnings []model.ScheduleWarning) map[string]string {
+	reasons := make(map[string]string, len(warnings))
+	for _, warning := range warnings {
+		reasons[warning.PlaceID] = warning.Reason
+	}
+	return reasons
+}
+
+func TestSolve(t *testing.T) {
+	mondayOnly := testPlaces("monday")[0]
+	mondayOnly.OpeningHours = []model.OpeningHours{{Weekday: time.Monday, Opening: "10:00", Closing: "18:00"}}
+
+	tests := []struct {
+		name     string
+		in       Input
+		cfg      Config
+		events   []string
+		starts   []time.Time
+		warnings map[string]string
+	}{
+		{
+			name: "first day starts at trip start, last day ends at trip end",
+			in: Input{
+				Start:  at(4, 15, 0),
+				End:    at(5, 12, 0),
+				Places: testPlaces("a", "b"),
+			},
+			cfg:      testConfig,
+			events:   []string{"a", "b"},
+			starts:   []time.Time{at(4, 15, 0), at(5, 9, 0)},
+			warnings: map[string]string{},
+		},
+		{
+			name: "trip start is rounded up to the slot",
+			in: Input{
+				Start:  at(4, 15, 10),
+				End:    at(4, 23, 0),
+				Places: testPlaces("a"),
+			},
+			cfg:      testConfig,
+			events:   []string{"a"},
+			starts:   []time.Time{at(4, 15, 30)},
+			warnings: map[string]string{},
+		},
+		{
+			name: "places not fitting before trip end are warnings",
+			in: Input{
+				Start:  at(4, 15, 0),
+				End:    at(4, 17, 0),
+				Places: testPlaces("a", "b", "c"),
+			},
+			cfg:      testConfig,
+			events:   []string{"a"},
+			starts:   []time.Time{at(4, 15, 0)},
+			warnings: map[string]string{"b": model.WarningNoFreeTime, "c": model.WarningNoFreeTime},
+		},
+		{
+			name: "shortest route",
+			in: Input{
+				Start:  at(4, 0, 0),
+				End:    at(4, 23, 0),
+				Places: testPlaces("d", "c", "b", "a"),
+				Matrix: lineMatrix(map[string]float64{"a": 0, "b": 20, "c": 10, "d": 30}),
+			},
+			cfg:      testConfig,
+			events:   []string{"a", "c", "b", "d"},
+			starts:   []time.Time{at(4, 9, 0), at(4, 10, 30), at(4, 12, 0), at(4, 13, 30)},
+			warnings: map[string]string{},
+		},
+		{
+			name: "max places per day",
+			in: Input{
+				Start:  at(4, 0, 0),
+				End:    at(4, 23, 0),
+				Places: testPlaces("a", "b", "c"),
+			},
+			cfg: Config{
+				DayStart:        testConfig.DayStart,
+				DayEnd:          testConfig.DayEnd,
+				MaxPlacesPerDay: 2,
+				Slot:            testConfig.Slot,
+				DefaultDuration: testConfig.DefaultDuration,
+				DefaultTravel:   testConfig.DefaultTravel,
+			},
+			events:   []string{"a", "b"},
+			starts:   []time.Time{at(4, 9, 0), at(4, 10, 30)},
+			warnings: map[string]string{"c": model.WarningNoFreeTime},
+		},
+		{
+			name: "closed on every trip day",
+			in: Input{
+				Start:  at(4, 0, 0),
+				End:    at(5, 23, 0),
+				Places: append(testPlaces("a"), mondayOnly),
+			},
+			cfg:      testConfig,
+			events:   []string{"a"},
+			starts:   []time.Time{at(4, 9, 0)},
+			warnings: map[string]string{"monday": model.WarningOutsideOpeningHours},
+		},
+		{
+			name: "places are planned around fixed events",
+			in: Input{
+				Start:  at(4, 0, 0),
+				End:    at(4, 23, 0),
+				Places: testPlaces("a"),
+				Fixed:  []model.Event{{PlaceID: "fixed", StartTime: at(4, 9, 0), EndTime: at(4, 11, 0)}},
+			},
+			cfg:      testConfig,
+			events:   []string{"a"},
+			starts:   []time.Time{at(4, 11, 30)},
+			warnings: map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.in.TripID = uuid.New()
+			result := Solve(tt.in, tt.cfg)
+
+			if ids := placeIDs(result.Events); !slices.Equal(ids, tt.events) {
+				t.Fatalf("events %v, want %v", ids, tt.events)
+			}
+			for i, event := range result.Events {
+				if !event.StartTime.Equal(tt.starts[i]) {
+					t.Errorf("event %s starts at %v, want %v", event.PlaceID, event.StartTime, tt.starts[i])
+				}
+				if event.StartTime.Before(tt.in.Start) || event.EndTime.After(tt.in.End) {
+					t.Errorf("event %s is outside of the trip", event.PlaceID)
+				}
+				if event.TripID != tt.in.TripID {
+					t.Errorf("event %s has trip %v", event.PlaceID, event.TripID)
+				}
+			}
+
+			if reasons := warningReasons(result.Warnings); !maps.Equal(reasons, tt.warnings) {
+				t.Errorf("warnings %v, want %v", reasons, tt.warnings)
+			}
+		})
+	}
+}
+
+func TestSolveIsDeterministic(t *testing.T) {
+	in := Input{
+		Start:  at(4, 0, 0),
+		End:    at(6, 23, 0),
+		Places: testPlaces("e", "a", "d", "b", "c", "a"),
+		Matrix: lineMatrix(map[string]float64{"a": 0, "b": 40, "c": 10, "d": 30, "e": 20}),
+	}
+
+	first := Solve(in, testConfig)
+	for i := 0; i < 10; i++ {
+		if next := Solve(in, testConfig); !slices.Equal(placeIDs(next.Events), placeIDs(first.Events)) {
+			t.Fatalf("got %v, then %v", placeIDs(first.Events), placeIDs(next.Events))
+		}
+	}
+	if len(first.Events) != 5 {
+		t.Errorf("got %d events of 5 unique places", len(first.Events))
+	}
+}
+
+func TestTwoOpt(t *testing.T) {
+	matrix := lineMatrix(map[string]float64{"a": 0, "b": 20, "c": 10, "d": 30})
+
+	route := twoOpt(testPlaces("a", "b", "c", "d"), matrix, testConfig)
+
+	if ids := routeIDs(route); !slices.Equal(ids, []string{"a", "c", "b", "d"}) {
+		t.Errorf("route %v, want [a c b d]", ids)
+	}
+	if cost := routeCost(route, matrix, testConfig); cost != 30*time.Minute {
+		t.Errorf("route cost %v, want 30m", cost)
+	}
+}